@@ -0,0 +1,345 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	urlPack "net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Security Header Audit
+// ----------------------------------------------------------------------
+
+// Names of the checks run by AuditResponse.
+const (
+	CheckTransport             = "transport"
+	CheckStrictTransport       = "strict-transport-security"
+	CheckContentSecurityPolicy = "content-security-policy"
+	CheckContentTypeOptions    = "x-content-type-options"
+	CheckReferrerPolicy        = "referrer-policy"
+	CheckPermissionsPolicy     = "permissions-policy"
+	CheckFrameOptions          = "frame-options"
+	CheckCookie                = "cookie"
+)
+
+// minHSTSMaxAge is the smallest Strict-Transport-Security max-age (180 days) not
+// reported as weak.
+const minHSTSMaxAge = 15552000
+
+// AuditIssue is a missing or weak security setting found in a response.
+type AuditIssue struct {
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// URLAudit holds the issues found on the rounds of a pack key. Url is the URL of
+// the latest round, Rounds the number of rounds audited and Affected, per issue
+// message, the number of rounds showing the issue.
+type URLAudit struct {
+	Key      string         `json:"key"`
+	Url      string         `json:"url"`
+	Rounds   int            `json:"rounds"`
+	Issues   []AuditIssue   `json:"issues"`
+	Affected map[string]int `json:"affected"`
+}
+
+// HostAudit groups the URL audits of a host.
+type HostAudit struct {
+	Host string     `json:"host"`
+	Urls []URLAudit `json:"urls"`
+}
+
+// SecurityAuditReport is the result of AuditPack, grouped per host and per pack key.
+// Summary counts the issues per severity name.
+type SecurityAuditReport struct {
+	Hosts   []HostAudit    `json:"hosts"`
+	Summary map[string]int `json:"summary"`
+}
+
+// AuditResponse checks the security headers and cookies of a single Response.
+// Transport-only checks (HSTS, Secure cookies) are skipped for plain HTTP URLs,
+// which get a single transport issue instead.
+func AuditResponse(response *Response) []AuditIssue {
+	if response == nil {
+		return nil
+	}
+
+	var issues []AuditIssue
+	add := func(check string, severity Severity, message string) {
+		issues = append(issues, AuditIssue{Check: check, Severity: severity, Message: message})
+	}
+
+	headers := lowerHeaders(response.Headers)
+	secure := isHTTPS(response.Url)
+
+	// Transport and HSTS
+	if !secure {
+		add(CheckTransport, SeverityMedium, "served over plain HTTP")
+	} else if hsts, ok := headers["strict-transport-security"]; !ok {
+		add(CheckStrictTransport, SeverityHigh, "missing Strict-Transport-Security")
+	} else {
+		directives := parseDirectives(hsts, ";")
+		maxAge, err := strconv.Atoi(directives["max-age"])
+		if err != nil || maxAge < minHSTSMaxAge {
+			add(CheckStrictTransport, SeverityMedium, "Strict-Transport-Security max-age below 180 days")
+		}
+		if _, ok := directives["includesubdomains"]; !ok {
+			add(CheckStrictTransport, SeverityLow, "Strict-Transport-Security without includeSubDomains")
+		}
+	}
+
+	// Content-Security-Policy
+	csp, hasCSP := headers["content-security-policy"]
+	cspDirectives := parseDirectives(csp, ";")
+	if !hasCSP {
+		add(CheckContentSecurityPolicy, SeverityMedium, "missing Content-Security-Policy")
+	} else {
+		scriptSources := cspDirectives["script-src"]
+		if scriptSources == "" {
+			scriptSources = cspDirectives["default-src"]
+		}
+		if strings.Contains(scriptSources, "'unsafe-inline'") {
+			add(CheckContentSecurityPolicy, SeverityMedium, "Content-Security-Policy allows 'unsafe-inline' scripts")
+		}
+		if strings.Contains(scriptSources, "'unsafe-eval'") {
+			add(CheckContentSecurityPolicy, SeverityMedium, "Content-Security-Policy allows 'unsafe-eval'")
+		}
+		for _, source := range strings.Fields(scriptSources) {
+			if source == "*" {
+				add(CheckContentSecurityPolicy, SeverityMedium, "Content-Security-Policy allows scripts from any origin")
+				break
+			}
+		}
+	}
+
+	// X-Content-Type-Options
+	if value, ok := headers["x-content-type-options"]; !ok {
+		add(CheckContentTypeOptions, SeverityLow, "missing X-Content-Type-Options")
+	} else if !strings.EqualFold(strings.TrimSpace(value), "nosniff") {
+		add(CheckContentTypeOptions, SeverityLow, "X-Content-Type-Options is not nosniff")
+	}
+
+	// Referrer-Policy
+	if value, ok := headers["referrer-policy"]; !ok {
+		add(CheckReferrerPolicy, SeverityLow, "missing Referrer-Policy")
+	} else {
+		for _, policy := range strings.Split(strings.ToLower(value), ",") {
+			policy = strings.TrimSpace(policy)
+			if policy == "unsafe-url" || policy == "no-referrer-when-downgrade" {
+				add(CheckReferrerPolicy, SeverityLow, "weak Referrer-Policy "+policy)
+			}
+		}
+	}
+
+	// Permissions-Policy
+	if _, ok := headers["permissions-policy"]; !ok {
+		add(CheckPermissionsPolicy, SeverityLow, "missing Permissions-Policy")
+	}
+
+	// Frame options
+	frameOptions, hasFrameOptions := headers["x-frame-options"]
+	_, hasFrameAncestors := cspDirectives["frame-ancestors"]
+	switch {
+	case !hasFrameOptions && !hasFrameAncestors:
+		add(CheckFrameOptions, SeverityMedium, "missing X-Frame-Options and CSP frame-ancestors")
+	case hasFrameOptions && !hasFrameAncestors:
+		value := strings.ToUpper(strings.TrimSpace(frameOptions))
+		if value != "DENY" && value != "SAMEORIGIN" {
+			add(CheckFrameOptions, SeverityLow, "X-Frame-Options "+value+" is not DENY or SAMEORIGIN")
+		}
+	}
+
+	// Cookies
+	if cookies, ok := headers["set-cookie"]; ok {
+		for _, cookie := range SplitSetCookie(cookies) {
+			name, attributes := parseCookieAttributes(cookie)
+			_, hasSecure := attributes["secure"]
+			sameSite, hasSameSite := attributes["samesite"]
+			if secure && !hasSecure {
+				add(CheckCookie, SeverityMedium, "cookie "+name+" without Secure")
+			}
+			if _, ok := attributes["httponly"]; !ok {
+				add(CheckCookie, SeverityMedium, "cookie "+name+" without HttpOnly")
+			}
+			if !hasSameSite {
+				add(CheckCookie, SeverityLow, "cookie "+name+" without SameSite")
+			} else if strings.EqualFold(sameSite, "none") && !hasSecure {
+				add(CheckCookie, SeverityHigh, "cookie "+name+" with SameSite=None without Secure")
+			}
+		}
+	}
+
+	return issues
+}
+
+// AuditPack audits every round of a ResponsePack or CompressResponsePack and groups
+// the issues per host and per pack key. Issues repeated across rounds of a URL are
+// reported once, with the number of affected rounds.
+func AuditPack(pack Pack) (*SecurityAuditReport, error) {
+	if pack == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+
+	hosts := map[string]map[string]*URLAudit{}
+	err := forEachRound(pack, func(key string, round int, response *Response) error {
+		host := response.Host
+		if host == "" {
+			host = hostOf(response.Url)
+		}
+		if hosts[host] == nil {
+			hosts[host] = map[string]*URLAudit{}
+		}
+		audit, ok := hosts[host][key]
+		if !ok {
+			audit = &URLAudit{Key: key, Url: response.Url, Issues: []AuditIssue{}, Affected: map[string]int{}}
+			hosts[host][key] = audit
+		}
+		audit.Url = response.Url
+		audit.Rounds++
+		for _, issue := range AuditResponse(response) {
+			if audit.Affected[issue.Message] == 0 {
+				audit.Issues = append(audit.Issues, issue)
+			}
+			audit.Affected[issue.Message]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SecurityAuditReport{Hosts: []HostAudit{}, Summary: map[string]int{}}
+	for _, host := range sortedKeys(hosts) {
+		hostAudit := HostAudit{Host: host}
+		for _, key := range sortedKeys(hosts[host]) {
+			audit := hosts[host][key]
+			sort.SliceStable(audit.Issues, func(i, j int) bool {
+				return audit.Issues[i].Severity > audit.Issues[j].Severity
+			})
+			for _, issue := range audit.Issues {
+				report.Summary[issue.Severity.String()]++
+			}
+			hostAudit.Urls = append(hostAudit.Urls, *audit)
+		}
+		report.Hosts = append(report.Hosts, hostAudit)
+	}
+
+	return report, nil
+}
+
+// ToJSON converts the report to a JSON-encoded byte slice.
+func (r *SecurityAuditReport) ToJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// ToString returns a text report, one block per host and URL, issues sorted by severity.
+func (r *SecurityAuditReport) ToString() string {
+	var sb strings.Builder
+	sb.WriteString("Security Audit:")
+	for _, severity := range []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo} {
+		if count := r.Summary[severity.String()]; count > 0 {
+			sb.WriteString(fmt.Sprintf("\n\t%s: %d", severity, count))
+		}
+	}
+	for _, host := range r.Hosts {
+		sb.WriteString("\nHost: ")
+		sb.WriteString(host.Host)
+		for _, audit := range host.Urls {
+			sb.WriteString(fmt.Sprintf("\n\tURL: %s (%d rounds)", audit.Url, audit.Rounds))
+			for _, issue := range audit.Issues {
+				sb.WriteString(fmt.Sprintf("\n\t\t[%s] %s: %s (%d/%d)", issue.Severity, issue.Check, issue.Message, audit.Affected[issue.Message], audit.Rounds))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Print prints the text report to the console.
+func (r *SecurityAuditReport) Print() {
+	fmt.Println(r.ToString())
+}
+
+// SplitSetCookie splits a Set-Cookie value joined with ", " back into cookies.
+// Commas inside Expires dates are kept in their cookie.
+func SplitSetCookie(value string) []string {
+	var cookies []string
+	for _, part := range strings.Split(value, ",") {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+		first, _, _ := strings.Cut(trimmed, ";")
+		if len(cookies) > 0 && !strings.Contains(first, "=") {
+			cookies[len(cookies)-1] += "," + part
+			continue
+		}
+		cookies = append(cookies, trimmed)
+	}
+	return cookies
+}
+
+// parseCookieAttributes returns the cookie name and its lower-cased attributes.
+func parseCookieAttributes(cookie string) (string, map[string]string) {
+	parts := strings.Split(cookie, ";")
+	name, _, _ := strings.Cut(strings.TrimSpace(parts[0]), "=")
+	attributes := map[string]string{}
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		attributes[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return name, attributes
+}
+
+// parseDirectives splits a header value into lower-cased directive names and their values.
+func parseDirectives(value string, separator string) map[string]string {
+	directives := map[string]string{}
+	for _, part := range strings.Split(value, separator) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// The name ends at the first "=" (max-age=...) or space (script-src 'self')
+		name, rest := part, ""
+		if index := strings.IndexAny(part, "= "); index >= 0 {
+			name, rest = part[:index], part[index+1:]
+		}
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(rest), `"`)
+	}
+	return directives
+}
+
+// lowerHeaders returns headers keyed by lower-cased names.
+func lowerHeaders(headers map[string]string) map[string]string {
+	output := make(map[string]string, len(headers))
+	for name, value := range headers {
+		output[strings.ToLower(name)] = value
+	}
+	return output
+}
+
+// isHTTPS reports whether url uses the https scheme.
+func isHTTPS(url string) bool {
+	return strings.HasPrefix(strings.ToLower(url), "https://")
+}
+
+// hostOf returns the host of url, or url itself when it cannot be parsed.
+func hostOf(url string) string {
+	parsed, err := urlPack.Parse(url)
+	if err != nil || parsed.Host == "" {
+		return url
+	}
+	return parsed.Host
+}
+
+// sortedKeys returns the keys of a string-keyed map in sorted order.
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,450 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// Cache-Control
+// ----------------------------------------------------------------------
+
+// CacheControl holds the directives of a Cache-Control header, keyed by lower-cased
+// name. Directives without an argument map to "".
+type CacheControl struct {
+	Directives map[string]string `json:"directives"`
+}
+
+// ParseCacheControl parses a Cache-Control header value. Quoted arguments, such as
+// the field list of private="Set-Cookie, X-Token", may contain commas. The first
+// occurrence of a directive wins.
+func ParseCacheControl(value string) CacheControl {
+	directives := map[string]string{}
+	for _, part := range splitQuoted(value) {
+		name, argument, _ := strings.Cut(part, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := directives[name]; ok {
+			continue
+		}
+		directives[name] = strings.Trim(strings.TrimSpace(argument), `"`)
+	}
+	return CacheControl{Directives: directives}
+}
+
+// Has reports whether the directive is present.
+func (c CacheControl) Has(name string) bool {
+	_, ok := c.Directives[strings.ToLower(name)]
+	return ok
+}
+
+// Seconds returns the delta-seconds argument of a directive such as max-age. The
+// boolean is false when the directive is absent or its argument is not a
+// non-negative integer.
+func (c CacheControl) Seconds(name string) (time.Duration, bool) {
+	argument, ok := c.Directives[strings.ToLower(name)]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(argument, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// Fields returns the field names qualifying a directive, e.g. no-cache="Set-Cookie".
+func (c CacheControl) Fields(name string) []string {
+	return splitList(c.Directives[strings.ToLower(name)])
+}
+
+// splitQuoted splits a comma-separated header value, keeping commas inside quotes.
+func splitQuoted(value string) []string {
+	var parts []string
+	var current strings.Builder
+	quoted := false
+	for _, char := range value {
+		switch {
+		case char == '"':
+			quoted = !quoted
+			current.WriteRune(char)
+		case char == ',' && !quoted:
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(char)
+		}
+	}
+	parts = append(parts, strings.TrimSpace(current.String()))
+	return parts
+}
+
+// Cache Policy
+// ----------------------------------------------------------------------
+
+// CacheScope selects the cache a policy is evaluated for.
+type CacheScope int
+
+const (
+	// SharedCache is a proxy or CDN cache serving many users.
+	SharedCache CacheScope = iota
+	// PrivateCache is a cache dedicated to one user, e.g. a browser cache.
+	PrivateCache
+)
+
+// String returns "shared" or "private".
+func (s CacheScope) String() string {
+	switch s {
+	case SharedCache:
+		return "shared"
+	case PrivateCache:
+		return "private"
+	default:
+		return fmt.Sprintf("CacheScope(%d)", int(s))
+	}
+}
+
+// MarshalJSON encodes the scope as its name.
+func (s CacheScope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Codes of the issues reported by CachePolicy.
+const (
+	CacheNoHeaders       = "no-cache-headers"
+	CacheHeuristic       = "heuristic-freshness"
+	CacheInvalidExpires  = "invalid-expires"
+	CacheConflicting     = "conflicting-directives"
+	CacheNoValidators    = "no-validators"
+	CacheVaryStar        = "vary-star"
+	CacheSharedSetCookie = "shared-set-cookie"
+)
+
+// heuristicStatusCodes are the status codes cacheable by default (RFC 9110, 15.1).
+var heuristicStatusCodes = map[codes.StatusCode]bool{
+	200: true, 203: true, 204: true, 206: true, 300: true, 301: true,
+	308: true, 404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// heuristicFraction is the share of the time since Last-Modified used as heuristic
+// freshness lifetime, as suggested by RFC 9111, 4.2.2.
+const heuristicFraction = 10
+
+// CacheIssue is a caching misconfiguration found in a response.
+type CacheIssue struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// CachePolicy is the caching behaviour of a Response for a cache scope, following
+// RFC 9111.
+//
+// Age is the corrected initial age of the response when it was received and
+// FreshnessLifetime how long it stays fresh from its generation; TTL is what is
+// left of it on receipt. Heuristic is true when the lifetime comes from
+// Last-Modified rather than explicit freshness information. Fresh is false when
+// the response may not be reused without validation. Durations are encoded in
+// JSON as nanoseconds.
+type CachePolicy struct {
+	Scope             CacheScope    `json:"scope"`
+	CacheControl      CacheControl  `json:"cacheControl"`
+	Date              time.Time     `json:"date"`
+	Expires           time.Time     `json:"expires"`
+	LastModified      time.Time     `json:"lastModified"`
+	ETag              string        `json:"etag"`
+	Vary              []string      `json:"vary"`
+	ResponseTime      time.Time     `json:"responseTime"`
+	Age               time.Duration `json:"age"`
+	Storable          bool          `json:"storable"`
+	Reason            string        `json:"reason"`
+	FreshnessLifetime time.Duration `json:"freshnessLifetime"`
+	Heuristic         bool          `json:"heuristic"`
+	TTL               time.Duration `json:"ttl"`
+	Fresh             bool          `json:"fresh"`
+	Issues            []CacheIssue  `json:"issues"`
+}
+
+// CachePolicy evaluates the Cache-Control, Expires, Age, Date, ETag, Last-Modified
+// and Vary headers of the response for a shared or private cache.
+//
+// The response time is the end of Timing when it was recorded, the Date header
+// otherwise. Requests recorded with an Authorization header are only storable in a
+// shared cache with public, must-revalidate or s-maxage.
+func (r *Response) CachePolicy(scope CacheScope) *CachePolicy {
+	headers := lowerHeaders(r.Headers)
+	cacheControl := ParseCacheControl(headers["cache-control"])
+
+	policy := &CachePolicy{
+		Scope:        scope,
+		CacheControl: cacheControl,
+		ETag:         strings.TrimSpace(headers["etag"]),
+		Vary:         splitList(headers["vary"]),
+		Issues:       []CacheIssue{},
+	}
+	issue := func(code string, severity Severity, message string) {
+		policy.Issues = append(policy.Issues, CacheIssue{Code: code, Severity: severity, Message: message})
+	}
+
+	policy.Date, _ = http.ParseTime(headers["date"])
+	policy.LastModified, _ = http.ParseTime(headers["last-modified"])
+	expiresValue, hasExpires := headers["expires"]
+	expires, expiresErr := http.ParseTime(expiresValue)
+	if hasExpires && expiresErr == nil {
+		policy.Expires = expires
+	}
+
+	// Age (RFC 9111, 4.2.3)
+	requestTime := policy.Date
+	policy.ResponseTime = policy.Date
+	if r.Timing != nil && !r.Timing.Start.IsZero() {
+		requestTime = r.Timing.Start
+		policy.ResponseTime = r.Timing.Start.Add(r.Timing.Duration)
+	}
+	var apparentAge time.Duration
+	if !policy.Date.IsZero() && policy.ResponseTime.After(policy.Date) {
+		apparentAge = policy.ResponseTime.Sub(policy.Date)
+	}
+	correctedAge := policy.ResponseTime.Sub(requestTime)
+	if seconds, err := strconv.ParseInt(strings.TrimSpace(headers["age"]), 10, 64); err == nil && seconds >= 0 {
+		correctedAge += time.Duration(seconds) * time.Second
+	}
+	policy.Age = max(apparentAge, correctedAge)
+
+	// Storability (RFC 9111, 3)
+	maxAge, hasMaxAge := cacheControl.Seconds("max-age")
+	sMaxAge, hasSMaxAge := cacheControl.Seconds("s-maxage")
+	shared := scope == SharedCache
+	public := cacheControl.Has("public")
+	private := cacheControl.Has("private")
+	explicit := hasExpires || hasMaxAge || (shared && hasSMaxAge)
+
+	authorized := false
+	if r.Request != nil {
+		_, authorized = lowerHeaders(r.Request.Headers)["authorization"]
+	}
+
+	switch {
+	case r.Method != codes.GET && r.Method != codes.HEAD:
+		policy.Reason = "method " + string(r.Method) + " is not cacheable"
+	case r.StatusCode < 200 || r.StatusCode == 206 || r.StatusCode == 304:
+		policy.Reason = fmt.Sprintf("status %d is not stored", r.StatusCode)
+	case cacheControl.Has("no-store"):
+		policy.Reason = "no-store"
+	case shared && private && len(cacheControl.Fields("private")) == 0:
+		policy.Reason = "private"
+	case shared && authorized && !public && !hasSMaxAge && !cacheControl.Has("must-revalidate"):
+		policy.Reason = "request carries Authorization"
+	case !explicit && !public && !(private && !shared) && !heuristicStatusCodes[r.StatusCode]:
+		policy.Reason = fmt.Sprintf("no freshness information and status %d is not heuristically cacheable", r.StatusCode)
+	default:
+		policy.Storable = true
+	}
+
+	// Freshness lifetime (RFC 9111, 4.2.1)
+	switch {
+	case shared && hasSMaxAge:
+		policy.FreshnessLifetime = sMaxAge
+	case hasMaxAge:
+		policy.FreshnessLifetime = maxAge
+	case hasExpires:
+		// An invalid Expires, e.g. "0", means already expired
+		if expiresErr == nil && !policy.Date.IsZero() && expires.After(policy.Date) {
+			policy.FreshnessLifetime = expires.Sub(policy.Date)
+		}
+	case !policy.LastModified.IsZero() && !policy.Date.IsZero() && policy.Date.After(policy.LastModified) &&
+		(public || heuristicStatusCodes[r.StatusCode]):
+		policy.FreshnessLifetime = policy.Date.Sub(policy.LastModified) / heuristicFraction
+		policy.Heuristic = true
+	}
+
+	if policy.FreshnessLifetime > policy.Age {
+		policy.TTL = policy.FreshnessLifetime - policy.Age
+	}
+	policy.Fresh = policy.Storable && policy.TTL > 0 && !cacheControl.Has("no-cache")
+
+	// Misconfigurations
+	if _, ok := headers["cache-control"]; !ok && !hasExpires {
+		issue(CacheNoHeaders, SeverityLow, "no Cache-Control nor Expires")
+	}
+	if policy.Heuristic {
+		issue(CacheHeuristic, SeverityInfo, "freshness lifetime is heuristic ("+policy.FreshnessLifetime.String()+" from Last-Modified)")
+	}
+	if hasExpires && expiresErr != nil && !hasMaxAge && !(shared && hasSMaxAge) {
+		issue(CacheInvalidExpires, SeverityLow, "invalid Expires "+strconv.Quote(expiresValue))
+	}
+	if cacheControl.Has("no-store") && (hasMaxAge || hasSMaxAge || public) {
+		issue(CacheConflicting, SeverityMedium, "no-store combined with public, max-age or s-maxage")
+	}
+	if public && private {
+		issue(CacheConflicting, SeverityMedium, "public combined with private")
+	}
+	if _, ok := cacheControl.Directives["max-age"]; ok && !hasMaxAge {
+		issue(CacheConflicting, SeverityLow, "invalid max-age "+strconv.Quote(cacheControl.Directives["max-age"]))
+	}
+	if policy.Storable && policy.ETag == "" && policy.LastModified.IsZero() &&
+		(cacheControl.Has("no-cache") || (policy.TTL == 0 && explicit)) {
+		issue(CacheNoValidators, SeverityLow, "stored but never fresh and no ETag nor Last-Modified to revalidate")
+	}
+	if listContains(policy.Vary, "*") {
+		issue(CacheVaryStar, SeverityMedium, "Vary: * prevents reuse")
+	}
+	if _, ok := headers["set-cookie"]; ok && shared && policy.Storable && !private && !listContains(cacheControl.Fields("no-cache"), "set-cookie") {
+		issue(CacheSharedSetCookie, SeverityHigh, "Set-Cookie on a response storable by shared caches")
+	}
+
+	return policy
+}
+
+// CurrentAge returns the age of the response at now.
+func (p *CachePolicy) CurrentAge(now time.Time) time.Duration {
+	if p.ResponseTime.IsZero() {
+		return p.Age
+	}
+	return p.Age + now.Sub(p.ResponseTime)
+}
+
+// FreshAt reports whether the response may be reused without validation at now.
+func (p *CachePolicy) FreshAt(now time.Time) bool {
+	return p.Fresh && p.FreshnessLifetime > p.CurrentAge(now)
+}
+
+// Cache Report
+// ----------------------------------------------------------------------
+
+// CacheEndpoint is the caching behaviour of the rounds of a pack key. Url,
+// Storable, Reason, FreshnessLifetime and Heuristic come from the latest round;
+// Issues are gathered from every round and Affected counts the rounds per issue
+// message.
+type CacheEndpoint struct {
+	Key               string         `json:"key"`
+	Url               string         `json:"url"`
+	Host              string         `json:"host"`
+	Rounds            int            `json:"rounds"`
+	Storable          bool           `json:"storable"`
+	Reason            string         `json:"reason"`
+	FreshnessLifetime time.Duration  `json:"freshnessLifetime"`
+	Heuristic         bool           `json:"heuristic"`
+	Issues            []CacheIssue   `json:"issues"`
+	Affected          map[string]int `json:"affected"`
+}
+
+// Misconfigured reports whether the endpoint has an issue above SeverityInfo.
+func (e *CacheEndpoint) Misconfigured() bool {
+	for _, issue := range e.Issues {
+		if issue.Severity > SeverityInfo {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheReport is the result of CacheReportPack. Endpoints lists the uncacheable or
+// misconfigured URLs, sorted by pack key.
+type CacheReport struct {
+	Scope         CacheScope      `json:"scope"`
+	Total         int             `json:"total"`
+	Cacheable     int             `json:"cacheable"`
+	Uncacheable   int             `json:"uncacheable"`
+	Misconfigured int             `json:"misconfigured"`
+	Endpoints     []CacheEndpoint `json:"endpoints"`
+}
+
+// CacheReportPack evaluates the cache policy of every round of a ResponsePack or
+// CompressResponsePack for scope and reports the URLs that are not cacheable or
+// carry caching issues.
+func CacheReportPack(pack Pack, scope CacheScope) (*CacheReport, error) {
+	if pack == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+
+	endpoints := map[string]*CacheEndpoint{}
+	err := forEachRound(pack, func(key string, round int, response *Response) error {
+		endpoint, ok := endpoints[key]
+		if !ok {
+			host := response.Host
+			if host == "" {
+				host = hostOf(response.Url)
+			}
+			endpoint = &CacheEndpoint{Key: key, Host: host, Issues: []CacheIssue{}, Affected: map[string]int{}}
+			endpoints[key] = endpoint
+		}
+
+		policy := response.CachePolicy(scope)
+		endpoint.Url = response.Url
+		endpoint.Rounds++
+		endpoint.Storable = policy.Storable
+		endpoint.Reason = policy.Reason
+		endpoint.FreshnessLifetime = policy.FreshnessLifetime
+		endpoint.Heuristic = policy.Heuristic
+		for _, issue := range policy.Issues {
+			if endpoint.Affected[issue.Message] == 0 {
+				endpoint.Issues = append(endpoint.Issues, issue)
+			}
+			endpoint.Affected[issue.Message]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CacheReport{Scope: scope, Endpoints: []CacheEndpoint{}}
+	for _, key := range sortedKeys(endpoints) {
+		endpoint := endpoints[key]
+		report.Total++
+		if endpoint.Storable {
+			report.Cacheable++
+		} else {
+			report.Uncacheable++
+		}
+		misconfigured := endpoint.Misconfigured()
+		if misconfigured {
+			report.Misconfigured++
+		}
+		if !endpoint.Storable || misconfigured {
+			sort.SliceStable(endpoint.Issues, func(i, j int) bool {
+				return endpoint.Issues[i].Severity > endpoint.Issues[j].Severity
+			})
+			report.Endpoints = append(report.Endpoints, *endpoint)
+		}
+	}
+	return report, nil
+}
+
+// ToJSON converts the report to a JSON-encoded byte slice.
+func (r *CacheReport) ToJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// ToString returns a text report with the totals and one block per endpoint.
+func (r *CacheReport) ToString() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Cache Report (%s):\n\tTotal: %d\n\tCacheable: %d\n\tUncacheable: %d\n\tMisconfigured: %d",
+		r.Scope, r.Total, r.Cacheable, r.Uncacheable, r.Misconfigured))
+	for _, endpoint := range r.Endpoints {
+		sb.WriteString(fmt.Sprintf("\nURL: %s (%d rounds)", endpoint.Url, endpoint.Rounds))
+		if endpoint.Storable {
+			sb.WriteString(fmt.Sprintf("\n\tStorable: true, lifetime %s", endpoint.FreshnessLifetime))
+		} else {
+			sb.WriteString("\n\tStorable: false, " + endpoint.Reason)
+		}
+		for _, issue := range endpoint.Issues {
+			sb.WriteString(fmt.Sprintf("\n\t[%s] %s: %s (%d/%d)", issue.Severity, issue.Code, issue.Message, endpoint.Affected[issue.Message], endpoint.Rounds))
+		}
+	}
+	return sb.String()
+}
+
+// Print prints the text report to the console.
+func (r *CacheReport) Print() {
+	fmt.Println(r.ToString())
+}
@@ -0,0 +1,260 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// Validators
+// ----------------------------------------------------------------------
+
+// Validators identify the version of a resource. LastModified is zero when the
+// header is absent or invalid; BodyHash is the hex SHA-256 of the body.
+type Validators struct {
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"lastModified"`
+	BodyHash     string    `json:"bodyHash"`
+}
+
+// Validators returns the ETag, Last-Modified and body hash of the response.
+func (r *Response) Validators() Validators {
+	headers := lowerHeaders(r.Headers)
+	lastModified, _ := http.ParseTime(headers["last-modified"])
+	sum := sha256.Sum256(r.Body)
+	return Validators{
+		ETag:         strings.TrimSpace(headers["etag"]),
+		LastModified: lastModified,
+		BodyHash:     hex.EncodeToString(sum[:]),
+	}
+}
+
+// ConditionalHeaders returns the If-None-Match and If-Modified-Since headers that
+// revalidate the response. The map is empty when the response has no validator.
+func (r *Response) ConditionalHeaders() map[string]string {
+	return r.Validators().conditionalHeaders()
+}
+
+// conditionalHeaders builds the conditional request headers of v.
+func (v Validators) conditionalHeaders() map[string]string {
+	headers := map[string]string{}
+	if v.ETag != "" {
+		headers["If-None-Match"] = v.ETag
+	}
+	if !v.LastModified.IsZero() {
+		headers["If-Modified-Since"] = v.LastModified.UTC().Format(http.TimeFormat)
+	}
+	return headers
+}
+
+// sameETag compares entity tags with the weak comparison of RFC 9110, 8.8.3.2.
+func sameETag(a string, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// weakETag reports whether etag is a weak entity tag, see RFC 9110, 8.8.3.
+func weakETag(etag string) bool {
+	return strings.HasPrefix(etag, "W/")
+}
+
+// Change Tracking
+// ----------------------------------------------------------------------
+
+// Reasons of a ChangeEvent, i.e. the validator that detected the change.
+const (
+	ChangedETag         = "etag"
+	ChangedLastModified = "last-modified"
+	ChangedBody         = "body"
+)
+
+// Codes of the inconsistencies reported by TrackChanges.
+const (
+	ChangeStaleETag         = "stale-etag"
+	ChangeUnstableETag      = "unstable-etag"
+	ChangeStaleLastModified = "stale-last-modified"
+	ChangeLastModifiedBack  = "last-modified-regressed"
+)
+
+// ChangeEvent is a change of the resource between the previous round and round
+// Round, the sequence number of the round, see Round.
+type ChangeEvent struct {
+	Round  int        `json:"round"`
+	Reason string     `json:"reason"`
+	Before Validators `json:"before"`
+	After  Validators `json:"after"`
+}
+
+// ChangeIssue is an inconsistency between the validators and the body of two rounds,
+// Round being the sequence number of the later one.
+type ChangeIssue struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Round    int      `json:"round"`
+	Message  string   `json:"message"`
+}
+
+// URLChanges is the change history of the rounds of a pack key. Url is the URL of
+// the latest round, Latest holds its validators and Conditional the request
+// headers that revalidate it.
+type URLChanges struct {
+	Key         string            `json:"key"`
+	Url         string            `json:"url"`
+	Rounds      int               `json:"rounds"`
+	Changes     int               `json:"changes"`
+	Events      []ChangeEvent     `json:"events"`
+	Issues      []ChangeIssue     `json:"issues"`
+	Latest      Validators        `json:"latest"`
+	Conditional map[string]string `json:"conditional"`
+}
+
+// ChangeReport is the result of TrackChanges, one entry per pack key sorted by key.
+type ChangeReport struct {
+	Urls []URLChanges `json:"urls"`
+}
+
+// TrackURLChanges compares consecutive rounds of url.
+//
+// A change is detected with the ETag when both rounds have one, then with
+// Last-Modified, then with the body hash. 304 Not Modified rounds never change the
+// resource. Bodies of rounds with the same status are always compared, so an ETag
+// or Last-Modified that stays the same while the body differs is reported, unless
+// the ETag is weak. Rounds are numbered by their sequence number, see History.
+func TrackURLChanges(pack Pack, url string) (*URLChanges, error) {
+	if pack == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+	rounds, err := pack.History(url)
+	if err != nil {
+		return nil, err
+	}
+	return trackChanges(url, rounds), nil
+}
+
+// TrackChanges runs TrackURLChanges on every URL of a ResponsePack or
+// CompressResponsePack.
+func TrackChanges(pack Pack) (*ChangeReport, error) {
+	if pack == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+
+	rounds := map[string][]Round{}
+	err := forEachRound(pack, func(key string, round int, response *Response) error {
+		rounds[key] = append(rounds[key], Round{Seq: round, Response: response})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ChangeReport{Urls: []URLChanges{}}
+	for _, key := range sortedKeys(rounds) {
+		report.Urls = append(report.Urls, *trackChanges(key, rounds[key]))
+	}
+	return report, nil
+}
+
+// trackChanges compares consecutive rounds of a pack key. Events and issues carry
+// the sequence number of their round, see Round.
+func trackChanges(key string, rounds []Round) *URLChanges {
+	changes := &URLChanges{
+		Key:         key,
+		Rounds:      len(rounds),
+		Events:      []ChangeEvent{},
+		Issues:      []ChangeIssue{},
+		Conditional: map[string]string{},
+	}
+	issue := func(code string, severity Severity, round int, message string) {
+		changes.Issues = append(changes.Issues, ChangeIssue{Code: code, Severity: severity, Round: round, Message: message})
+	}
+
+	// The current version of the resource, carried over 304 rounds
+	var previous *Response
+	var before Validators
+	for _, entry := range rounds {
+		round, response := entry.Seq, entry.Response
+		if response.StatusCode == codes.NotModified && previous != nil {
+			continue
+		}
+		after := response.Validators()
+		if previous == nil {
+			previous, before = response, after
+			continue
+		}
+
+		sameStatus := previous.StatusCode == response.StatusCode
+		bodyChanged := before.BodyHash != after.BodyHash
+		reason := ""
+		switch {
+		case before.ETag != "" && after.ETag != "":
+			if !sameETag(before.ETag, after.ETag) {
+				reason = ChangedETag
+			} else if bodyChanged && sameStatus && !weakETag(before.ETag) && !weakETag(after.ETag) {
+				// A weak ETag allows semantically equivalent bodies to differ
+				issue(ChangeStaleETag, SeverityMedium, round, "ETag "+after.ETag+" unchanged while the body changed")
+			}
+			if reason != "" && !bodyChanged {
+				issue(ChangeUnstableETag, SeverityLow, round, "ETag changed from "+before.ETag+" to "+after.ETag+" with the same body")
+			}
+		case !before.LastModified.IsZero() && !after.LastModified.IsZero():
+			if after.LastModified.Before(before.LastModified) {
+				issue(ChangeLastModifiedBack, SeverityLow, round, "Last-Modified went back to "+after.LastModified.UTC().Format(http.TimeFormat))
+			}
+			if !after.LastModified.Equal(before.LastModified) {
+				reason = ChangedLastModified
+			} else if bodyChanged && sameStatus {
+				issue(ChangeStaleLastModified, SeverityMedium, round, "Last-Modified unchanged while the body changed")
+			}
+		case bodyChanged:
+			reason = ChangedBody
+		}
+
+		if reason != "" {
+			changes.Changes++
+			changes.Events = append(changes.Events, ChangeEvent{Round: round, Reason: reason, Before: before, After: after})
+		}
+		previous, before = response, after
+	}
+
+	if len(rounds) > 0 {
+		latest := rounds[len(rounds)-1].Response
+		changes.Url = latest.Url
+		if latest.StatusCode == codes.NotModified && previous != nil {
+			latest = previous
+		}
+		changes.Latest = latest.Validators()
+		changes.Conditional = changes.Latest.conditionalHeaders()
+	}
+	return changes
+}
+
+// ToJSON converts the report to a JSON-encoded byte slice.
+func (r *ChangeReport) ToJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// ToString returns a text report, one block per URL with its change events and issues.
+func (r *ChangeReport) ToString() string {
+	var sb strings.Builder
+	sb.WriteString("Change Report:")
+	for _, url := range r.Urls {
+		sb.WriteString(fmt.Sprintf("\nURL: %s (%d rounds, %d changes)", url.Url, url.Rounds, url.Changes))
+		for _, event := range url.Events {
+			sb.WriteString(fmt.Sprintf("\n\tround %d: changed (%s)", event.Round, event.Reason))
+		}
+		for _, issue := range url.Issues {
+			sb.WriteString(fmt.Sprintf("\n\t[%s] round %d %s: %s", issue.Severity, issue.Round, issue.Code, issue.Message))
+		}
+	}
+	return sb.String()
+}
+
+// Print prints the text report to the console.
+func (r *ChangeReport) Print() {
+	fmt.Println(r.ToString())
+}
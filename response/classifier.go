@@ -0,0 +1,119 @@
+package response
+
+import (
+	"fmt"
+
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// Success Classifier
+// ----------------------------------------------------------------------
+
+// Outcome is how a Classifier counts a Response in the pack statistics.
+type Outcome int
+
+const (
+	// OutcomeSuccess counts the response in Success.
+	OutcomeSuccess Outcome = iota
+	// OutcomeFailure counts the response in Failure.
+	OutcomeFailure
+	// OutcomeIgnored counts the response in Ignored only; it is left out of the ratios.
+	OutcomeIgnored
+)
+
+// String returns "success", "failure" or "ignored".
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeFailure:
+		return "failure"
+	case OutcomeIgnored:
+		return "ignored"
+	default:
+		return fmt.Sprintf("Outcome(%d)", int(o))
+	}
+}
+
+// Classifier decides whether a Response is a success, a failure or ignored.
+type Classifier interface {
+	Classify(response *Response) Outcome
+}
+
+// ClassifierFunc adapts a function to the Classifier interface.
+type ClassifierFunc func(response *Response) Outcome
+
+// Classify calls f(response).
+func (f ClassifierFunc) Classify(response *Response) Outcome {
+	return f(response)
+}
+
+// outcomeOf returns OutcomeSuccess when ok, OutcomeFailure otherwise.
+func outcomeOf(ok bool) Outcome {
+	if ok {
+		return OutcomeSuccess
+	}
+	return OutcomeFailure
+}
+
+// SuccessClassifier counts 2xx responses as successes and everything else as
+// failures. It is the default classifier of a ResponsePack.
+func SuccessClassifier() Classifier {
+	return ClassifierFunc(func(response *Response) Outcome {
+		return outcomeOf(codes.IsSuccess(response.StatusCode))
+	})
+}
+
+// SuccessOrRedirectClassifier counts 2xx and 3xx responses as successes, e.g. to
+// accept 304 Not Modified.
+func SuccessOrRedirectClassifier() Classifier {
+	return ClassifierFunc(func(response *Response) Outcome {
+		return outcomeOf(response.StatusCode >= 200 && response.StatusCode < 400)
+	})
+}
+
+// StatusAllowListClassifier counts the listed status codes as successes and every
+// other status as a failure.
+func StatusAllowListClassifier(statuses ...codes.StatusCode) Classifier {
+	allowed := make(map[codes.StatusCode]bool, len(statuses))
+	for _, status := range statuses {
+		allowed[status] = true
+	}
+	return ClassifierFunc(func(response *Response) Outcome {
+		return outcomeOf(allowed[response.StatusCode])
+	})
+}
+
+// BodyPredicateClassifier refines base with the body: a response base counts as a
+// success is a failure when predicate returns false for its body. Failures and
+// ignored responses of base are kept. A nil base means SuccessClassifier.
+func BodyPredicateClassifier(base Classifier, predicate func(body []byte) bool) Classifier {
+	if base == nil {
+		base = SuccessClassifier()
+	}
+	return ClassifierFunc(func(response *Response) Outcome {
+		outcome := base.Classify(response)
+		if outcome == OutcomeSuccess && predicate != nil && !predicate(response.Body) {
+			return OutcomeFailure
+		}
+		return outcome
+	})
+}
+
+// IgnoreStatusClassifier ignores the listed status codes, e.g. an expected 404, and
+// classifies the others with base. A nil base means SuccessClassifier.
+func IgnoreStatusClassifier(base Classifier, statuses ...codes.StatusCode) Classifier {
+	if base == nil {
+		base = SuccessClassifier()
+	}
+	ignored := make(map[codes.StatusCode]bool, len(statuses))
+	for _, status := range statuses {
+		ignored[status] = true
+	}
+	return ClassifierFunc(func(response *Response) Outcome {
+		if ignored[response.StatusCode] {
+			return OutcomeIgnored
+		}
+		return base.Classify(response)
+	})
+}
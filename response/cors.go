@@ -0,0 +1,332 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// CORS Analyzer
+// ----------------------------------------------------------------------
+
+// Codes of the issues reported by AnalyzeCORS.
+const (
+	CORSMissingAllowOrigin     = "missing-allow-origin"
+	CORSOriginMismatch         = "origin-mismatch"
+	CORSMultipleOrigins        = "multiple-allow-origin"
+	CORSNullOrigin             = "null-origin"
+	CORSWildcardCredentials    = "wildcard-with-credentials"
+	CORSCredentialsNotAllowed  = "credentials-not-allowed"
+	CORSMissingVaryOrigin      = "missing-vary-origin"
+	CORSPreflightStatus        = "preflight-status"
+	CORSPreflightMethod        = "preflight-method"
+	CORSPreflightHeaders       = "preflight-headers"
+	CORSPreflightWildcardCreds = "preflight-wildcard-with-credentials"
+)
+
+// CORSOptions describes the browser request a response is evaluated against.
+// Empty fields are taken from the recorded request (Response.Request) when present:
+// Origin, Access-Control-Request-Method and Access-Control-Request-Headers, and
+// Credentials when it carried a Cookie or Authorization header.
+type CORSOptions struct {
+	Origin         string
+	Credentials    bool
+	RequestMethod  string
+	RequestHeaders []string
+}
+
+// CORSIssue is a CORS misconfiguration found in a response.
+type CORSIssue struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// CORSResult is the evaluation of a single response.
+//
+// CORS is false when neither an Origin nor any Access-Control-* header is present,
+// in which case the response is not a cross-origin exchange. Allowed reports
+// whether a browser would expose the response (or accept the preflight).
+type CORSResult struct {
+	Url         string      `json:"url"`
+	Origin      string      `json:"origin"`
+	CORS        bool        `json:"cors"`
+	Preflight   bool        `json:"preflight"`
+	Credentials bool        `json:"credentials"`
+	Allowed     bool        `json:"allowed"`
+	Issues      []CORSIssue `json:"issues"`
+}
+
+// AnalyzeCORS evaluates whether a browser would accept response for the request
+// described by options.
+func AnalyzeCORS(response *Response, options CORSOptions) *CORSResult {
+	if response == nil {
+		return nil
+	}
+
+	options = corsOptionsFromRequest(response, options)
+	headers := lowerHeaders(response.Headers)
+
+	result := &CORSResult{
+		Url:         response.Url,
+		Origin:      options.Origin,
+		Preflight:   response.Method == codes.OPTIONS && options.RequestMethod != "",
+		Credentials: options.Credentials,
+		Allowed:     true,
+		Issues:      []CORSIssue{},
+	}
+
+	allowOrigin, hasAllowOrigin := headers["access-control-allow-origin"]
+	result.CORS = options.Origin != "" || hasCORSHeaders(headers)
+	if !result.CORS {
+		return result
+	}
+
+	reject := func(code string, severity Severity, message string) {
+		result.Allowed = false
+		result.Issues = append(result.Issues, CORSIssue{Code: code, Severity: severity, Message: message})
+	}
+	warn := func(code string, severity Severity, message string) {
+		result.Issues = append(result.Issues, CORSIssue{Code: code, Severity: severity, Message: message})
+	}
+
+	allowOrigin = strings.TrimSpace(allowOrigin)
+	allowCredentials := strings.EqualFold(strings.TrimSpace(headers["access-control-allow-credentials"]), "true")
+
+	// Access-Control-Allow-Origin
+	switch {
+	case !hasAllowOrigin || allowOrigin == "":
+		reject(CORSMissingAllowOrigin, SeverityInfo, "missing Access-Control-Allow-Origin")
+	case strings.Contains(allowOrigin, ","):
+		reject(CORSMultipleOrigins, SeverityMedium, "Access-Control-Allow-Origin lists several origins: "+allowOrigin)
+	case allowOrigin == "*":
+		switch {
+		case options.Credentials:
+			reject(CORSWildcardCredentials, SeverityHigh, "credentialed request rejected by Access-Control-Allow-Origin *")
+		case allowCredentials:
+			warn(CORSWildcardCredentials, SeverityHigh, "Access-Control-Allow-Origin * combined with Access-Control-Allow-Credentials true")
+		}
+	case strings.EqualFold(allowOrigin, "null"):
+		warn(CORSNullOrigin, SeverityMedium, "Access-Control-Allow-Origin null allows sandboxed and file origins")
+		if options.Origin != "" && options.Origin != "null" {
+			reject(CORSOriginMismatch, SeverityInfo, "origin "+options.Origin+" does not match null")
+		}
+	default:
+		if options.Origin != "" && allowOrigin != options.Origin {
+			reject(CORSOriginMismatch, SeverityInfo, "origin "+options.Origin+" does not match "+allowOrigin)
+		}
+		if !varyContains(headers["vary"], "origin") {
+			warn(CORSMissingVaryOrigin, SeverityMedium, "Access-Control-Allow-Origin "+allowOrigin+" without Vary: Origin")
+		}
+	}
+
+	// Credentials
+	if options.Credentials && allowOrigin != "*" && hasAllowOrigin && !allowCredentials {
+		reject(CORSCredentialsNotAllowed, SeverityInfo, "credentialed request without Access-Control-Allow-Credentials true")
+	}
+
+	// Preflight
+	if result.Preflight {
+		if !codes.IsSuccess(response.StatusCode) {
+			reject(CORSPreflightStatus, SeverityMedium, fmt.Sprintf("preflight answered with status %d", response.StatusCode))
+		}
+
+		allowMethods := splitList(headers["access-control-allow-methods"])
+		if !corsSafelistedMethod(options.RequestMethod) && !listAllows(allowMethods, options.RequestMethod, options.Credentials) {
+			reject(CORSPreflightMethod, SeverityMedium, "method "+options.RequestMethod+" not in Access-Control-Allow-Methods")
+		}
+
+		allowHeaders := splitList(headers["access-control-allow-headers"])
+		for _, header := range options.RequestHeaders {
+			if !listAllows(allowHeaders, header, options.Credentials) {
+				reject(CORSPreflightHeaders, SeverityMedium, "header "+header+" not in Access-Control-Allow-Headers")
+			}
+		}
+
+		if options.Credentials && (listContains(allowMethods, "*") || listContains(allowHeaders, "*")) {
+			warn(CORSPreflightWildcardCreds, SeverityLow, "wildcard in Access-Control-Allow-Methods or -Headers is literal for credentialed requests")
+		}
+	}
+
+	return result
+}
+
+// corsOptionsFromRequest fills empty options from the recorded request.
+func corsOptionsFromRequest(response *Response, options CORSOptions) CORSOptions {
+	if response.Request == nil {
+		return options
+	}
+	headers := lowerHeaders(response.Request.Headers)
+	if options.Origin == "" {
+		options.Origin = headers["origin"]
+	}
+	if options.RequestMethod == "" {
+		options.RequestMethod = headers["access-control-request-method"]
+	}
+	if len(options.RequestHeaders) == 0 {
+		options.RequestHeaders = splitList(headers["access-control-request-headers"])
+	}
+	if !options.Credentials {
+		_, hasCookie := headers["cookie"]
+		_, hasAuthorization := headers["authorization"]
+		options.Credentials = hasCookie || hasAuthorization
+	}
+	return options
+}
+
+// hasCORSHeaders reports whether any Access-Control-* header is present.
+func hasCORSHeaders(headers map[string]string) bool {
+	for name := range headers {
+		if strings.HasPrefix(name, "access-control-") {
+			return true
+		}
+	}
+	return false
+}
+
+// varyContains reports whether the Vary header lists name or "*".
+func varyContains(vary string, name string) bool {
+	for _, value := range splitList(vary) {
+		if value == "*" || strings.EqualFold(value, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma-separated header value into trimmed, non-empty items.
+func splitList(value string) []string {
+	var output []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			output = append(output, item)
+		}
+	}
+	return output
+}
+
+// listContains reports whether list holds value, case-insensitively.
+func listContains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// listAllows reports whether an Access-Control-Allow-* list allows value. "*" is a
+// wildcard only for requests without credentials.
+func listAllows(list []string, value string, credentials bool) bool {
+	if !credentials && listContains(list, "*") {
+		return true
+	}
+	return listContains(list, value)
+}
+
+// corsSafelistedMethod reports whether method never needs to be allowed by a preflight.
+func corsSafelistedMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "POST":
+		return true
+	default:
+		return false
+	}
+}
+
+// CORS pack summary
+// ----------------------------------------------------------------------
+
+// HostCORSSummary aggregates CORS results for a host. Issues counts issues per code
+// and Results keeps the cross-origin results that carry issues.
+type HostCORSSummary struct {
+	Host        string         `json:"host"`
+	Responses   int            `json:"responses"`
+	CrossOrigin int            `json:"crossOrigin"`
+	Allowed     int            `json:"allowed"`
+	Rejected    int            `json:"rejected"`
+	Issues      map[string]int `json:"issues"`
+	Results     []*CORSResult  `json:"results"`
+}
+
+// CORSReport is the result of AnalyzeCORSPack, one summary per host sorted by host.
+type CORSReport struct {
+	Hosts []HostCORSSummary `json:"hosts"`
+}
+
+// AnalyzeCORSPack runs AnalyzeCORS on every round of a ResponsePack or
+// CompressResponsePack with the same options and groups the results by host.
+func AnalyzeCORSPack(pack Pack, options CORSOptions) (*CORSReport, error) {
+	if pack == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+
+	hosts := map[string]*HostCORSSummary{}
+	err := forEachRound(pack, func(key string, round int, response *Response) error {
+		host := response.Host
+		if host == "" {
+			host = hostOf(response.Url)
+		}
+		summary, ok := hosts[host]
+		if !ok {
+			summary = &HostCORSSummary{Host: host, Issues: map[string]int{}, Results: []*CORSResult{}}
+			hosts[host] = summary
+		}
+		summary.Responses++
+
+		result := AnalyzeCORS(response, options)
+		if !result.CORS {
+			return nil
+		}
+		result.Url = response.Url
+		summary.CrossOrigin++
+		if result.Allowed {
+			summary.Allowed++
+		} else {
+			summary.Rejected++
+		}
+		for _, issue := range result.Issues {
+			summary.Issues[issue.Code]++
+		}
+		if len(result.Issues) > 0 {
+			summary.Results = append(summary.Results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CORSReport{Hosts: []HostCORSSummary{}}
+	for _, host := range sortedKeys(hosts) {
+		report.Hosts = append(report.Hosts, *hosts[host])
+	}
+	return report, nil
+}
+
+// ToJSON converts the report to a JSON-encoded byte slice.
+func (r *CORSReport) ToJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// ToString returns a text summary of the report, one block per host.
+func (r *CORSReport) ToString() string {
+	var sb strings.Builder
+	sb.WriteString("CORS Report:")
+	for _, host := range r.Hosts {
+		sb.WriteString(fmt.Sprintf("\nHost: %s\n\tResponses: %d\n\tCrossOrigin: %d\n\tAllowed: %d\n\tRejected: %d",
+			host.Host, host.Responses, host.CrossOrigin, host.Allowed, host.Rejected))
+		for _, code := range sortedKeys(host.Issues) {
+			sb.WriteString(fmt.Sprintf("\n\t%s: %d", code, host.Issues[code]))
+		}
+	}
+	return sb.String()
+}
+
+// Print prints the text summary to the console.
+func (r *CORSReport) Print() {
+	fmt.Println(r.ToString())
+}
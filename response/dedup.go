@@ -0,0 +1,335 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// Body Deduplication
+// ----------------------------------------------------------------------
+
+// DedupStats describes the bodies a pack shares between its rounds, see
+// ConfigResponsePack.DedupBodies and ConfigCompressResponsePack.DedupBodies.
+//
+// Blobs and UniqueBytes count the distinct bodies, References and ReferencedBytes
+// the rounds referencing them. StoredBytes is what the pack keeps for the blobs,
+// gzipped in a CompressResponsePack. Empty bodies are not shared.
+type DedupStats struct {
+	Blobs           uint64 `json:"blobs"`
+	References      uint64 `json:"references"`
+	UniqueBytes     int64  `json:"uniqueBytes"`
+	ReferencedBytes int64  `json:"referencedBytes"`
+	StoredBytes     int64  `json:"storedBytes"`
+}
+
+// Ratio returns ReferencedBytes divided by UniqueBytes, 0 without shared bodies.
+func (s DedupStats) Ratio() float64 {
+	if s.UniqueBytes == 0 {
+		return 0
+	}
+	return float64(s.ReferencedBytes) / float64(s.UniqueBytes)
+}
+
+// Saved returns the body bytes the pack does not store thanks to deduplication.
+func (s DedupStats) Saved() int64 {
+	return s.ReferencedBytes - s.UniqueBytes
+}
+
+// String returns the statistics on one line.
+func (s DedupStats) String() string {
+	return fmt.Sprintf("%d blobs, %d references, unique %d B, referenced %d B, ratio %.2f",
+		s.Blobs, s.References, s.UniqueBytes, s.ReferencedBytes, s.Ratio())
+}
+
+// bodyHash is the SHA-256 of a body.
+type bodyHash [sha256.Size]byte
+
+// hashBody returns the hash a body is stored under.
+func hashBody(body []byte) bodyHash {
+	return sha256.Sum256(body)
+}
+
+// blob is a body shared by the rounds referencing it.
+type blob struct {
+	data []byte
+	size int64
+	refs int
+}
+
+// blobStore keeps one blob per distinct body, counting its references. Its
+// methods are called under the write lock of the pack, has under the read lock.
+type blobStore struct {
+	blobs map[bodyHash]*blob
+	stats DedupStats
+}
+
+// newBlobStore returns an empty store, nil when deduplication is off.
+func newBlobStore(enabled bool) *blobStore {
+	if !enabled {
+		return nil
+	}
+	return &blobStore{blobs: map[bodyHash]*blob{}}
+}
+
+// has reports whether the body of hash is stored.
+func (s *blobStore) has(hash bodyHash) bool {
+	_, ok := s.blobs[hash]
+	return ok
+}
+
+// acquire adds a reference to the body of hash, of size bytes, and returns its
+// stored data, capped at its length so that appending to it copies it. data is
+// called for the data to store when the body is new.
+func (s *blobStore) acquire(hash bodyHash, size int64, data func() []byte) []byte {
+	b, ok := s.blobs[hash]
+	if !ok {
+		b = &blob{data: data(), size: size}
+		s.blobs[hash] = b
+		s.stats.Blobs++
+		s.stats.UniqueBytes += size
+		s.stats.StoredBytes += int64(len(b.data))
+	}
+	b.refs++
+	s.stats.References++
+	s.stats.ReferencedBytes += size
+	return b.data[:len(b.data):len(b.data)]
+}
+
+// get returns the stored data of hash, nil when it is not stored.
+func (s *blobStore) get(hash bodyHash) []byte {
+	if b, ok := s.blobs[hash]; ok {
+		return b.data
+	}
+	return nil
+}
+
+// release drops a reference to the body of hash, and the blob with its last
+// reference.
+func (s *blobStore) release(hash bodyHash) {
+	b, ok := s.blobs[hash]
+	if !ok {
+		return
+	}
+	b.refs--
+	s.stats.References--
+	s.stats.ReferencedBytes -= b.size
+	if b.refs > 0 {
+		return
+	}
+	delete(s.blobs, hash)
+	s.stats.Blobs--
+	s.stats.UniqueBytes -= b.size
+	s.stats.StoredBytes -= int64(len(b.data))
+}
+
+// reset drops every blob.
+func (s *blobStore) reset() {
+	s.blobs = map[bodyHash]*blob{}
+	s.stats = DedupStats{}
+}
+
+// dedupStats returns the statistics of the store, zero when it is nil.
+func (s *blobStore) dedupStats() DedupStats {
+	if s == nil {
+		return DedupStats{}
+	}
+	return s.stats
+}
+
+// gzipBytes returns data gzipped. Writing to memory cannot fail.
+func gzipBytes(data []byte) []byte {
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	_, _ = gz.Write(data)
+	_ = gz.Close()
+	return buffer.Bytes()
+}
+
+// gunzipBytes returns data decompressed.
+func gunzipBytes(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer reader.Close()
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress data: %w", err)
+	}
+	return output, nil
+}
+
+// ResponsePack
+// ----------------------------------------------------------------------
+
+// Dedup returns the statistics of the bodies shared by the rounds of the pack, also
+// found in the statistics.
+func (p *ResponsePack) Dedup() DedupStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.Stats.Dedup
+}
+
+// dedupBody returns a shallow copy of response whose Body is the blob shared by the
+// rounds with the same body, response itself without deduplication. A RawResponse
+// ending with the body keeps only its head, so the blob is the only copy of the
+// body, see BodyShared. The rounds sharing a blob alias its bytes, which are
+// read-only, see ConfigResponsePack.DedupBodies. The caller holds the write lock.
+func (p *ResponsePack) dedupBody(response *Response) *Response {
+	if p.blobs == nil || len(response.Body) == 0 {
+		return response
+	}
+	body := response.Body
+	shared := p.blobs.acquire(hashBody(body), int64(len(body)), func() []byte {
+		return append([]byte(nil), body...)
+	})
+	p.Stats.Dedup = p.blobs.dedupStats()
+	if output := response.withHeadOnly(shared); output != response {
+		return output
+	}
+	output := *response
+	output.Body = shared
+	return &output
+}
+
+// releaseBody drops the reference of a stored round to its blob. The caller holds
+// the write lock.
+func (p *ResponsePack) releaseBody(response *Response) {
+	if p.blobs == nil || len(response.Body) == 0 {
+		return
+	}
+	p.blobs.release(hashBody(response.Body))
+	p.Stats.Dedup = p.blobs.dedupStats()
+}
+
+// CompressResponsePack
+// ----------------------------------------------------------------------
+
+// sharedBody is the blob a round of a CompressResponsePack references in place of
+// its body. rawHead is set when the round keeps only the head of its RawResponse,
+// the rest being the body.
+type sharedBody struct {
+	hash    bodyHash
+	rawHead bool
+}
+
+// pendingBody is the body of a round being added to a CompressResponsePack,
+// prepared outside the lock. gzipped is nil when the blob was already stored.
+type pendingBody struct {
+	sharedBody
+	size    int64
+	gzipped []byte
+}
+
+// compressedRound is a stored round copied out of a CompressResponsePack, to be
+// decompressed without the lock.
+type compressedRound struct {
+	data    []byte
+	body    []byte // gzipped shared body, nil without one
+	rawHead bool
+}
+
+// Dedup returns the statistics of the bodies shared by the rounds of the pack.
+func (r *CompressResponsePack) Dedup() DedupStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.blobs.dedupStats()
+}
+
+// splitBody returns response without its body, to be compressed, and the body to
+// share, nil without deduplication. It read locks the pack.
+func (r *CompressResponsePack) splitBody(response *Response) (*Response, *pendingBody) {
+	r.mu.RLock()
+	enabled := r.blobs != nil
+	r.mu.RUnlock()
+	if !enabled || len(response.Body) == 0 {
+		return response, nil
+	}
+
+	hash := hashBody(response.Body)
+	r.mu.RLock()
+	known := r.blobs.has(hash)
+	r.mu.RUnlock()
+
+	pending := &pendingBody{sharedBody: sharedBody{hash: hash}, size: int64(len(response.Body))}
+	if !known {
+		pending.gzipped = gzipBytes(response.Body)
+	}
+	output := *response
+	output.Body = nil
+	output.RawResponse = response.RawBytes()
+	if start := bodyStart(output.RawResponse); start >= 0 && bytes.Equal(output.RawResponse[start:], response.Body) {
+		output.RawResponse = output.RawResponse[:start:start]
+		pending.rawHead = true
+	}
+	return &output, pending
+}
+
+// referenceBody references the blob of a round added with the body pending. The
+// caller holds the write lock.
+func (r *CompressResponsePack) referenceBody(ref roundRef, pending *pendingBody, body []byte) {
+	if pending == nil {
+		return
+	}
+	r.blobs.acquire(pending.hash, pending.size, func() []byte {
+		// The blob was released since splitBody looked for it
+		if pending.gzipped == nil {
+			return gzipBytes(body)
+		}
+		return pending.gzipped
+	})
+	if r.bodies == nil {
+		r.bodies = map[roundRef]sharedBody{}
+	}
+	r.bodies[ref] = pending.sharedBody
+}
+
+// releaseBody drops the reference of a stored round to its blob, if any. The
+// caller holds the write lock.
+func (r *CompressResponsePack) releaseBody(ref roundRef) {
+	shared, ok := r.bodies[ref]
+	if !ok {
+		return
+	}
+	r.blobs.release(shared.hash)
+	delete(r.bodies, ref)
+}
+
+// resetBodies drops every blob. The caller holds the write lock.
+func (r *CompressResponsePack) resetBodies() {
+	r.bodies = map[roundRef]sharedBody{}
+	if r.blobs != nil {
+		r.blobs.reset()
+	}
+}
+
+// copyRound returns a stored round with its blob. The caller holds the lock.
+func (r *CompressResponsePack) copyRound(key string, seq int) compressedRound {
+	round := compressedRound{data: r.CompressedResponses[key][roundKey(seq)]}
+	if shared, ok := r.bodies[roundRef{key, seq}]; ok {
+		round.body = r.blobs.get(shared.hash)
+		round.rawHead = shared.rawHead
+	}
+	return round
+}
+
+// decompress returns the response of the round, with its shared body restored.
+func (c compressedRound) decompress() (*Response, error) {
+	response, err := NewResponseFromCompressed(c.data)
+	if err != nil || c.body == nil {
+		return response, err
+	}
+	body, err := gunzipBytes(c.body)
+	if err != nil {
+		return nil, err
+	}
+	response.Body = body
+	if c.rawHead {
+		response.RawResponse = append(response.RawResponse, body...)
+		response.shareBody()
+	}
+	return response, nil
+}
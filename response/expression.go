@@ -0,0 +1,721 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Query Language
+// ----------------------------------------------------------------------
+
+// ExpressionError is an error of ParseExpression. Offset is the byte offset of the
+// offending token in the source, 0 for the first byte.
+type ExpressionError struct {
+	Offset int    `json:"offset"`
+	Msg    string `json:"msg"`
+}
+
+// Error returns the message with its offset.
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("query: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Expression is a parsed query expression, see ParseExpression.
+type Expression struct {
+	source string
+	root   expressionNode
+}
+
+// ParseExpression parses a query expression such as
+//
+//	status>=500 AND host="api.internal" AND header[Content-Type]~"json"
+//
+// Comparisons are combined with AND, OR and NOT (or &&, || and !) and grouped with
+// parentheses; AND binds tighter than OR. A comparison is a field, an operator
+// (=, !=, >, >=, <, <=, ~ and !~ for regular expressions) and a value, quoted or
+// not. The fields are:
+//
+//	status           status code, or a class such as 5xx with = and !=
+//	method, host     compared case-insensitively
+//	url, body        the URL and the body, as text
+//	size             length of the body, in bytes
+//	header[Name]     a header, the name case-insensitive
+//	latency          Timing.Duration, e.g. latency>250ms
+//	time             Timing.Start, RFC 3339, e.g. time>="2026-05-01T12:00:00Z"
+//	tag              tag=smoke selects the responses with the tag
+//	json.path        a member of a JSON body, e.g. json.data.items.0.id=7
+//
+// A comparison on a field a response does not have (no Timing, no such header or
+// JSON member) does not select it, whatever the operator.
+func ParseExpression(source string) (*Expression, error) {
+	tokens, err := lexExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	parser := &expressionParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != tokenEnd {
+		return nil, &ExpressionError{Offset: token.offset, Msg: fmt.Sprintf("unexpected %s", token)}
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// Match reports whether the expression selects response.
+func (e *Expression) Match(response *Response) bool {
+	return e.root.match(response)
+}
+
+// Predicate returns the expression as a Predicate, to be used in a Query of
+// either pack. The comparisons of status, method, host and tag use the indexes of
+// an indexed ResponsePack.
+func (e *Expression) Predicate() Predicate {
+	return e
+}
+
+// candidates returns the rounds the indexes select for the expression.
+func (e *Expression) candidates(indexes *packIndexes) (roundSet, bool) {
+	return e.root.candidates(indexes)
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Nodes
+// ----------------------------------------------------------------------
+
+// expressionNode is a node of a parsed expression. candidates returns the rounds
+// the indexes select for the node, or false when they cannot answer it.
+type expressionNode interface {
+	match(response *Response) bool
+	candidates(indexes *packIndexes) (roundSet, bool)
+}
+
+type andNode struct{ left, right expressionNode }
+
+func (n andNode) match(response *Response) bool {
+	return n.left.match(response) && n.right.match(response)
+}
+
+func (n andNode) candidates(indexes *packIndexes) (roundSet, bool) {
+	left, leftOK := n.left.candidates(indexes)
+	right, rightOK := n.right.candidates(indexes)
+	switch {
+	case leftOK && rightOK:
+		return intersect(left, right), true
+	case leftOK:
+		return left, true
+	default:
+		return right, rightOK
+	}
+}
+
+type orNode struct{ left, right expressionNode }
+
+func (n orNode) match(response *Response) bool {
+	return n.left.match(response) || n.right.match(response)
+}
+
+func (n orNode) candidates(indexes *packIndexes) (roundSet, bool) {
+	left, ok := n.left.candidates(indexes)
+	if !ok {
+		return nil, false
+	}
+	right, ok := n.right.candidates(indexes)
+	if !ok {
+		return nil, false
+	}
+	output := roundSet{}
+	output.addAll(left)
+	output.addAll(right)
+	return output, true
+}
+
+type notNode struct{ operand expressionNode }
+
+func (n notNode) match(response *Response) bool {
+	return !n.operand.match(response)
+}
+
+func (n notNode) candidates(*packIndexes) (roundSet, bool) {
+	return nil, false
+}
+
+// comparisonNode is a comparison compiled into a Predicate.
+type comparisonNode struct{ predicate Predicate }
+
+func (n comparisonNode) match(response *Response) bool {
+	return n.predicate.Match(response)
+}
+
+func (n comparisonNode) candidates(indexes *packIndexes) (roundSet, bool) {
+	return candidatesOf(n.predicate, indexes)
+}
+
+// Lexer
+// ----------------------------------------------------------------------
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBracket
+	tokenRightBracket
+)
+
+type expressionToken struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// String describes the token for error messages.
+func (t expressionToken) String() string {
+	switch t.kind {
+	case tokenEnd:
+		return "end of query"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// isWordRune reports whether r can be part of an unquoted word: a field, a value
+// such as 5xx, 250ms or application/json, or a header name.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-./:+*", r)
+}
+
+// lexExpression splits source into tokens.
+func lexExpression(source string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	for offset := 0; offset < len(source); {
+		r, size := utf8.DecodeRuneInString(source[offset:])
+		start := offset
+
+		switch {
+		case unicode.IsSpace(r):
+			offset += size
+			continue
+		case r == '(' || r == ')' || r == '[' || r == ']':
+			kind := map[rune]tokenKind{'(': tokenLeftParen, ')': tokenRightParen, '[': tokenLeftBracket, ']': tokenRightBracket}[r]
+			tokens = append(tokens, expressionToken{kind: kind, text: string(r), offset: start})
+			offset += size
+			continue
+		case r == '"' || r == '\'':
+			text, end, err := lexString(source, offset)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, expressionToken{kind: tokenString, text: text, offset: start})
+			offset = end
+			continue
+		}
+
+		if operator := leadingOperator(source[offset:]); operator != "" {
+			kind := tokenOperator
+			switch operator {
+			case "&&":
+				kind = tokenAnd
+			case "||":
+				kind = tokenOr
+			case "!":
+				kind = tokenNot
+			}
+			tokens = append(tokens, expressionToken{kind: kind, text: operator, offset: start})
+			offset += len(operator)
+			continue
+		}
+
+		if !isWordRune(r) {
+			return nil, &ExpressionError{Offset: start, Msg: fmt.Sprintf("unexpected character %q", r)}
+		}
+		for offset < len(source) {
+			r, size = utf8.DecodeRuneInString(source[offset:])
+			if !isWordRune(r) {
+				break
+			}
+			offset += size
+		}
+		word := source[start:offset]
+		kind := tokenWord
+		switch strings.ToUpper(word) {
+		case "AND":
+			kind = tokenAnd
+		case "OR":
+			kind = tokenOr
+		case "NOT":
+			kind = tokenNot
+		}
+		tokens = append(tokens, expressionToken{kind: kind, text: word, offset: start})
+	}
+	return append(tokens, expressionToken{kind: tokenEnd, offset: len(source)}), nil
+}
+
+// leadingOperator returns the operator source starts with, longest first.
+func leadingOperator(source string) string {
+	for _, operator := range []string{"&&", "||", "!=", "!~", ">=", "<=", "==", "=", ">", "<", "~", "!"} {
+		if strings.HasPrefix(source, operator) {
+			return operator
+		}
+	}
+	return ""
+}
+
+// lexString reads the quoted string starting at offset, with backslash escapes,
+// and returns its content and the offset after the closing quote.
+func lexString(source string, offset int) (string, int, error) {
+	quote := source[offset]
+	var sb strings.Builder
+	for i := offset + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			if i+1 < len(source) {
+				i++
+				sb.WriteByte(source[i])
+			}
+		case quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(source[i])
+		}
+	}
+	return "", 0, &ExpressionError{Offset: offset, Msg: "unterminated string"}
+}
+
+// Parser
+// ----------------------------------------------------------------------
+
+type expressionParser struct {
+	tokens   []expressionToken
+	position int
+}
+
+func (p *expressionParser) peek() expressionToken {
+	return p.tokens[p.position]
+}
+
+func (p *expressionParser) next() expressionToken {
+	token := p.tokens[p.position]
+	if token.kind != tokenEnd {
+		p.position++
+	}
+	return token
+}
+
+// unexpected returns the error for token where what was expected.
+func unexpected(token expressionToken, what string) error {
+	return &ExpressionError{Offset: token.offset, Msg: fmt.Sprintf("expected %s, found %s", what, token)}
+}
+
+// parseOr parses and ( OR and )*.
+func (p *expressionParser) parseOr() (expressionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses unary ( AND unary )*.
+func (p *expressionParser) parseAnd() (expressionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+// parseUnary parses NOT unary, ( expression ) or a comparison.
+func (p *expressionParser) parseUnary() (expressionNode, error) {
+	switch token := p.peek(); token.kind {
+	case tokenNot:
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	case tokenLeftParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return nil, unexpected(closing, `")"`)
+		}
+		return inner, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+// parseComparison parses field operator value.
+func (p *expressionParser) parseComparison() (expressionNode, error) {
+	field := p.next()
+	if field.kind != tokenWord {
+		return nil, unexpected(field, "a field")
+	}
+
+	var argument string
+	if strings.EqualFold(field.text, "header") {
+		if open := p.next(); open.kind != tokenLeftBracket {
+			return nil, unexpected(open, `"[" after header`)
+		}
+		name := p.next()
+		if name.kind != tokenWord && name.kind != tokenString {
+			return nil, unexpected(name, "a header name")
+		}
+		argument = name.text
+		if closing := p.next(); closing.kind != tokenRightBracket {
+			return nil, unexpected(closing, `"]"`)
+		}
+	}
+
+	operator := p.next()
+	if operator.kind != tokenOperator || operator.text == "!" {
+		return nil, unexpected(operator, "a comparison operator")
+	}
+	if operator.text == "==" {
+		operator.text = "="
+	}
+
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, unexpected(value, "a value")
+	}
+
+	predicate, err := compileComparison(field, argument, operator, value)
+	if err != nil {
+		return nil, err
+	}
+	return comparisonNode{predicate}, nil
+}
+
+// Comparisons
+// ----------------------------------------------------------------------
+
+var statusClassPattern = regexp.MustCompile(`(?i)^[1-5]xx$`)
+
+// compileComparison turns a comparison into a Predicate, checking that the
+// operator and the value suit the field.
+func compileComparison(field expressionToken, argument string, operator, value expressionToken) (Predicate, error) {
+	name := strings.ToLower(field.text)
+	op := operator.text
+	invalidOperator := func(kind string) error {
+		return &ExpressionError{Offset: operator.offset, Msg: fmt.Sprintf("operator %s does not apply to %s", op, kind)}
+	}
+	invalidValue := func(what string) error {
+		return &ExpressionError{Offset: value.offset, Msg: fmt.Sprintf("expected %s, found %s", what, value)}
+	}
+
+	switch {
+	case name == "status":
+		if statusClassPattern.MatchString(value.text) {
+			if op != "=" && op != "!=" {
+				return nil, invalidOperator("a status class")
+			}
+			class := strings.ToLower(value.text)
+			return statusPredicate(func(status int) bool {
+				return (statusClass(status) == class) == (op == "=")
+			}), nil
+		}
+		number, err := strconv.ParseFloat(value.text, 64)
+		if err != nil {
+			return nil, invalidValue("a status code or class")
+		}
+		if isRegexOperator(op) {
+			return nil, invalidOperator("status")
+		}
+		return statusPredicate(func(status int) bool {
+			return compareOrdered(float64(status), number, op)
+		}), nil
+
+	case name == "size":
+		number, err := strconv.ParseFloat(value.text, 64)
+		if err != nil {
+			return nil, invalidValue("a size in bytes")
+		}
+		if isRegexOperator(op) {
+			return nil, invalidOperator("size")
+		}
+		return PredicateFunc(func(response *Response) bool {
+			return compareOrdered(float64(len(response.Body)), number, op)
+		}), nil
+
+	case name == "latency":
+		duration, err := time.ParseDuration(value.text)
+		if err != nil {
+			return nil, invalidValue("a duration such as 250ms")
+		}
+		if isRegexOperator(op) {
+			return nil, invalidOperator("latency")
+		}
+		return PredicateFunc(func(response *Response) bool {
+			return response.Timing != nil && compareOrdered(float64(response.Timing.Duration), float64(duration), op)
+		}), nil
+
+	case name == "time":
+		moment, err := time.Parse(time.RFC3339, value.text)
+		if err != nil {
+			return nil, invalidValue("an RFC 3339 time")
+		}
+		if isRegexOperator(op) {
+			return nil, invalidOperator("time")
+		}
+		return PredicateFunc(func(response *Response) bool {
+			return response.Timing != nil && compareOrdered(float64(response.Timing.Start.Sub(moment)), 0, op)
+		}), nil
+
+	case name == "tag":
+		switch op {
+		case "=":
+			return TaggedWith(value.text), nil
+		case "!=":
+			return Not(TaggedWith(value.text)), nil
+		case "~", "!~":
+			pattern, err := compileValuePattern(value)
+			if err != nil {
+				return nil, err
+			}
+			matchTag := indexedPredicate{field: IndexTag, accept: pattern.MatchString, match: func(response *Response) bool {
+				for _, tag := range response.Tags {
+					if pattern.MatchString(tag) {
+						return true
+					}
+				}
+				return false
+			}}
+			if op == "!~" {
+				return Not(matchTag), nil
+			}
+			return matchTag, nil
+		default:
+			return nil, invalidOperator("tag")
+		}
+
+	case name == "method" || name == "host" || name == "url" || name == "body" || name == "header":
+		if name == "header" {
+			argument = strings.ToLower(argument)
+		}
+		text := func(response *Response) (string, bool) {
+			switch name {
+			case "method":
+				return string(response.Method), true
+			case "host":
+				return packHost(response), true
+			case "url":
+				return response.Url, true
+			case "body":
+				return string(response.Body), true
+			default:
+				header, ok := lowerHeaders(response.Headers)[argument]
+				return header, ok
+			}
+		}
+		compare, err := compileTextComparison(operator, value, name == "method" || name == "host")
+		if err != nil {
+			return nil, err
+		}
+		match := func(response *Response) bool {
+			actual, ok := text(response)
+			return ok && compare(actual)
+		}
+		// Every round is indexed under its method and its host, folded
+		if (name == "method" || name == "host") && !isRegexOperator(op) {
+			return indexedPredicate{field: IndexField(name), accept: compare, match: match}, nil
+		}
+		return PredicateFunc(match), nil
+
+	case strings.HasPrefix(name, "json."):
+		return compileJSONComparison(strings.Split(field.text[len("json."):], "."), operator, value)
+
+	default:
+		return nil, &ExpressionError{Offset: field.offset, Msg: fmt.Sprintf("unknown field %s", field)}
+	}
+}
+
+// statusPredicate selects the status codes compare accepts, from the index on
+// IndexStatus when there is one.
+func statusPredicate(compare func(status int) bool) Predicate {
+	return indexedPredicate{
+		field: IndexStatus,
+		accept: func(value string) bool {
+			status, err := strconv.Atoi(value)
+			return err == nil && compare(status)
+		},
+		match: func(response *Response) bool {
+			return compare(int(response.StatusCode))
+		},
+	}
+}
+
+// isRegexOperator reports whether op is ~ or !~.
+func isRegexOperator(op string) bool {
+	return op == "~" || op == "!~"
+}
+
+// compareOrdered compares a with b under op, one of = != > >= < <=.
+func compareOrdered(a, b float64, op string) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	default:
+		return false
+	}
+}
+
+// compileValuePattern compiles the value of a ~ or !~ comparison.
+func compileValuePattern(value expressionToken) (*regexp.Regexp, error) {
+	pattern, err := regexp.Compile(value.text)
+	if err != nil {
+		return nil, &ExpressionError{Offset: value.offset, Msg: fmt.Sprintf("invalid regular expression: %v", err)}
+	}
+	return pattern, nil
+}
+
+// compileTextComparison compiles a comparison of text: = and != compare, folding
+// case when fold is set, ~ and !~ match a regular expression.
+func compileTextComparison(operator, value expressionToken, fold bool) (func(actual string) bool, error) {
+	switch op := operator.text; op {
+	case "=", "!=":
+		return func(actual string) bool {
+			equal := actual == value.text || (fold && strings.EqualFold(actual, value.text))
+			return equal == (op == "=")
+		}, nil
+	case "~", "!~":
+		pattern, err := compileValuePattern(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(actual string) bool {
+			return pattern.MatchString(actual) == (op == "~")
+		}, nil
+	default:
+		return nil, &ExpressionError{Offset: operator.offset, Msg: fmt.Sprintf("operator %s does not apply to text", op)}
+	}
+}
+
+// compileJSONComparison compiles a comparison of the member at path of a JSON body.
+// Numbers compare numerically, and a quoted value always compares as text.
+func compileJSONComparison(path []string, operator, value expressionToken) (Predicate, error) {
+	for _, segment := range path {
+		if segment == "" {
+			return nil, &ExpressionError{Offset: operator.offset, Msg: "empty JSON path segment"}
+		}
+	}
+
+	op := operator.text
+	number, numberErr := strconv.ParseFloat(value.text, 64)
+	isNumber := value.kind == tokenWord && numberErr == nil
+	if !isNumber && !isRegexOperator(op) && op != "=" && op != "!=" {
+		return nil, &ExpressionError{Offset: operator.offset, Msg: fmt.Sprintf("operator %s needs a number", op)}
+	}
+	compareText, err := compileTextComparison(operator, value, false)
+	if err != nil && !isNumber {
+		return nil, err
+	}
+
+	return PredicateFunc(func(response *Response) bool {
+		member, ok := jsonMember(response.Body, path)
+		if !ok {
+			return false
+		}
+		if actual, isJSONNumber := member.(json.Number); isJSONNumber && isNumber && !isRegexOperator(op) {
+			parsed, err := actual.Float64()
+			return err == nil && compareOrdered(parsed, number, op)
+		}
+		if compareText == nil {
+			return false
+		}
+		switch actual := member.(type) {
+		case string:
+			return compareText(actual)
+		case json.Number:
+			return compareText(actual.String())
+		case bool:
+			return compareText(strconv.FormatBool(actual))
+		case nil:
+			return compareText("null")
+		default:
+			return false
+		}
+	}), nil
+}
+
+// jsonMember returns the member of the JSON body at path, numeric segments indexing
+// arrays.
+func jsonMember(body []byte, path []string) (interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var member interface{}
+	if err := decoder.Decode(&member); err != nil {
+		return nil, false
+	}
+	for _, segment := range path {
+		switch node := member.(type) {
+		case map[string]interface{}:
+			child, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			member = child
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			member = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return member, true
+}
@@ -0,0 +1,105 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GraphQL
+// ----------------------------------------------------------------------
+
+// GraphQLLocation is a line and column of the GraphQL document.
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphQLError is an entry of the errors array of a GraphQL response. Path holds
+// field names (string) and list indexes (float64), as decoded from JSON.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// PathString returns the path joined with dots, e.g. "user.friends.0.name".
+func (e GraphQLError) PathString() string {
+	parts := make([]string, 0, len(e.Path))
+	for _, segment := range e.Path {
+		switch value := segment.(type) {
+		case string:
+			parts = append(parts, value)
+		case float64:
+			parts = append(parts, strconv.FormatFloat(value, 'f', -1, 64))
+		default:
+			parts = append(parts, fmt.Sprint(value))
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// String returns the message followed by the path, when there is one.
+func (e GraphQLError) String() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	return e.Message + " (path: " + e.PathString() + ")"
+}
+
+// GraphQLResponse is the body of a GraphQL response. Data is the raw "data" member,
+// nil when it is absent or null.
+type GraphQLResponse struct {
+	Data       json.RawMessage        `json:"data,omitempty"`
+	Errors     []GraphQLError         `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Failed reports whether the response carries errors.
+func (g *GraphQLResponse) Failed() bool {
+	return len(g.Errors) > 0
+}
+
+// Partial reports whether the response carries both data and errors, i.e. some
+// fields were resolved and others failed.
+func (g *GraphQLResponse) Partial() bool {
+	return g.Data != nil && g.Failed()
+}
+
+// GraphQL parses the body of the response as a GraphQL response. An error is
+// returned when the body is not a JSON object with a "data" or "errors" member.
+func (r *Response) GraphQL() (*GraphQLResponse, error) {
+	body := bytes.TrimSpace(r.Body)
+	if len(body) == 0 || body[0] != '{' {
+		return nil, fmt.Errorf("response body is not a GraphQL response")
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode GraphQL response: %v", err)
+	}
+	data, hasData := members["data"]
+	_, hasErrors := members["errors"]
+	if !hasData && !hasErrors {
+		return nil, fmt.Errorf("response body is not a GraphQL response")
+	}
+
+	var output GraphQLResponse
+	if err := json.Unmarshal(body, &output); err != nil {
+		return nil, fmt.Errorf("failed to decode GraphQL response: %v", err)
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		output.Data = nil
+	}
+	return &output, nil
+}
+
+// HasGraphQLErrors reports whether the body is a GraphQL response with a non-empty
+// errors array.
+func (r *Response) HasGraphQLErrors() bool {
+	graphQL, err := r.GraphQL()
+	return err == nil && graphQL.Failed()
+}
@@ -0,0 +1,210 @@
+package response
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	urlPack "net/url"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR export
+// ----------------------------------------------------------------------
+
+// harDocument is the root of a HAR 1.2 document.
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	Url         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// PackToHAR exports every round of pack as a HAR 1.2 document. Request details come
+// from Response.Request and timings from Response.Timing when they are present.
+// Binary bodies are base64-encoded.
+func PackToHAR(pack Pack) ([]byte, error) {
+	document := harDocument{
+		Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "jr_goresponse", Version: "1.0"},
+			Entries: []harEntry{},
+		},
+	}
+
+	err := forEachRound(pack, func(key string, round int, response *Response) error {
+		document.Log.Entries = append(document.Log.Entries, newHAREntry(round, response))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(document)
+}
+
+// newHAREntry converts a round into a HAR entry.
+func newHAREntry(round int, response *Response) harEntry {
+	url := response.Url
+	entry := harEntry{
+		StartedDateTime: time.Time{}.Format(time.RFC3339Nano),
+		Time:            0,
+		Request: harRequest{
+			Method:      string(response.Method),
+			Url:         url,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			QueryString: harQueryString(url),
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+		Response: harResponse{
+			Status:      int(response.StatusCode),
+			StatusText:  http.StatusText(int(response.StatusCode)),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(response.Headers),
+			Content:     harBody(response.Headers["Content-Type"], response.Body),
+			RedirectURL: response.Headers["Location"],
+			HeadersSize: -1,
+			BodySize:    len(response.Body),
+		},
+		Comment: "round " + strconv.Itoa(round),
+	}
+
+	if response.Request != nil {
+		entry.Request.Headers = harHeaders(response.Request.Headers)
+		entry.Request.BodySize = len(response.Request.Body)
+		if len(response.Request.Body) > 0 {
+			entry.Request.PostData = &harPostData{
+				MimeType: response.Request.Headers["Content-Type"],
+				Text:     string(response.Request.Body),
+			}
+		}
+	}
+
+	if response.Timing != nil {
+		milliseconds := float64(response.Timing.Duration) / float64(time.Millisecond)
+		entry.StartedDateTime = response.Timing.Start.Format(time.RFC3339Nano)
+		entry.Time = milliseconds
+		entry.Timings.Wait = milliseconds
+	}
+
+	return entry
+}
+
+// harHeaders converts a header map into sorted HAR name/value pairs.
+func harHeaders(headers map[string]string) []harNameValue {
+	output := make([]harNameValue, 0, len(headers))
+	for name, value := range headers {
+		output = append(output, harNameValue{Name: name, Value: value})
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].Name < output[j].Name
+	})
+	return output
+}
+
+// harQueryString converts the query of url into HAR name/value pairs.
+func harQueryString(url string) []harNameValue {
+	output := []harNameValue{}
+	parsed, err := urlPack.Parse(url)
+	if err != nil {
+		return output
+	}
+	query := parsed.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range query[key] {
+			output = append(output, harNameValue{Name: key, Value: value})
+		}
+	}
+	return output
+}
+
+// harBody converts a body into HAR content, base64-encoding binary data.
+func harBody(mimeType string, body []byte) harContent {
+	content := harContent{Size: len(body), MimeType: mimeType}
+	if len(body) == 0 {
+		return content
+	}
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return content
+}
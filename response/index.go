@@ -0,0 +1,331 @@
+package response
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Secondary Indexes
+// ----------------------------------------------------------------------
+
+// IndexField is a field a ResponsePack can index its rounds by, see
+// ConfigResponsePack.Indexes.
+type IndexField string
+
+const (
+	// IndexHost indexes by Response.Host, or the host of the URL, case-insensitive.
+	IndexHost IndexField = "host"
+	// IndexStatus indexes by status code.
+	IndexStatus IndexField = "status"
+	// IndexMethod indexes by method, case-insensitive.
+	IndexMethod IndexField = "method"
+	// IndexTag indexes by tag, see Response.AddTags.
+	IndexTag IndexField = "tag"
+)
+
+// roundRef identifies a stored round by its pack key and sequence number.
+type roundRef struct {
+	key string
+	seq int
+}
+
+// roundSet is a set of stored rounds.
+type roundSet map[roundRef]struct{}
+
+// addAll adds the rounds of other to s.
+func (s roundSet) addAll(other roundSet) {
+	for ref := range other {
+		s[ref] = struct{}{}
+	}
+}
+
+// intersect returns the rounds in both a and b, b when a is nil.
+func intersect(a, b roundSet) roundSet {
+	if a == nil {
+		return b
+	}
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	output := roundSet{}
+	for ref := range a {
+		if _, ok := b[ref]; ok {
+			output[ref] = struct{}{}
+		}
+	}
+	return output
+}
+
+// packIndexes maps every indexed field to its values and the rounds having them.
+// It also keeps the set of failed rounds, for GetErrorReport.
+type packIndexes struct {
+	fields   map[IndexField]map[string]roundSet
+	failures roundSet
+}
+
+// newPackIndexes returns empty indexes on fields, nil without fields.
+func newPackIndexes(fields []IndexField) *packIndexes {
+	if len(fields) == 0 {
+		return nil
+	}
+	indexes := &packIndexes{fields: map[IndexField]map[string]roundSet{}, failures: roundSet{}}
+	for _, field := range fields {
+		indexes.fields[field] = map[string]roundSet{}
+	}
+	return indexes
+}
+
+// indexValue returns the value raw is indexed under for field.
+func indexValue(field IndexField, raw string) string {
+	switch field {
+	case IndexHost:
+		return strings.ToLower(raw)
+	case IndexMethod:
+		return strings.ToUpper(raw)
+	default:
+		return raw
+	}
+}
+
+// valuesOf returns the values response is indexed under for field.
+func valuesOf(field IndexField, response *Response) []string {
+	switch field {
+	case IndexHost:
+		return []string{indexValue(field, packHost(response))}
+	case IndexStatus:
+		return []string{strconv.Itoa(int(response.StatusCode))}
+	case IndexMethod:
+		return []string{indexValue(field, string(response.Method))}
+	case IndexTag:
+		return response.Tags
+	default:
+		return nil
+	}
+}
+
+// add indexes a stored round.
+func (x *packIndexes) add(ref roundRef, response *Response, failed bool) {
+	for field, values := range x.fields {
+		for _, value := range valuesOf(field, response) {
+			if values[value] == nil {
+				values[value] = roundSet{}
+			}
+			values[value][ref] = struct{}{}
+		}
+	}
+	if failed {
+		x.failures[ref] = struct{}{}
+	}
+}
+
+// remove drops a stored round from the indexes.
+func (x *packIndexes) remove(ref roundRef, response *Response) {
+	for field, values := range x.fields {
+		for _, value := range valuesOf(field, response) {
+			delete(values[value], ref)
+			if len(values[value]) == 0 {
+				delete(values, value)
+			}
+		}
+	}
+	delete(x.failures, ref)
+}
+
+// fieldList returns the indexed fields, sorted.
+func (x *packIndexes) fieldList() []IndexField {
+	if x == nil {
+		return nil
+	}
+	fields := make([]IndexField, 0, len(x.fields))
+	for field := range x.fields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i] < fields[j] })
+	return fields
+}
+
+// Indexed Predicates
+// ----------------------------------------------------------------------
+
+// indexedPredicate is a Predicate an index on field can answer: the rounds it
+// selects are among the rounds indexed under one of values, or under a value
+// accept accepts when values is nil.
+type indexedPredicate struct {
+	field  IndexField
+	values []string
+	accept func(value string) bool
+	match  PredicateFunc
+}
+
+// Match calls the match function of the predicate.
+func (p indexedPredicate) Match(response *Response) bool {
+	return p.match(response)
+}
+
+// candidates returns the rounds indexed under the values of the predicate.
+func (p indexedPredicate) candidates(indexes *packIndexes) (roundSet, bool) {
+	values, ok := indexes.fields[p.field]
+	if !ok {
+		return nil, false
+	}
+	output := roundSet{}
+	if p.values != nil {
+		for _, value := range p.values {
+			output.addAll(values[value])
+		}
+		return output, true
+	}
+	for value, set := range values {
+		if p.accept(value) {
+			output.addAll(set)
+		}
+	}
+	return output, true
+}
+
+// candidatesOf returns the rounds that may match predicate according to indexes,
+// or false when no index answers it.
+func candidatesOf(predicate Predicate, indexes *packIndexes) (roundSet, bool) {
+	indexed, ok := predicate.(interface {
+		candidates(indexes *packIndexes) (roundSet, bool)
+	})
+	if !ok {
+		return nil, false
+	}
+	return indexed.candidates(indexes)
+}
+
+// ResponsePack
+// ----------------------------------------------------------------------
+
+// SetIndexes replaces the secondary indexes of the pack by indexes on fields and
+// builds them from the stored rounds. Without fields the pack has no index.
+func (p *ResponsePack) SetIndexes(fields ...IndexField) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.indexes = newPackIndexes(fields)
+	p.reindex()
+}
+
+// Indexes returns the fields the pack is indexed by, sorted.
+func (p *ResponsePack) Indexes() []IndexField {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.indexes.fieldList()
+}
+
+// index adds a stored round to the indexes, if any. The caller holds the write lock.
+func (p *ResponsePack) index(key string, seq int, response *Response) {
+	if p.indexes != nil {
+		p.indexes.add(roundRef{key, seq}, response, p.classify(response) == OutcomeFailure)
+	}
+}
+
+// unindex removes a stored round from the indexes, if any. The caller holds the
+// write lock.
+func (p *ResponsePack) unindex(key string, seq int, response *Response) {
+	if p.indexes != nil {
+		p.indexes.remove(roundRef{key, seq}, response)
+	}
+}
+
+// reindex rebuilds the indexes, if any, from the stored rounds. The caller holds
+// the write lock.
+func (p *ResponsePack) reindex() {
+	if p.indexes == nil {
+		return
+	}
+	p.indexes = newPackIndexes(p.indexes.fieldList())
+	for key, rounds := range p.Responses {
+		for round, response := range rounds {
+			p.index(key, roundNumber(round), response)
+		}
+	}
+}
+
+// indexedRounds returns the stored rounds of refs in key and round order. The caller
+// holds the lock.
+func (p *ResponsePack) indexedRounds(refs roundSet) []QueryResult {
+	results := make([]QueryResult, 0, len(refs))
+	for ref := range refs {
+		if response, ok := p.Responses[ref.key][roundKey(ref.seq)]; ok {
+			results = append(results, QueryResult{Key: ref.key, Round: ref.seq, Response: response})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Key != results[j].Key {
+			return results[i].Key < results[j].Key
+		}
+		return results[i].Round < results[j].Round
+	})
+	return results
+}
+
+// queryIndexed runs query over the candidates of the indexes. It returns false when
+// the pack has no index answering a predicate of the query.
+func (p *ResponsePack) queryIndexed(query Query) ([]QueryResult, bool) {
+	p.mu.RLock()
+	if p.indexes == nil {
+		p.mu.RUnlock()
+		return nil, false
+	}
+	refs, ok := allOf(query.Where).candidates(p.indexes)
+	if !ok {
+		p.mu.RUnlock()
+		return nil, false
+	}
+	candidates := p.indexedRounds(refs)
+	p.mu.RUnlock()
+
+	results := []QueryResult{}
+	for _, candidate := range candidates {
+		if query.matches(candidate.Response) {
+			results = append(results, candidate)
+		}
+	}
+	return query.arrange(results), true
+}
+
+// HostStats returns the detailed statistics of the rounds of host, compared
+// case-insensitively. A pack indexed by IndexHost only reads the rounds of host.
+func (p *ResponsePack) HostStats(host string) (PackStats, error) {
+	if p == nil {
+		return PackStats{}, fmt.Errorf("response pack is nil")
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := &ResponsePack{}
+	stats.resetStats()
+	var matched []*Response
+	if hosts, ok := p.indexes.hostIndex(); ok {
+		for ref := range hosts[indexValue(IndexHost, host)] {
+			response := p.Responses[ref.key][roundKey(ref.seq)]
+			stats.track(ref.key, response)
+			matched = append(matched, response)
+		}
+	} else {
+		for key, rounds := range p.Responses {
+			for _, response := range rounds {
+				if strings.EqualFold(packHost(response), host) {
+					stats.track(key, response)
+					matched = append(matched, response)
+				}
+			}
+		}
+	}
+	stats.Stats.BodySize, stats.Stats.Latency = roundDistributions(matched)
+	return stats.Stats, nil
+}
+
+// hostIndex returns the index on IndexHost, if any.
+func (x *packIndexes) hostIndex() (map[string]roundSet, bool) {
+	if x == nil {
+		return nil, false
+	}
+	hosts, ok := x.fields[IndexHost]
+	return hosts, ok
+}
@@ -0,0 +1,187 @@
+package response
+
+import (
+	urlPack "net/url"
+	"sort"
+	"strings"
+)
+
+// Pack Keys
+// ----------------------------------------------------------------------
+
+// KeyFunc returns the key a pack stores a Response under: the responses with the
+// same key are the rounds of one entry. Every lookup of a pack (GetResponse,
+// History, GetRound, DeleteResponse, ...) takes such a key, which Key computes for
+// a Response. A pack's KeyFunc is set when it is constructed, URLKey by default.
+type KeyFunc func(response *Response) string
+
+// URLKey keys a response by its URL, as written. It is the default KeyFunc.
+func URLKey(response *Response) string {
+	return response.Url
+}
+
+// MethodURLKey keys a response by its upper-cased method and URL, e.g.
+// "POST https://api.example.com/items", so that a GET and a POST to the same URL
+// are separate entries.
+func MethodURLKey(response *Response) string {
+	return strings.ToUpper(string(response.Method)) + " " + response.Url
+}
+
+// NormalizedURLKey keys a response by NormalizeURL of its URL, so that URLs that
+// only differ in case, default port, escaping, dot segments, a trailing slash or
+// the order of the query parameters are one entry.
+func NormalizedURLKey(response *Response) string {
+	return NormalizeURL(response.Url)
+}
+
+// HostKey keys a response by its lower-cased host: Response.Host, or the host of
+// its URL when empty.
+func HostKey(response *Response) string {
+	return strings.ToLower(packHost(response))
+}
+
+// NormalizeURL applies the RFC 3986 syntax-based normalization to raw: scheme and
+// host are lower-cased, the default port of http and https is removed, percent
+// encodings are upper-cased and decoded for unreserved characters, dot segments
+// are removed and an empty path becomes "/". A trailing slash is removed from
+// other paths, the query parameters are sorted and the fragment is dropped. raw is
+// returned as is when it is not an absolute URL.
+func NormalizeURL(raw string) string {
+	parsed, err := urlPack.Parse(raw)
+	if err != nil || parsed.Scheme == "" || parsed.Opaque != "" {
+		return raw
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	host := strings.ToLower(parsed.Host)
+	port := parsed.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+
+	path := removeDotSegments(normalizeEscapes(parsed.EscapedPath()))
+	if path == "" {
+		path = "/"
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(scheme + "://")
+	if parsed.User != nil {
+		sb.WriteString(parsed.User.String() + "@")
+	}
+	sb.WriteString(host)
+	sb.WriteString(path)
+
+	var params []string
+	for _, param := range strings.Split(parsed.RawQuery, "&") {
+		if param != "" {
+			params = append(params, normalizeEscapes(param))
+		}
+	}
+	if len(params) > 0 {
+		sort.Strings(params)
+		sb.WriteString("?" + strings.Join(params, "&"))
+	}
+	return sb.String()
+}
+
+// normalizeEscapes upper-cases the hex digits of the percent encodings of s and
+// decodes the ones of unreserved characters.
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		decoded := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(decoded) {
+			sb.WriteByte(decoded)
+		} else {
+			sb.WriteString("%" + strings.ToUpper(s[i+1:i+3]))
+		}
+		i += 2
+	}
+	return sb.String()
+}
+
+// isHex reports whether c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// unhex returns the value of the hexadecimal digit c.
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// isUnreserved reports whether c is an RFC 3986 unreserved character.
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// removeDotSegments removes the "." and ".." segments of path, RFC 3986 section 5.2.4.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	output := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				output = append(output, "")
+			}
+		case "..":
+			if len(output) > 1 {
+				output = output[:len(output)-1]
+			}
+			if last {
+				output = append(output, "")
+			}
+		default:
+			output = append(output, segment)
+		}
+	}
+	return strings.Join(output, "/")
+}
+
+// Key returns the key the pack stores response under.
+func (p *ResponsePack) Key(response *Response) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.keyOf(response)
+}
+
+// keyOf returns the key of response. The caller holds the lock.
+func (p *ResponsePack) keyOf(response *Response) string {
+	if p.key == nil {
+		return URLKey(response)
+	}
+	return p.key(response)
+}
+
+// Key returns the key the pack stores response under.
+func (r *CompressResponsePack) Key(response *Response) string {
+	// The key function is only set at construction, it is read without the lock
+	if r.key == nil {
+		return URLKey(response)
+	}
+	return r.key(response)
+}
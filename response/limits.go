@@ -0,0 +1,427 @@
+package response
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Capacity Limits
+// ----------------------------------------------------------------------
+
+// EvictionPolicy chooses what a pack evicts when it has too many keys or bytes.
+type EvictionPolicy int
+
+const (
+	// EvictLRU evicts the key least recently added to or read, see PackLimits.
+	EvictLRU EvictionPolicy = iota
+	// EvictFIFO evicts the key first added.
+	EvictFIFO
+	// EvictOldestRound evicts the round added first, whatever its key.
+	EvictOldestRound
+)
+
+// String returns "lru", "fifo" or "oldestRound".
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictLRU:
+		return "lru"
+	case EvictFIFO:
+		return "fifo"
+	case EvictOldestRound:
+		return "oldestRound"
+	default:
+		return fmt.Sprintf("EvictionPolicy(%d)", int(p))
+	}
+}
+
+// EvictionReason is the limit that made a pack evict a round.
+type EvictionReason string
+
+const (
+	// EvictedMaxKeys is an eviction for PackLimits.MaxKeys.
+	EvictedMaxKeys EvictionReason = "maxKeys"
+	// EvictedMaxRounds is an eviction for PackLimits.MaxRoundsPerKey.
+	EvictedMaxRounds EvictionReason = "maxRounds"
+	// EvictedMaxBytes is an eviction for PackLimits.MaxBytes.
+	EvictedMaxBytes EvictionReason = "maxBytes"
+	// EvictedTTL is an eviction for PackLimits.TTL.
+	EvictedTTL EvictionReason = "ttl"
+)
+
+// Eviction is a round evicted from a pack, passed to PackLimits.OnEvict. Response is
+// decompressed for a CompressResponsePack.
+type Eviction struct {
+	Key      string         `json:"key"`
+	Round    int            `json:"round"`
+	Reason   EvictionReason `json:"reason"`
+	Response *Response      `json:"response"`
+}
+
+// PackLimits bounds the rounds a pack retains. Zero fields are not limited.
+//
+// MaxRoundsPerKey evicts the oldest rounds of a key. MaxKeys evicts every round of
+// the key chosen by Policy, and MaxBytes evicts rounds one at a time, the oldest of
+// the key chosen by Policy or, with EvictOldestRound, the oldest of the pack. The
+// bytes of a round are the length of its body and raw response, compressed for a
+// CompressResponsePack. TTL evicts the rounds added longer ago than TTL.
+//
+// Limits are enforced when a round is added and by Prune. AddResponse and
+// ReplaceRound reject a round larger than MaxBytes on its own with an error rather
+// than storing and evicting it at once. TTL is not checked on reads: a round past
+// its TTL is returned until the next AddResponse or Prune evicts it, so call Prune
+// before reading when expired rounds must not be seen. OnEvict, when set, is
+// called once per evicted round after the pack is unlocked, so it may use the pack.
+type PackLimits struct {
+	MaxKeys         int
+	MaxRoundsPerKey int
+	MaxBytes        int64
+	TTL             time.Duration
+	Policy          EvictionPolicy
+	OnEvict         func(eviction Eviction)
+}
+
+// enabled reports whether any limit is set.
+func (l PackLimits) enabled() bool {
+	return l.MaxKeys > 0 || l.MaxRoundsPerKey > 0 || l.MaxBytes > 0 || l.TTL > 0
+}
+
+// limitedRound is a round retained by a pack under limits.
+type limitedRound struct {
+	ref   roundRef
+	added time.Time
+	size  int64
+}
+
+// packLimiter keeps the rounds of a pack in the order they were added and its keys
+// in the order of the policy, and picks the rounds to evict. Its methods are called
+// under the write lock of the pack, except touch, which readers call under the read
+// lock and which is serialized by touchMu.
+type packLimiter struct {
+	limits        PackLimits
+	rounds        *list.List // of limitedRound, oldest first
+	roundElements map[roundRef]*list.Element
+	keys          *list.List // of string, next victim first
+	keyElements   map[string]*list.Element
+	counts        map[string]int
+	bytes         int64
+	touchMu       sync.Mutex
+}
+
+// newPackLimiter returns a limiter for limits, nil when no limit is set.
+func newPackLimiter(limits PackLimits) *packLimiter {
+	if !limits.enabled() {
+		return nil
+	}
+	l := &packLimiter{limits: limits}
+	l.reset()
+	return l
+}
+
+// reset forgets every round.
+func (l *packLimiter) reset() {
+	l.rounds = list.New()
+	l.roundElements = map[roundRef]*list.Element{}
+	l.keys = list.New()
+	l.keyElements = map[string]*list.Element{}
+	l.counts = map[string]int{}
+	l.bytes = 0
+}
+
+// added records a round added to the pack.
+func (l *packLimiter) added(ref roundRef, size int64) {
+	l.roundElements[ref] = l.rounds.PushBack(limitedRound{ref: ref, added: time.Now(), size: size})
+	l.bytes += size
+	l.counts[ref.key]++
+	if element, ok := l.keyElements[ref.key]; !ok {
+		l.keyElements[ref.key] = l.keys.PushBack(ref.key)
+	} else if l.limits.Policy == EvictLRU {
+		l.keys.MoveToBack(element)
+	}
+}
+
+// resized records a round replaced in place; it keeps its age.
+func (l *packLimiter) resized(ref roundRef, size int64) {
+	element, ok := l.roundElements[ref]
+	if !ok {
+		return
+	}
+	round := element.Value.(limitedRound)
+	l.bytes += size - round.size
+	round.size = size
+	element.Value = round
+}
+
+// removed records a round removed from the pack.
+func (l *packLimiter) removed(ref roundRef) {
+	element, ok := l.roundElements[ref]
+	if !ok {
+		return
+	}
+	l.bytes -= element.Value.(limitedRound).size
+	l.rounds.Remove(element)
+	delete(l.roundElements, ref)
+
+	l.counts[ref.key]--
+	if l.counts[ref.key] <= 0 {
+		delete(l.counts, ref.key)
+		if element, ok := l.keyElements[ref.key]; ok {
+			l.keys.Remove(element)
+			delete(l.keyElements, ref.key)
+		}
+	}
+}
+
+// touch marks key as used, for EvictLRU.
+func (l *packLimiter) touch(key string) {
+	if l == nil || l.limits.Policy != EvictLRU {
+		return
+	}
+	l.touchMu.Lock()
+	defer l.touchMu.Unlock()
+	if element, ok := l.keyElements[key]; ok {
+		l.keys.MoveToBack(element)
+	}
+}
+
+// enforce evicts rounds until the limits hold, checking MaxRoundsPerKey for keys.
+// oldest returns the oldest stored round of a key and evict removes a round from
+// the pack, calling removed.
+func (l *packLimiter) enforce(keys []string, oldest func(key string) int, evict func(ref roundRef, reason EvictionReason)) {
+	if l.limits.TTL > 0 {
+		now := time.Now()
+		for front := l.rounds.Front(); front != nil; front = l.rounds.Front() {
+			round := front.Value.(limitedRound)
+			if now.Sub(round.added) < l.limits.TTL {
+				break
+			}
+			evict(round.ref, EvictedTTL)
+		}
+	}
+
+	if l.limits.MaxRoundsPerKey > 0 {
+		for _, key := range keys {
+			for l.counts[key] > l.limits.MaxRoundsPerKey {
+				evict(roundRef{key, oldest(key)}, EvictedMaxRounds)
+			}
+		}
+	}
+
+	if l.limits.MaxKeys > 0 {
+		for len(l.counts) > l.limits.MaxKeys {
+			key := l.victimKey()
+			for l.counts[key] > 0 {
+				evict(roundRef{key, oldest(key)}, EvictedMaxKeys)
+			}
+		}
+	}
+
+	if l.limits.MaxBytes > 0 {
+		for l.bytes > l.limits.MaxBytes && l.rounds.Len() > 0 {
+			if l.limits.Policy == EvictOldestRound {
+				evict(l.rounds.Front().Value.(limitedRound).ref, EvictedMaxBytes)
+				continue
+			}
+			key := l.victimKey()
+			evict(roundRef{key, oldest(key)}, EvictedMaxBytes)
+		}
+	}
+}
+
+// fits returns an error when a round of size bytes is larger than MaxBytes, so
+// that it could never be retained. A nil limiter accepts every round.
+func (l *packLimiter) fits(size int64) error {
+	if l == nil || l.limits.MaxBytes <= 0 || size <= l.limits.MaxBytes {
+		return nil
+	}
+	return fmt.Errorf("round of %d bytes exceeds the limit of %d bytes", size, l.limits.MaxBytes)
+}
+
+// victimKey returns the key the policy evicts next.
+func (l *packLimiter) victimKey() string {
+	if l.limits.Policy == EvictOldestRound {
+		return l.rounds.Front().Value.(limitedRound).ref.key
+	}
+	return l.keys.Front().Value.(string)
+}
+
+// responseBytes returns the bytes a round of a ResponsePack counts for MaxBytes, a
+// body shared with the raw response once.
+func responseBytes(response *Response) int64 {
+	if response.BodyShared() && !response.rawHead {
+		return int64(len(response.RawResponse))
+	}
+	return int64(len(response.Body) + len(response.RawResponse))
+}
+
+// notifyEvictions calls onEvict with every eviction, if set.
+func notifyEvictions(onEvict func(eviction Eviction), evictions []Eviction) {
+	if onEvict == nil {
+		return
+	}
+	for _, eviction := range evictions {
+		onEvict(eviction)
+	}
+}
+
+// ResponsePack
+// ----------------------------------------------------------------------
+
+// SetLimits replaces the capacity limits of the pack and enforces them on the
+// stored rounds, which count as added now. Zero limits remove every limit.
+func (p *ResponsePack) SetLimits(limits PackLimits) {
+	p.mu.Lock()
+	p.limiter = newPackLimiter(limits)
+	if p.limiter == nil {
+		p.mu.Unlock()
+		return
+	}
+	keys := sortedKeys(p.Responses)
+	for _, key := range keys {
+		for _, seq := range orderedSeqs(p.Responses[key], p.order[key]) {
+			p.limiter.added(roundRef{key, seq}, responseBytes(p.Responses[key][roundKey(seq)]))
+		}
+	}
+	evictions := p.enforceLimits(keys)
+	p.mu.Unlock()
+
+	notifyEvictions(limits.OnEvict, evictions)
+}
+
+// Prune evicts the rounds whose TTL has passed, and any round over the limits.
+func (p *ResponsePack) Prune() {
+	p.mu.Lock()
+	if p.limiter == nil {
+		p.mu.Unlock()
+		return
+	}
+	evictions := p.enforceLimits(nil)
+	onEvict := p.limiter.limits.OnEvict
+	p.mu.Unlock()
+
+	notifyEvictions(onEvict, evictions)
+}
+
+// enforceLimits evicts rounds until the limits of the pack hold and returns the
+// evictions. The caller holds the write lock.
+func (p *ResponsePack) enforceLimits(keys []string) []Eviction {
+	if p.limiter == nil {
+		return nil
+	}
+	var evictions []Eviction
+	oldest := func(key string) int {
+		return orderedSeqs(p.Responses[key], p.order[key])[0]
+	}
+	p.limiter.enforce(keys, oldest, func(ref roundRef, reason EvictionReason) {
+		response := p.Responses[ref.key][roundKey(ref.seq)]
+		p.forget(ref.key, response)
+		p.unindex(ref.key, ref.seq, response)
+		removeRound(p.Responses, p.order, ref.key, ref.seq)
+		p.limiter.removed(ref)
+		p.Evicted++
+		evictions = append(evictions, Eviction{Key: ref.key, Round: ref.seq, Reason: reason, Response: response})
+	})
+	if len(evictions) > 0 {
+		p.updateRatios()
+	}
+	return evictions
+}
+
+// onEvict returns the eviction callback of the pack. The caller holds the lock.
+func (p *ResponsePack) onEvict() func(eviction Eviction) {
+	if p.limiter == nil {
+		return nil
+	}
+	return p.limiter.limits.OnEvict
+}
+
+// CompressResponsePack
+// ----------------------------------------------------------------------
+
+// compressedEviction is an eviction whose response is still compressed.
+type compressedEviction struct {
+	Eviction
+	round compressedRound
+}
+
+// SetLimits replaces the capacity limits of the pack and enforces them on the
+// stored rounds, which count as added now. Zero limits remove every limit.
+func (r *CompressResponsePack) SetLimits(limits PackLimits) {
+	r.mu.Lock()
+	r.limiter = newPackLimiter(limits)
+	if r.limiter == nil {
+		r.mu.Unlock()
+		return
+	}
+	keys := sortedKeys(r.CompressedResponses)
+	for _, key := range keys {
+		for _, seq := range orderedSeqs(r.CompressedResponses[key], r.order[key]) {
+			r.limiter.added(roundRef{key, seq}, int64(len(r.CompressedResponses[key][roundKey(seq)])))
+		}
+	}
+	evictions := r.enforceLimits(keys)
+	r.mu.Unlock()
+
+	notifyCompressedEvictions(limits.OnEvict, evictions)
+}
+
+// Prune evicts the rounds whose TTL has passed, and any round over the limits.
+func (r *CompressResponsePack) Prune() {
+	r.mu.Lock()
+	if r.limiter == nil {
+		r.mu.Unlock()
+		return
+	}
+	evictions := r.enforceLimits(nil)
+	onEvict := r.limiter.limits.OnEvict
+	r.mu.Unlock()
+
+	notifyCompressedEvictions(onEvict, evictions)
+}
+
+// enforceLimits evicts rounds until the limits of the pack hold and returns the
+// evictions. The caller holds the write lock.
+func (r *CompressResponsePack) enforceLimits(keys []string) []compressedEviction {
+	if r.limiter == nil {
+		return nil
+	}
+	var evictions []compressedEviction
+	oldest := func(key string) int {
+		return orderedSeqs(r.CompressedResponses[key], r.order[key])[0]
+	}
+	r.limiter.enforce(keys, oldest, func(ref roundRef, reason EvictionReason) {
+		round := r.copyRound(ref.key, ref.seq)
+		r.unaccount(ref.key, ref.seq)
+		r.releaseBody(ref)
+		removeRound(r.CompressedResponses, r.order, ref.key, ref.seq)
+		r.limiter.removed(ref)
+		r.Evicted++
+		evictions = append(evictions, compressedEviction{
+			Eviction: Eviction{Key: ref.key, Round: ref.seq, Reason: reason},
+			round:    round,
+		})
+	})
+	return evictions
+}
+
+// onEvict returns the eviction callback of the pack. The caller holds the lock.
+func (r *CompressResponsePack) onEvict() func(eviction Eviction) {
+	if r.limiter == nil {
+		return nil
+	}
+	return r.limiter.limits.OnEvict
+}
+
+// notifyCompressedEvictions decompresses the evicted rounds and calls onEvict with
+// every eviction, if set. A round that fails to decompress is passed without
+// Response.
+func notifyCompressedEvictions(onEvict func(eviction Eviction), evictions []compressedEviction) {
+	if onEvict == nil {
+		return
+	}
+	for _, eviction := range evictions {
+		eviction.Response, _ = eviction.round.decompress()
+		onEvict(eviction.Eviction)
+	}
+}
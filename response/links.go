@@ -0,0 +1,319 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	urlPack "net/url"
+	"strings"
+	"time"
+)
+
+// Link Header
+// ----------------------------------------------------------------------
+
+// Link is a link of an RFC 8288 Link header.
+//
+// Target is resolved against the URL of the request that produced the response,
+// or the response URL when no request was recorded. Rel holds the lower-cased
+// relation types, Title prefers title* over title, and Params keeps every
+// parameter, lower-cased, as written (first occurrence).
+type Link struct {
+	Target   string            `json:"target"`
+	Rel      []string          `json:"rel"`
+	Anchor   string            `json:"anchor,omitempty"`
+	Title    string            `json:"title,omitempty"`
+	Type     string            `json:"type,omitempty"`
+	Media    string            `json:"media,omitempty"`
+	Hreflang []string          `json:"hreflang,omitempty"`
+	Params   map[string]string `json:"params"`
+}
+
+// HasRel reports whether the link has the relation type rel.
+func (l Link) HasRel(rel string) bool {
+	return listContains(l.Rel, rel)
+}
+
+// Links parses the Link header of the response. Link values joined with ", " by
+// the recorder are split again; commas inside <URI> references and quoted strings
+// are kept.
+func (r *Response) Links() []Link {
+	value := lowerHeaders(r.Headers)["link"]
+	if strings.TrimSpace(value) == "" {
+		return []Link{}
+	}
+
+	base := r.Url
+	if r.Request != nil && r.Request.Url != "" {
+		base = r.Request.Url
+	}
+	baseURL, err := urlPack.Parse(base)
+	if err != nil {
+		baseURL = nil
+	}
+
+	links := []Link{}
+	for _, linkValue := range splitLinkValues(value) {
+		link, ok := parseLinkValue(linkValue, baseURL)
+		if ok {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// Link returns the first link with the relation type rel.
+func (r *Response) Link(rel string) (Link, bool) {
+	for _, link := range r.Links() {
+		if link.HasRel(rel) {
+			return link, true
+		}
+	}
+	return Link{}, false
+}
+
+// splitLinkValues splits a Link header into link-values on the commas outside
+// <URI> references and quoted strings.
+func splitLinkValues(value string) []string {
+	var parts []string
+	var current strings.Builder
+	inURI, quoted, escaped := false, false, false
+	for _, char := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && char == '\\':
+			escaped = true
+		case char == '"' && !inURI:
+			quoted = !quoted
+		case char == '<' && !quoted:
+			inURI = true
+		case char == '>' && !quoted:
+			inURI = false
+		case char == ',' && !inURI && !quoted:
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		}
+		current.WriteRune(char)
+	}
+	return append(parts, strings.TrimSpace(current.String()))
+}
+
+// parseLinkValue parses `<target>; rel="next"; title="Next"` into a Link.
+func parseLinkValue(value string, base *urlPack.URL) (Link, bool) {
+	if !strings.HasPrefix(value, "<") {
+		return Link{}, false
+	}
+	end := strings.Index(value, ">")
+	if end < 0 {
+		return Link{}, false
+	}
+
+	link := Link{Target: strings.TrimSpace(value[1:end]), Rel: []string{}, Params: map[string]string{}}
+	if base != nil {
+		if reference, err := urlPack.Parse(link.Target); err == nil {
+			link.Target = base.ResolveReference(reference).String()
+		}
+	}
+
+	for _, param := range splitLinkParams(value[end+1:]) {
+		name, argument, _ := strings.Cut(param, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		argument = unquoteLinkParam(strings.TrimSpace(argument))
+		if name == "" {
+			continue
+		}
+
+		// hreflang may repeat, the other parameters count once
+		if name == "hreflang" {
+			link.Hreflang = append(link.Hreflang, argument)
+		}
+		if _, ok := link.Params[name]; ok {
+			continue
+		}
+		link.Params[name] = argument
+
+		switch name {
+		case "rel":
+			for _, rel := range strings.Fields(argument) {
+				link.Rel = append(link.Rel, strings.ToLower(rel))
+			}
+		case "anchor":
+			link.Anchor = argument
+		case "title":
+			if link.Title == "" {
+				link.Title = argument
+			}
+		case "title*":
+			if decoded, ok := decodeExtValue(argument); ok {
+				link.Title = decoded
+			}
+		case "type":
+			link.Type = argument
+		case "media":
+			link.Media = argument
+		}
+	}
+	return link, true
+}
+
+// splitLinkParams splits the parameters of a link-value on the semicolons outside
+// quoted strings.
+func splitLinkParams(value string) []string {
+	var params []string
+	var current strings.Builder
+	quoted, escaped := false, false
+	for _, char := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && char == '\\':
+			escaped = true
+		case char == '"':
+			quoted = !quoted
+		case char == ';' && !quoted:
+			params = append(params, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		}
+		current.WriteRune(char)
+	}
+	return append(params, strings.TrimSpace(current.String()))
+}
+
+// unquoteLinkParam removes the quotes and backslash escapes of a quoted-string.
+func unquoteLinkParam(value string) string {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+	var sb strings.Builder
+	escaped := false
+	for _, char := range value[1 : len(value)-1] {
+		if !escaped && char == '\\' {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(char)
+	}
+	return sb.String()
+}
+
+// decodeExtValue decodes an RFC 8187 ext-value such as UTF-8'en'%E2%82%AC.
+func decodeExtValue(value string) (string, bool) {
+	parts := strings.SplitN(value, "'", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[0], "utf-8") {
+		return "", false
+	}
+	decoded, err := urlPack.PathUnescape(parts[2])
+	if err != nil {
+		return "", false
+	}
+	return decoded, true
+}
+
+// Pagination
+// ----------------------------------------------------------------------
+
+// PageFetcher fetches the page at url.
+type PageFetcher func(ctx context.Context, url string) (*Response, error)
+
+// HTTPPageFetcher returns a PageFetcher that sends GET requests with client, or
+// http.DefaultClient when nil, adding headers to every request. The request and
+// its timing are recorded on the returned Response.
+func HTTPPageFetcher(client *http.Client, headers map[string]string) PageFetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context, url string) (*Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		start := time.Now()
+		httpResponse, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer httpResponse.Body.Close()
+
+		response, err := NewResponseFromHTTPResponse(httpResponse)
+		if err != nil {
+			return nil, err
+		}
+		response.Request = newRecordedRequest(req, nil)
+		response.Timing = &Timing{Start: start, Duration: time.Since(start)}
+		return response, nil
+	}
+}
+
+// PaginateOptions configures Paginate.
+//
+// Rel is the relation followed, "next" when empty. MaxPages bounds the number of
+// pages fetched, 0 meaning no limit. Pages are added to Pack, or to a new
+// ResponsePack when nil.
+type PaginateOptions struct {
+	Rel      string
+	MaxPages int
+	Pack     *ResponsePack
+}
+
+// Paginate fetches start, then follows the Rel links of every page, adding each
+// page to the pack as a round of start: round_1 is the first page, round_2 the
+// second and so on. The URL of each page is kept in Response.Request.Url.
+//
+// Pagination stops when a page has no Rel link, links to a page already fetched,
+// is not successful (it is still added), or MaxPages is reached. On a fetch error
+// or a cancelled context, the pages fetched so far are returned with the error.
+func Paginate(ctx context.Context, start string, fetch PageFetcher, options PaginateOptions) (*ResponsePack, error) {
+	if fetch == nil {
+		return nil, fmt.Errorf("page fetcher is nil")
+	}
+	if options.Rel == "" {
+		options.Rel = "next"
+	}
+	pack := options.Pack
+	if pack == nil {
+		pack = NewResponsePack()
+	}
+
+	visited := map[string]bool{}
+	next := start
+	for pages := 0; next != "" && (options.MaxPages <= 0 || pages < options.MaxPages); pages++ {
+		if err := ctx.Err(); err != nil {
+			return pack, err
+		}
+
+		page, err := fetch(ctx, next)
+		if err != nil {
+			return pack, fmt.Errorf("failed to fetch page %d (%s): %w", pages+1, next, err)
+		}
+		if page == nil {
+			return pack, fmt.Errorf("failed to fetch page %d (%s): response is nil", pages+1, next)
+		}
+		visited[next] = true
+
+		page = page.Clone()
+		if page.Request == nil {
+			page.Request = &RecordedRequest{Method: page.Method, Headers: map[string]string{}}
+		}
+		page.Request.Url = next
+
+		link, ok := page.Link(options.Rel)
+		next = ""
+		if ok && page.IsSuccessful() && !visited[link.Target] {
+			next = link.Target
+		}
+
+		page.Url = start
+		if err := pack.AddResponse(page); err != nil {
+			return pack, err
+		}
+	}
+	return pack, nil
+}
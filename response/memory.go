@@ -0,0 +1,176 @@
+package response
+
+import (
+	"fmt"
+)
+
+// Memory Accounting
+// ----------------------------------------------------------------------
+
+// MemoryUsage is the memory held by rounds, in bytes.
+//
+// Logical is the size of the responses themselves: URL, host, method, headers,
+// body, raw response, recorded request and tags. Stored is what a pack keeps for
+// them: the responses in a ResponsePack, with a shared body counted once, the
+// compressed rounds in a CompressResponsePack. A dropped RawResponse counts for
+// neither. Both leave out the fixed overhead of Go values and maps.
+type MemoryUsage struct {
+	Rounds  uint64 `json:"rounds"`
+	Logical int64  `json:"logical"`
+	Stored  int64  `json:"stored"`
+}
+
+// CompressionRatio returns Logical divided by Stored, 0 when nothing is stored.
+func (m MemoryUsage) CompressionRatio() float64 {
+	if m.Stored == 0 {
+		return 0
+	}
+	return float64(m.Logical) / float64(m.Stored)
+}
+
+// String returns the usage on one line.
+func (m MemoryUsage) String() string {
+	return fmt.Sprintf("%d rounds, logical %d B, stored %d B, ratio %.2f",
+		m.Rounds, m.Logical, m.Stored, m.CompressionRatio())
+}
+
+// add counts a round.
+func (m *MemoryUsage) add(logical, stored int64) {
+	m.Rounds++
+	m.Logical += logical
+	m.Stored += stored
+}
+
+// remove uncounts a round.
+func (m *MemoryUsage) remove(logical, stored int64) {
+	if m.Rounds > 0 {
+		m.Rounds--
+	}
+	m.Logical -= logical
+	m.Stored -= stored
+}
+
+// Size returns the logical size of the response, see MemoryUsage.
+func (r *Response) Size() int64 {
+	size := len(r.Method) + len(r.Url) + len(r.Host) + len(r.Body) + len(r.RawResponse)
+	if r.rawHead {
+		size += len(r.Body)
+	}
+	for key, value := range r.Headers {
+		size += len(key) + len(value)
+	}
+	for _, tag := range r.Tags {
+		size += len(tag)
+	}
+	if r.Request != nil {
+		size += len(r.Request.Method) + len(r.Request.Url) + len(r.Request.Body)
+		for key, value := range r.Request.Headers {
+			size += len(key) + len(value)
+		}
+	}
+	return int64(size)
+}
+
+// MemoryUsage returns the memory of the response as a single round. A body shared
+// with RawResponse is only stored once, see BodyShared.
+func (r *Response) MemoryUsage() MemoryUsage {
+	size := r.Size()
+	stored := size
+	if r.BodyShared() {
+		stored -= int64(len(r.Body))
+	}
+	return MemoryUsage{Rounds: 1, Logical: size, Stored: stored}
+}
+
+// addKeyMemory counts a round of key in usage.
+func addKeyMemory(usage map[string]MemoryUsage, key string, logical, stored int64) {
+	entry := usage[key]
+	entry.add(logical, stored)
+	usage[key] = entry
+}
+
+// removeKeyMemory uncounts a round of key in usage, removing key with its last round.
+func removeKeyMemory(usage map[string]MemoryUsage, key string, logical, stored int64) {
+	entry := usage[key]
+	entry.remove(logical, stored)
+	if entry.Rounds == 0 {
+		delete(usage, key)
+		return
+	}
+	usage[key] = entry
+}
+
+// ResponsePack
+// ----------------------------------------------------------------------
+
+// Memory returns the memory used by the rounds of the pack, also found in the
+// statistics.
+func (p *ResponsePack) Memory() MemoryUsage {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.Stats.Memory
+}
+
+// KeyMemory returns the memory used by the rounds of key.
+func (p *ResponsePack) KeyMemory(key string) (MemoryUsage, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	usage, ok := p.memory[key]
+	if !ok {
+		return MemoryUsage{}, fmt.Errorf("response not found for key: %s", key)
+	}
+	return usage, nil
+}
+
+// CompressResponsePack
+// ----------------------------------------------------------------------
+
+// Memory returns the memory used by the rounds of the pack. Its CompressionRatio is
+// the compression ratio of the pack.
+func (r *CompressResponsePack) Memory() MemoryUsage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.total
+}
+
+// KeyMemory returns the memory used by the rounds of key.
+func (r *CompressResponsePack) KeyMemory(key string) (MemoryUsage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	usage, ok := r.memory[key]
+	if !ok {
+		return MemoryUsage{}, fmt.Errorf("response not found for key: %s", key)
+	}
+	return usage, nil
+}
+
+// account counts a stored round of key, whose response has the logical size
+// logical. The caller holds the write lock.
+func (r *CompressResponsePack) account(key string, seq int, logical int64) {
+	if r.memory == nil {
+		r.memory = map[string]MemoryUsage{}
+		r.sizes = map[roundRef]int64{}
+	}
+	stored := int64(len(r.CompressedResponses[key][roundKey(seq)]))
+	r.sizes[roundRef{key, seq}] = logical
+	r.total.add(logical, stored)
+	addKeyMemory(r.memory, key, logical, stored)
+}
+
+// unaccount uncounts a stored round of key before it is removed. The caller holds
+// the write lock.
+func (r *CompressResponsePack) unaccount(key string, seq int) {
+	ref := roundRef{key, seq}
+	logical := r.sizes[ref]
+	stored := int64(len(r.CompressedResponses[key][roundKey(seq)]))
+	delete(r.sizes, ref)
+	r.total.remove(logical, stored)
+	removeKeyMemory(r.memory, key, logical, stored)
+}
+
+// resetMemory clears the memory accounting. The caller holds the write lock.
+func (r *CompressResponsePack) resetMemory() {
+	r.total = MemoryUsage{}
+	r.memory = map[string]MemoryUsage{}
+	r.sizes = map[roundRef]int64{}
+}
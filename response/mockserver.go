@@ -0,0 +1,391 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	urlPack "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// Mock Server
+// ----------------------------------------------------------------------
+
+// ServeMode defines how a MockServer picks the round served for a path.
+type ServeMode int
+
+const (
+	// ServeSequential serves rounds in order, starting over after the last one.
+	ServeSequential ServeMode = iota
+	// ServeRandom serves a random round on every request.
+	ServeRandom
+	// ServeSticky assigns a round to each client and keeps serving it. Clients are
+	// identified by the X-Mock-Client header or, if absent, by their remote IP.
+	ServeSticky
+)
+
+// String returns the name of the serve mode.
+func (m ServeMode) String() string {
+	switch m {
+	case ServeSequential:
+		return "sequential"
+	case ServeRandom:
+		return "random"
+	case ServeSticky:
+		return "sticky"
+	default:
+		return fmt.Sprintf("ServeMode(%d)", int(m))
+	}
+}
+
+// FaultConfig configures the faults a MockServer injects.
+//
+// ResetRate is the probability (0 to 1) of resetting the connection instead of answering.
+// When BurstEvery is above zero, the first BurstLength requests of every BurstEvery
+// requests are answered with BurstStatus (503 by default).
+type FaultConfig struct {
+	ResetRate   float64
+	BurstEvery  uint64
+	BurstLength uint64
+	BurstStatus codes.StatusCode
+}
+
+// MockServer is an http.Handler that serves the rounds stored in a Pack. Incoming
+// paths (and queries) are mapped to the stored URLs, so a pack recorded against
+// https://api.example.com/items is served under /items. When URLs of several hosts
+// share a path, the one whose host is the Host of the request is served.
+//
+// The rounds are read once, by SetPack, LoadPackFile or a reload: rounds added to
+// the pack afterwards are not served until the pack is set again.
+//
+// The handler answers admin requests under AdminPrefix:
+//
+//	POST {AdminPrefix}/reload   reloads the pack from PackFile
+//
+// Packs are loaded with PackConfig, whose KeyFunc should be the key function the
+// pack was saved with, see NewResponsePackFromJSONWithConfig.
+type MockServer struct {
+	Mode        ServeMode
+	Latency     time.Duration
+	Jitter      time.Duration
+	Faults      FaultConfig
+	PackFile    string
+	PackConfig  ConfigResponsePack
+	AdminPrefix string
+	mu          sync.Mutex
+	routes      map[string][]string // map[path]stored URLs
+	rounds      map[string][]Round  // map[stored URL]rounds, in key and round order
+	cursors     map[string]int
+	sticky      map[string]int
+	requests    uint64
+	rng         *rand.Rand
+}
+
+// NewMockServer creates a MockServer serving pack with the given mode.
+func NewMockServer(pack Pack, mode ServeMode) *MockServer {
+	server := &MockServer{
+		Mode:        mode,
+		AdminPrefix: "/__admin",
+		rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	server.SetPack(pack)
+	return server
+}
+
+// SetPack replaces the served pack, reading its rounds, and resets round cursors.
+func (s *MockServer) SetPack(pack Pack) {
+	routes := map[string][]string{}
+	rounds := map[string][]Round{}
+	if pack != nil {
+		// Keys are URLs by default, but any KeyFunc may group several URLs
+		err := forEachRound(pack, func(key string, seq int, response *Response) error {
+			if _, ok := rounds[response.Url]; !ok {
+				path := routePath(response.Url)
+				routes[path] = append(routes[path], response.Url)
+			}
+			rounds[response.Url] = append(rounds[response.Url], Round{Seq: seq, Response: response})
+			return nil
+		})
+		if err != nil {
+			routes, rounds = map[string][]string{}, map[string][]Round{}
+		}
+		for path := range routes {
+			sort.Strings(routes[path])
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = routes
+	s.rounds = rounds
+	s.cursors = map[string]int{}
+	s.sticky = map[string]int{}
+}
+
+// Seed makes random choices (ServeRandom, ResetRate) deterministic.
+func (s *MockServer) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rng = rand.New(rand.NewSource(seed))
+}
+
+// LoadPackFile loads a ResponsePack JSON file with PackConfig and serves it.
+func (s *MockServer) LoadPackFile(path string) error {
+	pack, err := LoadResponsePackFileWithConfig(path, s.PackConfig)
+	if err != nil {
+		return err
+	}
+	s.SetPack(pack)
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.AdminPrefix != "" && strings.HasPrefix(r.URL.Path, s.AdminPrefix+"/") {
+		s.serveAdmin(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests++
+	requestNumber := s.requests
+	faults := s.Faults
+	reset := faults.ResetRate > 0 && s.rng.Float64() < faults.ResetRate
+	delay := s.Latency
+	if s.Jitter > 0 {
+		delay += time.Duration(s.rng.Int63n(int64(s.Jitter)))
+	}
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if reset {
+		resetConnection(w)
+		return
+	}
+
+	if faults.inBurst(requestNumber) {
+		status := faults.BurstStatus
+		if status == 0 {
+			status = codes.ServiceUnavailable
+		}
+		writeMockError(w, status, "injected fault")
+		return
+	}
+
+	response, round, status := s.pick(r)
+	if response == nil {
+		writeMockError(w, status, fmt.Sprintf("no recorded response for %s %s", r.Method, r.URL.RequestURI()))
+		return
+	}
+
+	header := w.Header()
+	for key, value := range response.Headers {
+		if strings.EqualFold(key, "Content-Length") || strings.EqualFold(key, "Transfer-Encoding") {
+			continue
+		}
+		header.Set(key, value)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(response.Body)))
+	header.Set("X-Mock-Round", strconv.Itoa(round))
+	w.WriteHeader(int(response.StatusCode))
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(response.Body)
+	}
+}
+
+// inBurst reports whether the n-th request falls inside a 5xx burst.
+func (f FaultConfig) inBurst(n uint64) bool {
+	if f.BurstEvery == 0 || f.BurstLength == 0 {
+		return false
+	}
+	return (n-1)%f.BurstEvery < f.BurstLength
+}
+
+// pick selects the round answering r and returns it with its sequence number. When
+// nothing can answer, it returns nil and the status to reply with.
+func (s *MockServer) pick(r *http.Request) (*Response, int, codes.StatusCode) {
+	s.mu.Lock()
+	urls := s.routes[r.URL.Path]
+	s.mu.Unlock()
+
+	urls = sameHost(urls, r.Host)
+	if len(urls) == 0 {
+		return nil, 0, codes.NotFound
+	}
+
+	url, ok := sameQuery(urls, r.URL.RawQuery)
+	if !ok {
+		return nil, 0, codes.NotFound
+	}
+
+	s.mu.Lock()
+	rounds := s.rounds[url]
+	s.mu.Unlock()
+
+	var matched []int
+	for index, round := range rounds {
+		if strings.EqualFold(string(round.Response.Method), r.Method) {
+			matched = append(matched, index)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, 0, codes.MethodNotAllowed
+	}
+
+	cursorKey := r.Method + " " + url
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var position int
+	switch s.Mode {
+	case ServeRandom:
+		position = s.rng.Intn(len(matched))
+	case ServeSticky:
+		client := clientID(r) + "|" + cursorKey
+		assigned, ok := s.sticky[client]
+		if !ok {
+			assigned = s.cursors[cursorKey] % len(matched)
+			s.cursors[cursorKey]++
+			s.sticky[client] = assigned
+		}
+		position = assigned % len(matched)
+	default:
+		position = s.cursors[cursorKey] % len(matched)
+		s.cursors[cursorKey]++
+	}
+
+	round := rounds[matched[position]]
+	return round.Response, round.Seq, 0
+}
+
+// serveAdmin answers requests under AdminPrefix.
+func (s *MockServer) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, s.AdminPrefix) {
+	case "/reload":
+		if r.Method != http.MethodPost {
+			writeMockError(w, codes.MethodNotAllowed, "reload requires POST")
+			return
+		}
+		if s.PackFile == "" {
+			writeMockError(w, codes.BadRequest, "no pack file configured")
+			return
+		}
+		if err := s.LoadPackFile(s.PackFile); err != nil {
+			writeMockError(w, codes.InternalServerError, err.Error())
+			return
+		}
+		s.mu.Lock()
+		routes := len(s.routes)
+		s.mu.Unlock()
+		writeMockJSON(w, codes.OK, map[string]interface{}{"reloaded": s.PackFile, "paths": routes})
+	default:
+		writeMockError(w, codes.NotFound, "unknown admin endpoint")
+	}
+}
+
+// routePath returns the path a stored URL is served under.
+func routePath(key string) string {
+	parsed, err := urlPack.Parse(key)
+	if err != nil || parsed.Path == "" {
+		return "/"
+	}
+	return parsed.Path
+}
+
+// sameHost returns the URLs of host when urls span several hosts and some are on
+// host, urls otherwise.
+func sameHost(urls []string, host string) []string {
+	hostname := host
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		hostname = name
+	}
+	var matched []string
+	hosts := map[string]bool{}
+	for _, url := range urls {
+		stored, err := urlPack.Parse(url)
+		if err != nil {
+			continue
+		}
+		hosts[strings.ToLower(stored.Host)] = true
+		if strings.EqualFold(stored.Host, host) || strings.EqualFold(stored.Hostname(), hostname) {
+			matched = append(matched, url)
+		}
+	}
+	if len(hosts) < 2 || len(matched) == 0 {
+		return urls
+	}
+	return matched
+}
+
+// sameQuery returns the URL of urls whose query is query. A single URL answers any
+// query; among several query variants, none answers a query that was not recorded.
+func sameQuery(urls []string, query string) (string, bool) {
+	if len(urls) == 1 {
+		return urls[0], true
+	}
+	for _, candidate := range urls {
+		stored, err := urlPack.Parse(candidate)
+		if err == nil && stored.RawQuery == query {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// clientID identifies a client for ServeSticky.
+func clientID(r *http.Request) string {
+	if id := r.Header.Get("X-Mock-Client"); id != "" {
+		return id
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// resetConnection closes the underlying connection without answering. On TCP
+// connections the linger is set to zero so the client sees a reset.
+func resetConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.SetLinger(0)
+	}
+	_ = conn.Close()
+}
+
+// writeMockError writes a JSON error body with the given status.
+func writeMockError(w http.ResponseWriter, status codes.StatusCode, message string) {
+	writeMockJSON(w, status, map[string]string{"error": message})
+}
+
+// writeMockJSON writes value as a JSON body with the given status.
+func writeMockJSON(w http.ResponseWriter, status codes.StatusCode, value interface{}) {
+	data, _ := json.Marshal(value)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(status))
+	_, _ = w.Write(data)
+}
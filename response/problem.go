@@ -0,0 +1,178 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Problem Details
+// ----------------------------------------------------------------------
+
+// Media types of RFC 9457 problem details.
+const (
+	ProblemJSONType = "application/problem+json"
+	ProblemXMLType  = "application/problem+xml"
+)
+
+// problemBlank is the problem type used when a problem omits it.
+const problemBlank = "about:blank"
+
+// ProblemDetails is an RFC 9457 problem details object. Members with a wrong type
+// are ignored, as the RFC requires; members other than the standard ones are kept
+// in Extensions.
+type ProblemDetails struct {
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title,omitempty"`
+	Status     int                    `json:"status,omitempty"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Summary returns "title: detail", or whichever of the two is present.
+func (p *ProblemDetails) Summary() string {
+	switch {
+	case p.Title != "" && p.Detail != "":
+		return p.Title + ": " + p.Detail
+	case p.Title != "":
+		return p.Title
+	default:
+		return p.Detail
+	}
+}
+
+// Problem parses the body of the response as problem details when its Content-Type
+// is application/problem+json or application/problem+xml. An error is returned for
+// other content types and for malformed bodies.
+func (r *Response) Problem() (*ProblemDetails, error) {
+	contentType := lowerHeaders(r.Headers)["content-type"]
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("response is not a problem details document: %q", contentType)
+	}
+
+	switch mediaType {
+	case ProblemJSONType:
+		return parseProblemJSON(r.Body)
+	case ProblemXMLType:
+		return parseProblemXML(r.Body)
+	default:
+		return nil, fmt.Errorf("response is not a problem details document: %q", contentType)
+	}
+}
+
+// parseProblemJSON decodes an application/problem+json body.
+func parseProblemJSON(body []byte) (*ProblemDetails, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var members map[string]interface{}
+	if err := decoder.Decode(&members); err != nil {
+		return nil, fmt.Errorf("failed to decode problem details: %v", err)
+	}
+
+	problem := &ProblemDetails{Type: problemBlank, Extensions: map[string]interface{}{}}
+	for name, value := range members {
+		switch name {
+		case "type", "title", "detail", "instance":
+			if text, ok := value.(string); ok {
+				problem.setMember(name, text)
+			}
+		case "status":
+			if number, ok := value.(json.Number); ok {
+				if status, err := number.Int64(); err == nil {
+					problem.Status = int(status)
+				}
+			}
+		default:
+			problem.Extensions[name] = value
+		}
+	}
+	return problem, nil
+}
+
+// xmlProblemNode is an element of an application/problem+xml body.
+type xmlProblemNode struct {
+	XMLName  xml.Name
+	Text     string           `xml:",chardata"`
+	Children []xmlProblemNode `xml:",any"`
+}
+
+// parseProblemXML decodes an application/problem+xml body. Extension elements with
+// children become maps, or lists when every child is an <i> element (RFC 9457,
+// Appendix B); other extension elements become strings.
+func parseProblemXML(body []byte) (*ProblemDetails, error) {
+	var root xmlProblemNode
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("failed to decode problem details: %v", err)
+	}
+	if root.XMLName.Local != "problem" {
+		return nil, fmt.Errorf("failed to decode problem details: root element is <%s>", root.XMLName.Local)
+	}
+
+	problem := &ProblemDetails{Type: problemBlank, Extensions: map[string]interface{}{}}
+	for _, child := range root.Children {
+		name := child.XMLName.Local
+		text := strings.TrimSpace(child.Text)
+		switch name {
+		case "type", "title", "detail", "instance":
+			problem.setMember(name, text)
+		case "status":
+			var status int
+			if _, err := fmt.Sscanf(text, "%d", &status); err == nil {
+				problem.Status = status
+			}
+		default:
+			problem.Extensions[name] = child.value()
+		}
+	}
+	return problem, nil
+}
+
+// value converts an extension element into a string, list or map.
+func (n xmlProblemNode) value() interface{} {
+	if len(n.Children) == 0 {
+		return strings.TrimSpace(n.Text)
+	}
+
+	list := true
+	for _, child := range n.Children {
+		if child.XMLName.Local != "i" {
+			list = false
+			break
+		}
+	}
+	if list {
+		items := make([]interface{}, 0, len(n.Children))
+		for _, child := range n.Children {
+			items = append(items, child.value())
+		}
+		return items
+	}
+
+	members := map[string]interface{}{}
+	for _, child := range n.Children {
+		members[child.XMLName.Local] = child.value()
+	}
+	return members
+}
+
+// setMember sets one of the standard string members.
+func (p *ProblemDetails) setMember(name string, value string) {
+	switch name {
+	case "type":
+		if value != "" {
+			p.Type = value
+		}
+	case "title":
+		p.Title = value
+	case "detail":
+		p.Detail = value
+	case "instance":
+		p.Instance = value
+	}
+}
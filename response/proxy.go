@@ -0,0 +1,213 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httputil"
+	urlPack "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// Recording Proxy
+// ----------------------------------------------------------------------
+
+// recordingKey is the context key holding the capture state of a proxied request.
+type recordingKey struct{}
+
+// recordingState is the capture state of a proxied request.
+type recordingState struct {
+	start time.Time
+	body  []byte
+}
+
+// RecordingProxy is a reverse proxy, built on httputil.ReverseProxy, that forwards
+// traffic to Target and captures every exchange into Pack.
+//
+// Captured rounds are stored under the upstream URL, keep the request in
+// Response.Request and the latency in Response.Timing. Redaction is left to Pack,
+// see SetRedactionPolicy.
+//
+// The proxy answers admin requests under AdminPrefix:
+//
+//	GET {AdminPrefix}/pack              downloads the pack as JSON
+//	GET {AdminPrefix}/pack?format=har   downloads the pack as HAR 1.2
+type RecordingProxy struct {
+	Pack        Pack
+	Target      *urlPack.URL
+	SampleRate  float64
+	AdminPrefix string
+	OnError     func(err error)
+	proxy       *httputil.ReverseProxy
+	mu          sync.Mutex
+	rng         *rand.Rand
+}
+
+// NewRecordingProxy creates a RecordingProxy forwarding to target and recording into
+// pack. Every exchange is recorded until SampleRate is lowered.
+func NewRecordingProxy(target string, pack Pack) (*RecordingProxy, error) {
+	if pack == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+
+	targetURL, err := urlPack.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target URL: %w", err)
+	}
+	if targetURL.Scheme == "" || targetURL.Host == "" {
+		return nil, fmt.Errorf("invalid target URL: %s", target)
+	}
+
+	recorder := &RecordingProxy{
+		Pack:        pack,
+		Target:      targetURL,
+		SampleRate:  1,
+		AdminPrefix: "/__proxy",
+		rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	recorder.proxy = httputil.NewSingleHostReverseProxy(targetURL)
+	recorder.proxy.ModifyResponse = recorder.capture
+
+	return recorder, nil
+}
+
+// redactingPack is a Pack that redacts the responses it stores.
+type redactingPack interface {
+	Pack
+	SetRedactionPolicy(policy *RedactionPolicy)
+}
+
+// SetRedactionPolicy sets the policy redacting captured rounds. The policy is set on
+// Pack, which redacts every round as it is stored, so each round is redacted once.
+// It returns an error if Pack does not support redaction.
+func (p *RecordingProxy) SetRedactionPolicy(policy *RedactionPolicy) error {
+	pack, ok := p.Pack.(redactingPack)
+	if !ok {
+		return fmt.Errorf("pack %T does not support redaction", p.Pack)
+	}
+	pack.SetRedactionPolicy(policy)
+	return nil
+}
+
+// Seed makes sampling decisions deterministic.
+func (p *RecordingProxy) Seed(seed int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rng = rand.New(rand.NewSource(seed))
+}
+
+// ServeHTTP implements http.Handler.
+func (p *RecordingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.AdminPrefix != "" && strings.HasPrefix(r.URL.Path, p.AdminPrefix+"/") {
+		p.serveAdmin(w, r)
+		return
+	}
+
+	if !p.sampled() {
+		p.proxy.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := readRequestBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	state := &recordingState{start: time.Now(), body: body}
+	p.proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), recordingKey{}, state)))
+}
+
+// sampled decides whether the next exchange is recorded.
+func (p *RecordingProxy) sampled() bool {
+	if p.SampleRate >= 1 {
+		return true
+	}
+	if p.SampleRate <= 0 {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rng.Float64() < p.SampleRate
+}
+
+// capture is the ModifyResponse hook storing the upstream exchange into the pack.
+// Capture failures are reported to OnError and never break the proxied response.
+func (p *RecordingProxy) capture(httpResponse *http.Response) error {
+	state, ok := httpResponse.Request.Context().Value(recordingKey{}).(*recordingState)
+	if !ok {
+		return nil
+	}
+
+	response, err := NewResponseFromHTTPResponse(httpResponse)
+	if err != nil {
+		p.reportError(err)
+		return nil
+	}
+
+	outgoing := httpResponse.Request
+	response.Url = outgoing.URL.String()
+	response.Host = outgoing.URL.Host
+	response.Method = codes.Method(outgoing.Method)
+	response.Request = newRecordedRequest(outgoing, state.body)
+	response.Timing = &Timing{Start: state.start, Duration: time.Since(state.start)}
+
+	err = p.Pack.AddResponse(response)
+	if err != nil {
+		p.reportError(err)
+	}
+	return nil
+}
+
+// reportError passes capture errors to OnError, when set.
+func (p *RecordingProxy) reportError(err error) {
+	if p.OnError != nil {
+		p.OnError(fmt.Errorf("proxy: failed to record exchange: %w", err))
+	}
+}
+
+// serveAdmin answers requests under AdminPrefix.
+func (p *RecordingProxy) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimPrefix(r.URL.Path, p.AdminPrefix) != "/pack" {
+		writeMockError(w, codes.NotFound, "unknown admin endpoint")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMockError(w, codes.MethodNotAllowed, "pack download requires GET")
+		return
+	}
+
+	var data []byte
+	var err error
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "", "json":
+		var snapshot *ResponsePack
+		snapshot, err = snapshotPack(p.Pack)
+		if err == nil {
+			data, err = snapshot.ToJSON()
+		}
+	case "har":
+		data, err = PackToHAR(p.Pack)
+	default:
+		writeMockError(w, codes.BadRequest, "unknown format, use json or har")
+		return
+	}
+
+	if err != nil {
+		writeMockError(w, codes.InternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,472 @@
+package response
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// Tags
+// ----------------------------------------------------------------------
+
+// AddTags adds tags to the response, skipping the ones it already has. Tag the
+// response before adding it to a pack.
+func (r *Response) AddTags(tags ...string) {
+	for _, tag := range tags {
+		if !r.HasTag(tag) {
+			r.Tags = append(r.Tags, tag)
+		}
+	}
+}
+
+// HasTag reports whether the response has tag.
+func (r *Response) HasTag(tag string) bool {
+	for _, own := range r.Tags {
+		if own == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Predicates
+// ----------------------------------------------------------------------
+
+// Predicate selects the responses of a Query.
+type Predicate interface {
+	Match(response *Response) bool
+}
+
+// PredicateFunc adapts a function to the Predicate interface.
+type PredicateFunc func(response *Response) bool
+
+// Match calls f(response).
+func (f PredicateFunc) Match(response *Response) bool {
+	return f(response)
+}
+
+// StatusRange selects the status codes from min to max, both included.
+func StatusRange(min, max codes.StatusCode) Predicate {
+	return statusPredicate(func(status int) bool {
+		return codes.StatusCode(status) >= min && codes.StatusCode(status) <= max
+	})
+}
+
+// StatusClass selects the status classes, "1xx" to "5xx", as in PackStats.
+func StatusClass(classes ...string) Predicate {
+	return statusPredicate(func(status int) bool {
+		class := statusClass(status)
+		for _, wanted := range classes {
+			if strings.EqualFold(class, wanted) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MethodIs selects the methods, compared case-insensitively.
+func MethodIs(methods ...codes.Method) Predicate {
+	values := make([]string, len(methods))
+	for i, method := range methods {
+		values[i] = indexValue(IndexMethod, string(method))
+	}
+	return indexedPredicate{
+		field:  IndexMethod,
+		values: values,
+		match: func(response *Response) bool {
+			for _, method := range methods {
+				if strings.EqualFold(string(response.Method), string(method)) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// HostIs selects the hosts, compared case-insensitively. The host of a response is
+// Response.Host, or the host of its URL when empty.
+func HostIs(hosts ...string) Predicate {
+	values := make([]string, len(hosts))
+	for i, host := range hosts {
+		values[i] = indexValue(IndexHost, host)
+	}
+	return indexedPredicate{
+		field:  IndexHost,
+		values: values,
+		match: func(response *Response) bool {
+			host := packHost(response)
+			for _, wanted := range hosts {
+				if strings.EqualFold(host, wanted) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// URLPrefix selects the URLs starting with prefix.
+func URLPrefix(prefix string) Predicate {
+	return PredicateFunc(func(response *Response) bool {
+		return strings.HasPrefix(response.Url, prefix)
+	})
+}
+
+// URLMatches selects the URLs matching pattern.
+func URLMatches(pattern *regexp.Regexp) Predicate {
+	return PredicateFunc(func(response *Response) bool {
+		return pattern.MatchString(response.Url)
+	})
+}
+
+// HeaderIs selects the responses whose header name, case-insensitive, equals value.
+func HeaderIs(name, value string) Predicate {
+	name = strings.ToLower(name)
+	return PredicateFunc(func(response *Response) bool {
+		actual, ok := lowerHeaders(response.Headers)[name]
+		return ok && actual == value
+	})
+}
+
+// HeaderMatches selects the responses whose header name, case-insensitive, matches
+// pattern.
+func HeaderMatches(name string, pattern *regexp.Regexp) Predicate {
+	name = strings.ToLower(name)
+	return PredicateFunc(func(response *Response) bool {
+		actual, ok := lowerHeaders(response.Headers)[name]
+		return ok && pattern.MatchString(actual)
+	})
+}
+
+// BodyContains selects the responses whose body contains substr.
+func BodyContains(substr string) Predicate {
+	return PredicateFunc(func(response *Response) bool {
+		return bytes.Contains(response.Body, []byte(substr))
+	})
+}
+
+// TimeRange selects the responses whose Timing.Start is within [from, to). A zero
+// bound is open. Responses without Timing are not selected.
+func TimeRange(from, to time.Time) Predicate {
+	return PredicateFunc(func(response *Response) bool {
+		if response.Timing == nil {
+			return false
+		}
+		start := response.Timing.Start
+		return (from.IsZero() || !start.Before(from)) && (to.IsZero() || start.Before(to))
+	})
+}
+
+// TaggedWith selects the responses that have every tag.
+func TaggedWith(tags ...string) Predicate {
+	predicates := make(allOf, len(tags))
+	for i, tag := range tags {
+		tag := tag
+		predicates[i] = indexedPredicate{
+			field:  IndexTag,
+			values: []string{tag},
+			match:  func(response *Response) bool { return response.HasTag(tag) },
+		}
+	}
+	return predicates
+}
+
+// AnyOf selects the responses selected by at least one of predicates.
+func AnyOf(predicates ...Predicate) Predicate {
+	return anyOf(predicates)
+}
+
+// Not selects the responses predicate does not select.
+func Not(predicate Predicate) Predicate {
+	return PredicateFunc(func(response *Response) bool {
+		return !predicate.Match(response)
+	})
+}
+
+// allOf selects the responses every predicate selects.
+type allOf []Predicate
+
+// Match reports whether every predicate selects response.
+func (a allOf) Match(response *Response) bool {
+	for _, predicate := range a {
+		if predicate != nil && !predicate.Match(response) {
+			return false
+		}
+	}
+	return true
+}
+
+// candidates intersects the candidates of the predicates answered by an index.
+func (a allOf) candidates(indexes *packIndexes) (roundSet, bool) {
+	var output roundSet
+	for _, predicate := range a {
+		if set, ok := candidatesOf(predicate, indexes); ok {
+			output = intersect(output, set)
+		}
+	}
+	return output, output != nil
+}
+
+// anyOf selects the responses at least one predicate selects.
+type anyOf []Predicate
+
+// Match reports whether a predicate selects response.
+func (a anyOf) Match(response *Response) bool {
+	for _, predicate := range a {
+		if predicate != nil && predicate.Match(response) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates unites the candidates of the predicates, provided an index answers
+// every one of them.
+func (a anyOf) candidates(indexes *packIndexes) (roundSet, bool) {
+	output := roundSet{}
+	for _, predicate := range a {
+		set, ok := candidatesOf(predicate, indexes)
+		if !ok {
+			return nil, false
+		}
+		output.addAll(set)
+	}
+	return output, true
+}
+
+// Query
+// ----------------------------------------------------------------------
+
+// SortField is the order of the results of a Query.
+type SortField int
+
+const (
+	// SortByKey orders by pack key, then by round. It is the default.
+	SortByKey SortField = iota
+	// SortByStatus orders by status code.
+	SortByStatus
+	// SortByTime orders by Timing.Start, rounds without Timing first.
+	SortByTime
+	// SortByLatency orders by Timing.Duration, rounds without Timing first.
+	SortByLatency
+	// SortByBodySize orders by the length of the body.
+	SortByBodySize
+)
+
+// String returns the name of the sort field.
+func (f SortField) String() string {
+	switch f {
+	case SortByKey:
+		return "key"
+	case SortByStatus:
+		return "status"
+	case SortByTime:
+		return "time"
+	case SortByLatency:
+		return "latency"
+	case SortByBodySize:
+		return "bodySize"
+	default:
+		return fmt.Sprintf("SortField(%d)", int(f))
+	}
+}
+
+// Query selects rounds of a pack.
+//
+// A round is selected when every predicate of Where selects it; an empty Where
+// selects every round. Results are ordered by SortBy, ties keeping the key and
+// round order, and reversed when Descending. Offset results are skipped and at
+// most Limit are returned, 0 meaning no limit.
+type Query struct {
+	Where      []Predicate
+	SortBy     SortField
+	Descending bool
+	Offset     int
+	Limit      int
+}
+
+// QueryResult is a round selected by a Query: its pack key, its sequence number and
+// the response.
+type QueryResult struct {
+	Key      string    `json:"key"`
+	Round    int       `json:"round"`
+	Response *Response `json:"response"`
+}
+
+// validate checks the pagination of the query.
+func (q Query) validate() error {
+	if q.Offset < 0 || q.Limit < 0 {
+		return fmt.Errorf("query offset and limit must not be negative")
+	}
+	return nil
+}
+
+// matches reports whether every predicate selects response.
+func (q Query) matches(response *Response) bool {
+	return allOf(q.Where).Match(response)
+}
+
+// less reports whether a is ordered before b by the sort field of the query.
+func (q Query) less(a, b *Response) bool {
+	switch q.SortBy {
+	case SortByStatus:
+		return a.StatusCode < b.StatusCode
+	case SortByTime:
+		return timingStart(a).Before(timingStart(b))
+	case SortByLatency:
+		return timingDuration(a) < timingDuration(b)
+	case SortByBodySize:
+		return len(a.Body) < len(b.Body)
+	default:
+		return false
+	}
+}
+
+// timingStart returns Timing.Start, the zero time without Timing.
+func timingStart(response *Response) time.Time {
+	if response.Timing == nil {
+		return time.Time{}
+	}
+	return response.Timing.Start
+}
+
+// timingDuration returns Timing.Duration, 0 without Timing.
+func timingDuration(response *Response) time.Duration {
+	if response.Timing == nil {
+		return 0
+	}
+	return response.Timing.Duration
+}
+
+// QueryPack runs query over pack. The rounds of each key are read under the lock of
+// the pack, so a concurrent AddResponse never leaves a key half read.
+func QueryPack(pack Pack, query Query) ([]QueryResult, error) {
+	if pack == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	results := []QueryResult{}
+	err := forEachRound(pack, func(key string, round int, response *Response) error {
+		if query.matches(response) {
+			results = append(results, QueryResult{Key: key, Round: round, Response: response})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return query.arrange(results), nil
+}
+
+// arrange sorts and paginates results, given in key and round order.
+func (q Query) arrange(results []QueryResult) []QueryResult {
+	if q.SortBy != SortByKey {
+		sort.SliceStable(results, func(i, j int) bool {
+			return q.less(results[i].Response, results[j].Response)
+		})
+	}
+	if q.Descending {
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+		}
+	}
+
+	if q.Offset >= len(results) {
+		return []QueryResult{}
+	}
+	results = results[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(results) {
+		results = results[:q.Limit]
+	}
+	return results
+}
+
+// Query returns the rounds of the pack selected by query. When the indexes of the
+// pack answer a predicate of query, only the rounds they select are read.
+func (p *ResponsePack) Query(query Query) ([]QueryResult, error) {
+	if p == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	if results, ok := p.queryIndexed(query); ok {
+		return results, nil
+	}
+	return QueryPack(p, query)
+}
+
+// Filter returns a new ResponsePack with the rounds selected by query, added in
+// result order and numbered from 1. The new pack shares the responses of p and has
+// its key function, classifier, redaction policy, indexes and info; the rounds, already
+// redacted, are not redacted again.
+func (p *ResponsePack) Filter(query Query) (*ResponsePack, error) {
+	results, err := p.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	config := ConfigResponsePack{
+		KeyFunc:                p.key,
+		Classifier:             p.classifier,
+		GraphQLErrorsAsFailure: p.graphQLErrorsAsFailure,
+		DropRawResponse:        p.DropRawResponse,
+		DedupBodies:            p.blobs != nil,
+		Info:                   p.Info,
+		Indexes:                p.indexes.fieldList(),
+	}
+	redaction := p.Redaction
+	output := NewResponsePackFromConfig(config)
+	p.mu.RUnlock()
+
+	for _, result := range results {
+		if err := output.AddResponse(result.Response); err != nil {
+			return nil, err
+		}
+	}
+	output.SetRedactionPolicy(redaction)
+	return output, nil
+}
+
+// Query returns the rounds of the pack selected by query, decompressed.
+func (r *CompressResponsePack) Query(query Query) ([]QueryResult, error) {
+	if r == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+	return QueryPack(r, query)
+}
+
+// Filter returns a new CompressResponsePack with the rounds selected by query,
+// added in result order and numbered from 1. The new pack has the key function,
+// redaction policy and meta info of r; the rounds are not redacted again.
+func (r *CompressResponsePack) Filter(query Query) (*CompressResponsePack, error) {
+	results, err := r.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	config := ConfigCompressResponsePack{KeyFunc: r.key, MetaInfo: r.MetaInfo, DedupBodies: r.blobs != nil}
+	redaction := r.Redaction
+	output := NewCompressResponsePackFromConfig(config)
+	r.mu.RUnlock()
+
+	for _, result := range results {
+		if err := output.AddResponse(result.Response); err != nil {
+			return nil, err
+		}
+	}
+	output.SetRedactionPolicy(redaction)
+	return output, nil
+}
@@ -0,0 +1,389 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// Rate Limit Headers
+// ----------------------------------------------------------------------
+
+// Sources of the rate limit information of a RateLimit.
+const (
+	RateLimitSourceIETF = "ietf"
+	RateLimitSourceX    = "x-ratelimit"
+)
+
+// epochThreshold separates X-RateLimit-Reset values given as Unix timestamps from
+// values given as delta seconds.
+const epochThreshold = 1000000000
+
+// RateLimitPolicy is a quota advertised by RateLimit-Policy, e.g. "default";q=100;w=60
+// or 100;w=60. Window is -1 when the policy has none.
+type RateLimitPolicy struct {
+	Name   string            `json:"name"`
+	Quota  int64             `json:"quota"`
+	Window time.Duration     `json:"window"`
+	Params map[string]string `json:"params"`
+}
+
+// RateLimit is the throttling information of a response. Numeric fields are -1 when
+// the headers do not carry them.
+//
+// RetryAfter comes from Retry-After, in seconds or HTTP-date form; a date is turned
+// into a delay from the Date header or the end of Timing, and RetryAt is set in both
+// forms when a reference time is known. Limit, Remaining and Reset come from the
+// IETF RateLimit headers, or from the X-RateLimit-* and X-Rate-Limit-* variants;
+// Source tells which.
+type RateLimit struct {
+	RetryAfter time.Duration     `json:"retryAfter"`
+	RetryAt    time.Time         `json:"retryAt"`
+	Limit      int64             `json:"limit"`
+	Remaining  int64             `json:"remaining"`
+	Reset      time.Duration     `json:"reset"`
+	Policies   []RateLimitPolicy `json:"policies"`
+	Source     string            `json:"source"`
+}
+
+// Throttled reports whether the limit is exhausted or the server asked to retry later.
+func (l RateLimit) Throttled() bool {
+	return l.Remaining == 0 || l.RetryAfter >= 0 || !l.RetryAt.IsZero()
+}
+
+// RateLimit parses the Retry-After, RateLimit, RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset, RateLimit-Policy and X-RateLimit-* headers of the response.
+func (r *Response) RateLimit() RateLimit {
+	headers := lowerHeaders(r.Headers)
+	limit := RateLimit{RetryAfter: -1, Limit: -1, Remaining: -1, Reset: -1, Policies: []RateLimitPolicy{}}
+
+	reference, _ := http.ParseTime(headers["date"])
+	if reference.IsZero() && r.Timing != nil && !r.Timing.Start.IsZero() {
+		reference = r.Timing.Start.Add(r.Timing.Duration)
+	}
+
+	// Retry-After
+	if value := strings.TrimSpace(headers["retry-after"]); value != "" {
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+			limit.RetryAfter = time.Duration(seconds) * time.Second
+			if !reference.IsZero() {
+				limit.RetryAt = reference.Add(limit.RetryAfter)
+			}
+		} else if date, err := http.ParseTime(value); err == nil {
+			limit.RetryAt = date
+			if !reference.IsZero() {
+				limit.RetryAfter = max(date.Sub(reference), 0)
+			}
+		}
+	}
+
+	// IETF RateLimit, either "name";r=50;t=30 items or limit=100, remaining=50, reset=30
+	if value, ok := headers["ratelimit"]; ok {
+		limit.Source = RateLimitSourceIETF
+		for _, item := range splitQuoted(value) {
+			name, params := parseRateLimitItem(item)
+			switch {
+			case params["r"] != "" || params["t"] != "":
+				limit.Remaining = parseCount(params["r"], limit.Remaining)
+				limit.Reset = parseSeconds(params["t"], limit.Reset)
+			case strings.EqualFold(name, "limit"):
+				limit.Limit = parseCount(params[""], limit.Limit)
+			case strings.EqualFold(name, "remaining"):
+				limit.Remaining = parseCount(params[""], limit.Remaining)
+			case strings.EqualFold(name, "reset"):
+				limit.Reset = parseSeconds(params[""], limit.Reset)
+			}
+		}
+	}
+	for _, prefix := range []string{"ratelimit-", "x-ratelimit-", "x-rate-limit-"} {
+		if limit.Source != "" && limit.Source != rateLimitSource(prefix) {
+			break
+		}
+		found := false
+		if value, ok := headers[prefix+"limit"]; ok {
+			limit.Limit = parseCount(leadingToken(value), limit.Limit)
+			found = true
+		}
+		if value, ok := headers[prefix+"remaining"]; ok {
+			limit.Remaining = parseCount(leadingToken(value), limit.Remaining)
+			found = true
+		}
+		if value, ok := headers[prefix+"reset-after"]; ok {
+			limit.Reset = parseSeconds(value, limit.Reset)
+			found = true
+		} else if value, ok := headers[prefix+"reset"]; ok {
+			limit.Reset = parseReset(value, reference, limit.Reset)
+			found = true
+		}
+		if found && limit.Source == "" {
+			limit.Source = rateLimitSource(prefix)
+		}
+	}
+
+	// RateLimit-Policy
+	for _, item := range splitQuoted(headers["ratelimit-policy"]) {
+		if item == "" {
+			continue
+		}
+		name, params := parseRateLimitItem(item)
+		policy := RateLimitPolicy{Name: name, Quota: -1, Window: -1, Params: params}
+		if quota, ok := params["q"]; ok {
+			policy.Quota = parseCount(quota, -1)
+		} else {
+			// Older drafts: 100;w=60
+			policy.Name = ""
+			policy.Quota = parseCount(name, -1)
+		}
+		policy.Window = parseSeconds(params["w"], -1)
+		limit.Policies = append(limit.Policies, policy)
+	}
+	if limit.Limit < 0 {
+		for _, policy := range limit.Policies {
+			if policy.Quota >= 0 {
+				limit.Limit = policy.Quota
+				break
+			}
+		}
+	}
+	if len(limit.Policies) > 0 && limit.Source == "" {
+		limit.Source = RateLimitSourceIETF
+	}
+
+	return limit
+}
+
+// rateLimitSource returns the Source of the headers starting with prefix.
+func rateLimitSource(prefix string) string {
+	if prefix == "ratelimit-" {
+		return RateLimitSourceIETF
+	}
+	return RateLimitSourceX
+}
+
+// parseRateLimitItem splits a structured item such as "default";q=100;w=60 or
+// limit=100 into its name and parameters. The value of a name=value item is stored
+// under the "" parameter.
+func parseRateLimitItem(item string) (string, map[string]string) {
+	parts := strings.Split(item, ";")
+	params := map[string]string{}
+	name, value, hasValue := strings.Cut(strings.TrimSpace(parts[0]), "=")
+	name = strings.Trim(strings.TrimSpace(name), `"`)
+	if hasValue {
+		params[""] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return name, params
+}
+
+// leadingToken returns the value up to the first "," or ";", as in
+// X-RateLimit-Limit: 100, 100;w=60.
+func leadingToken(value string) string {
+	if index := strings.IndexAny(value, ",;"); index >= 0 {
+		value = value[:index]
+	}
+	return strings.TrimSpace(value)
+}
+
+// parseCount parses a non-negative integer, returning fallback on failure.
+func parseCount(value string, fallback int64) int64 {
+	count, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || count < 0 {
+		return fallback
+	}
+	return count
+}
+
+// parseSeconds parses non-negative, possibly fractional, seconds.
+func parseSeconds(value string, fallback time.Duration) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return fallback
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// parseReset parses a reset given in delta seconds or, above epochThreshold, as a
+// Unix timestamp relative to reference.
+func parseReset(value string, reference time.Time, fallback time.Duration) time.Duration {
+	seconds := parseCount(leadingToken(value), -1)
+	switch {
+	case seconds < 0:
+		return fallback
+	case seconds < epochThreshold:
+		return time.Duration(seconds) * time.Second
+	case reference.IsZero():
+		return fallback
+	default:
+		return max(time.Unix(seconds, 0).Sub(reference), 0)
+	}
+}
+
+// Throttling Report
+// ----------------------------------------------------------------------
+
+// Bounds of the exponential backoff recommended when the server gives no hint.
+const (
+	baseBackoff = time.Second
+	maxBackoff  = time.Minute
+)
+
+// HostThrottling summarizes the throttling of a host.
+//
+// Throttled counts the 429 and 503 rounds; Streak is the longest run of consecutive
+// throttled rounds of a URL. Limit is the largest limit observed and MinRemaining
+// the smallest remaining count (-1 when none was seen). RecommendedBackoff is the
+// longest Retry-After or reset observed on throttled rounds, or an exponential
+// backoff from the streak; RecommendedInterval spaces requests to stay within the
+// tightest observed policy.
+type HostThrottling struct {
+	Host                string            `json:"host"`
+	Rounds              int               `json:"rounds"`
+	Throttled           int               `json:"throttled"`
+	TooManyRequests     int               `json:"tooManyRequests"`
+	Unavailable         int               `json:"unavailable"`
+	Streak              int               `json:"streak"`
+	Urls                map[string]int    `json:"urls"`
+	Limit               int64             `json:"limit"`
+	MinRemaining        int64             `json:"minRemaining"`
+	MaxRetryAfter       time.Duration     `json:"maxRetryAfter"`
+	Policies            []RateLimitPolicy `json:"policies"`
+	RecommendedBackoff  time.Duration     `json:"recommendedBackoff"`
+	RecommendedInterval time.Duration     `json:"recommendedInterval"`
+}
+
+// ThrottleRate returns the share of throttled rounds, between 0 and 1.
+func (h *HostThrottling) ThrottleRate() float64 {
+	if h.Rounds == 0 {
+		return 0
+	}
+	return float64(h.Throttled) / float64(h.Rounds)
+}
+
+// ThrottlingReport is the result of AnalyzeThrottling, one entry per host sorted by host.
+type ThrottlingReport struct {
+	Hosts []HostThrottling `json:"hosts"`
+}
+
+// AnalyzeThrottling reads the rate limit headers of every round of a ResponsePack or
+// CompressResponsePack and summarizes the 429 and 503 rounds per host.
+func AnalyzeThrottling(pack Pack) (*ThrottlingReport, error) {
+	if pack == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+
+	hosts := map[string]*HostThrottling{}
+	policies := map[string]map[string]RateLimitPolicy{}
+	hints := map[string]time.Duration{}
+	streaks := map[string]int{}
+	err := forEachRound(pack, func(key string, round int, response *Response) error {
+		host := response.Host
+		if host == "" {
+			host = hostOf(response.Url)
+		}
+		summary, ok := hosts[host]
+		if !ok {
+			summary = &HostThrottling{Host: host, Urls: map[string]int{}, Limit: -1, MinRemaining: -1, MaxRetryAfter: -1}
+			hosts[host] = summary
+			policies[host] = map[string]RateLimitPolicy{}
+		}
+		summary.Rounds++
+
+		limit := response.RateLimit()
+		summary.Limit = max(summary.Limit, limit.Limit)
+		if limit.Remaining >= 0 && (summary.MinRemaining < 0 || limit.Remaining < summary.MinRemaining) {
+			summary.MinRemaining = limit.Remaining
+		}
+		for _, policy := range limit.Policies {
+			policies[host][fmt.Sprintf("%s;q=%d;w=%d", policy.Name, policy.Quota, policy.Window)] = policy
+		}
+
+		if response.StatusCode != codes.TooManyRequests && response.StatusCode != codes.ServiceUnavailable {
+			streaks[key] = 0
+			return nil
+		}
+
+		summary.Throttled++
+		if response.StatusCode == codes.TooManyRequests {
+			summary.TooManyRequests++
+		} else {
+			summary.Unavailable++
+		}
+		summary.Urls[response.Url]++
+		streaks[key]++
+		summary.Streak = max(summary.Streak, streaks[key])
+		summary.MaxRetryAfter = max(summary.MaxRetryAfter, limit.RetryAfter)
+		hints[host] = max(hints[host], limit.RetryAfter, limit.Reset)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ThrottlingReport{Hosts: []HostThrottling{}}
+	for _, host := range sortedKeys(hosts) {
+		summary := hosts[host]
+
+		summary.Policies = []RateLimitPolicy{}
+		for _, key := range sortedKeys(policies[host]) {
+			policy := policies[host][key]
+			summary.Policies = append(summary.Policies, policy)
+			if policy.Quota > 0 && policy.Window > 0 {
+				summary.RecommendedInterval = max(summary.RecommendedInterval, policy.Window/time.Duration(policy.Quota))
+			}
+		}
+
+		switch {
+		case hints[host] > 0:
+			summary.RecommendedBackoff = hints[host]
+		case summary.Streak > 0:
+			summary.RecommendedBackoff = min(baseBackoff<<min(summary.Streak-1, 16), maxBackoff)
+		}
+		report.Hosts = append(report.Hosts, *summary)
+	}
+	return report, nil
+}
+
+// ToJSON converts the report to a JSON-encoded byte slice.
+func (r *ThrottlingReport) ToJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// ToString returns a text report, one block per host.
+func (r *ThrottlingReport) ToString() string {
+	var sb strings.Builder
+	sb.WriteString("Throttling Report:")
+	for _, host := range r.Hosts {
+		sb.WriteString(fmt.Sprintf("\nHost: %s\n\tRounds: %d\n\tThrottled: %d (%.2f%%)\n\t429: %d\n\t503: %d\n\tStreak: %d",
+			host.Host, host.Rounds, host.Throttled, host.ThrottleRate()*100, host.TooManyRequests, host.Unavailable, host.Streak))
+		if host.Limit >= 0 {
+			sb.WriteString(fmt.Sprintf("\n\tLimit: %d", host.Limit))
+		}
+		if host.MinRemaining >= 0 {
+			sb.WriteString(fmt.Sprintf("\n\tMinRemaining: %d", host.MinRemaining))
+		}
+		for _, policy := range host.Policies {
+			sb.WriteString(fmt.Sprintf("\n\tPolicy: %s %d per %s", policy.Name, policy.Quota, policy.Window))
+		}
+		if host.RecommendedBackoff > 0 {
+			sb.WriteString("\n\tRecommendedBackoff: " + host.RecommendedBackoff.String())
+		}
+		if host.RecommendedInterval > 0 {
+			sb.WriteString("\n\tRecommendedInterval: " + host.RecommendedInterval.String())
+		}
+	}
+	return sb.String()
+}
+
+// Print prints the text report to the console.
+func (r *ThrottlingReport) Print() {
+	fmt.Println(r.ToString())
+}
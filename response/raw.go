@@ -0,0 +1,162 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httputil"
+)
+
+// Raw Response Storage
+// ----------------------------------------------------------------------
+
+// bodyStart returns the offset of the body in a raw HTTP message, after the blank
+// line ending its head, or -1 when raw has no head.
+func bodyStart(raw []byte) int {
+	if index := bytes.Index(raw, []byte("\r\n\r\n")); index >= 0 {
+		return index + 4
+	}
+	if index := bytes.Index(raw, []byte("\n\n")); index >= 0 {
+		return index + 2
+	}
+	return -1
+}
+
+// BodyShared reports whether Body is a view into RawResponse rather than a copy of
+// its bytes. ParseRawHTTPResponse, ParseStringHTTPResponse and the JSON and
+// compressed decoders share the body when RawResponse carries it as is, i.e. not
+// transfer-encoded. Appending to a shared Body copies it; changing its bytes in
+// place changes RawResponse too.
+//
+// The rounds of a ResponsePack deduplicating bodies also share their body: their
+// RawResponse holds only the head of the message and RawBytes appends Body to it.
+func (r *Response) BodyShared() bool {
+	if len(r.Body) == 0 {
+		return false
+	}
+	if r.rawHead {
+		return true
+	}
+	start := bodyStart(r.RawResponse)
+	if start < 0 || start+len(r.Body) > len(r.RawResponse) {
+		return false
+	}
+	return &r.RawResponse[start] == &r.Body[0]
+}
+
+// shareBody makes Body a view into RawResponse when RawResponse carries the same
+// bytes after its head.
+func (r *Response) shareBody() {
+	if len(r.Body) == 0 || r.rawHead || r.BodyShared() {
+		return
+	}
+	start := bodyStart(r.RawResponse)
+	if start < 0 || start+len(r.Body) > len(r.RawResponse) {
+		return
+	}
+	end := start + len(r.Body)
+	if bytes.Equal(r.RawResponse[start:end], r.Body) {
+		// The capacity stops at the body so appends never write into RawResponse
+		r.Body = r.RawResponse[start:end:end]
+	}
+}
+
+// DropRawResponse releases RawResponse to save memory. RawBytes, ReadRawResponse,
+// ToJSON, ToReadableJSON and Compress then regenerate it from the status code,
+// headers and body, as an HTTP/1.1 message with a Content-Length. A shared Body is
+// copied first so the raw message can be freed.
+func (r *Response) DropRawResponse() {
+	if r.BodyShared() {
+		r.Body = append([]byte(nil), r.Body...)
+	}
+	r.RawResponse = nil
+	r.rawHead = false
+	r.rawDropped = true
+}
+
+// RawBytes returns RawResponse, regenerated when it was dropped, see
+// DropRawResponse, or followed by Body when RawResponse holds only the head of the
+// message, see BodyShared. A regenerated message is built on each call.
+func (r *Response) RawBytes() []byte {
+	if r.rawHead {
+		raw := make([]byte, 0, len(r.RawResponse)+len(r.Body))
+		return append(append(raw, r.RawResponse...), r.Body...)
+	}
+	if r.RawResponse != nil || !r.rawDropped {
+		return r.RawResponse
+	}
+	raw, err := httputil.DumpResponse(r.ToHTTPResponse(nil), true)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// restoreRaw makes RawResponse the whole message again when it holds only its
+// head, sharing Body with it.
+func (r *Response) restoreRaw() {
+	if !r.rawHead {
+		return
+	}
+	r.RawResponse = r.RawBytes()
+	r.rawHead = false
+	r.shareBody()
+}
+
+// withHeadOnly returns a shallow copy of the response whose RawResponse keeps only
+// the head of the message, body being the rest of it, the response itself when
+// RawResponse does not end with body.
+func (r *Response) withHeadOnly(body []byte) *Response {
+	start := bodyStart(r.RawResponse)
+	if r.rawHead || start < 0 || !bytes.Equal(r.RawResponse[start:], body) {
+		return r
+	}
+	output := *r
+	output.RawResponse = append([]byte(nil), r.RawResponse[:start]...)
+	output.Body = body
+	output.rawHead = true
+	return &output
+}
+
+// withoutRaw returns a shallow copy of the response with RawResponse dropped, the
+// response itself when it has none.
+func (r *Response) withoutRaw() *Response {
+	if r.RawResponse == nil {
+		return r
+	}
+	output := *r
+	output.DropRawResponse()
+	return &output
+}
+
+// plainResponse has the fields of Response without its JSON methods.
+type plainResponse Response
+
+// jsonView returns the response to encode, with a dropped RawResponse regenerated.
+func (r *Response) jsonView() *plainResponse {
+	if !r.rawHead && (!r.rawDropped || r.RawResponse != nil) {
+		return (*plainResponse)(r)
+	}
+	output := *r
+	output.RawResponse = r.RawBytes()
+	return (*plainResponse)(&output)
+}
+
+// MarshalJSON encodes the response, regenerating a dropped RawResponse so the JSON
+// is the same whether or not it was dropped.
+func (r *Response) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.jsonView())
+}
+
+// MarshalJSON encodes the fields of the response next to Problem and GraphQLErrors.
+// Without it the entry would be encoded by the MarshalJSON of its Response alone.
+func (e *ErrorReportEntry) MarshalJSON() ([]byte, error) {
+	var response *plainResponse
+	if e.Response != nil {
+		response = e.Response.jsonView()
+	}
+	return json.Marshal(struct {
+		*plainResponse
+		Problem       *ProblemDetails `json:"problem,omitempty"`
+		GraphQLErrors []GraphQLError  `json:"graphqlErrors,omitempty"`
+	}{response, e.Problem, e.GraphQLErrors})
+}
@@ -0,0 +1,456 @@
+package response
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Redaction
+// ----------------------------------------------------------------------
+
+// RedactStrategy defines how a sensitive value is replaced.
+type RedactStrategy int
+
+const (
+	// RedactMask replaces the value with the policy Mask.
+	RedactMask RedactStrategy = iota
+	// RedactHash replaces the value with "sha256:" and the first 16 hex digits of its
+	// SHA-256, so equal secrets can still be correlated.
+	RedactHash
+	// RedactDrop removes the header, JSON field or JSON array item. Pattern matches
+	// are replaced by an empty string.
+	RedactDrop
+)
+
+// String returns the name of the strategy.
+func (s RedactStrategy) String() string {
+	switch s {
+	case RedactMask:
+		return "mask"
+	case RedactHash:
+		return "hash"
+	case RedactDrop:
+		return "drop"
+	default:
+		return fmt.Sprintf("RedactStrategy(%d)", int(s))
+	}
+}
+
+// DefaultRedactMask is the replacement used by RedactMask when Mask is empty.
+const DefaultRedactMask = "[REDACTED]"
+
+// RedactionPolicy describes which parts of a Response are sensitive and how they are
+// replaced. It applies to response and recorded request headers and bodies, and to
+// RawResponse.
+//
+// Headers are matched case-insensitively. JSONPaths are dotted paths into JSON bodies
+// ("user.token", "items.*.password"), where "*" matches every array item or object
+// field and a leading "$." is optional. JSONKeys match a field name at any depth.
+// Patterns are applied to header values and text bodies.
+type RedactionPolicy struct {
+	Headers   []string
+	JSONPaths []string
+	JSONKeys  []string
+	Patterns  []*regexp.Regexp
+	Strategy  RedactStrategy
+	Mask      string
+}
+
+// NewRedactionPolicy returns an empty policy using the given strategy.
+func NewRedactionPolicy(strategy RedactStrategy) *RedactionPolicy {
+	return &RedactionPolicy{
+		Strategy: strategy,
+		Mask:     DefaultRedactMask,
+	}
+}
+
+// DefaultRedactionPolicy returns a masking policy covering the usual credential
+// headers and JSON fields.
+func DefaultRedactionPolicy() *RedactionPolicy {
+	policy := NewRedactionPolicy(RedactMask)
+	policy.Headers = []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"Cookie",
+		"Set-Cookie",
+		"X-Api-Key",
+		"X-Auth-Token",
+	}
+	policy.JSONKeys = []string{
+		"password",
+		"secret",
+		"token",
+		"access_token",
+		"refresh_token",
+		"api_key",
+		"apiKey",
+	}
+	return policy
+}
+
+// Redact returns a redacted copy of response. The given response is not modified.
+func (p *RedactionPolicy) Redact(response *Response) *Response {
+	if response == nil {
+		return nil
+	}
+	clone := response.Clone()
+	p.Apply(clone)
+	return clone
+}
+
+// Apply redacts response in place.
+func (p *RedactionPolicy) Apply(response *Response) {
+	if p == nil || response == nil {
+		return
+	}
+
+	response.restoreRaw()
+	originalBody := response.Body
+	response.Headers = p.redactHeaders(response.Headers)
+	response.Body = p.redactBody(response.Body)
+	if response.BodyLength == uint64(len(originalBody)) {
+		response.BodyLength = uint64(len(response.Body))
+	}
+	updateContentLength(response.Headers, len(originalBody), len(response.Body))
+	response.RawResponse = p.redactRaw(response.RawResponse, originalBody, response.Body)
+
+	if response.Request != nil {
+		originalBody = response.Request.Body
+		response.Request.Headers = p.redactHeaders(response.Request.Headers)
+		response.Request.Body = p.redactBody(response.Request.Body)
+		updateContentLength(response.Request.Headers, len(originalBody), len(response.Request.Body))
+	}
+}
+
+// updateContentLength sets the Content-Length header to after when it announced
+// the body before redaction, of before bytes.
+func updateContentLength(headers map[string]string, before int, after int) {
+	if before == after {
+		return
+	}
+	for name, value := range headers {
+		if strings.EqualFold(name, "Content-Length") && strings.TrimSpace(value) == strconv.Itoa(before) {
+			headers[name] = strconv.Itoa(after)
+		}
+	}
+}
+
+// replace returns the replacement of value according to the strategy.
+func (p *RedactionPolicy) replace(value string) string {
+	switch p.Strategy {
+	case RedactHash:
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:])[:16]
+	case RedactDrop:
+		return ""
+	default:
+		if p.Mask == "" {
+			return DefaultRedactMask
+		}
+		return p.Mask
+	}
+}
+
+// sensitiveHeader reports whether name is listed in the policy headers.
+func (p *RedactionPolicy) sensitiveHeader(name string) bool {
+	for _, header := range p.Headers {
+		if strings.EqualFold(header, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactPatterns replaces every pattern match in text.
+func (p *RedactionPolicy) redactPatterns(text string) string {
+	for _, pattern := range p.Patterns {
+		text = pattern.ReplaceAllStringFunc(text, p.replace)
+	}
+	return text
+}
+
+// redactHeaders returns a redacted copy of headers.
+func (p *RedactionPolicy) redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	output := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if p.sensitiveHeader(name) {
+			if p.Strategy == RedactDrop {
+				continue
+			}
+			output[name] = p.replace(value)
+			continue
+		}
+		output[name] = p.redactPatterns(value)
+	}
+	return output
+}
+
+// redactBody redacts JSON fields and patterns in a body. Binary bodies are left as is.
+// A body holding several JSON documents, such as NDJSON, is redacted line by line.
+func (p *RedactionPolicy) redactBody(body []byte) []byte {
+	if len(body) == 0 || !utf8.Valid(body) {
+		return body
+	}
+
+	if len(p.JSONPaths) > 0 || len(p.JSONKeys) > 0 {
+		if redacted, ok := p.redactJSONDocument(body); ok {
+			body = redacted
+		} else if bytes.Contains(body, []byte("\n")) {
+			lines := bytes.Split(body, []byte("\n"))
+			for index, line := range lines {
+				if redacted, ok := p.redactJSONDocument(line); ok {
+					lines[index] = redacted
+				}
+			}
+			body = bytes.Join(lines, []byte("\n"))
+		}
+	}
+
+	if len(p.Patterns) == 0 {
+		return body
+	}
+	return []byte(p.redactPatterns(string(body)))
+}
+
+// redactJSONDocument redacts the JSON fields of data when it holds exactly one JSON
+// document. ok is false when data is not a single document; data is returned as
+// is when no field matched, so its formatting is kept.
+func (p *RedactionPolicy) redactJSONDocument(data []byte) (output []byte, ok bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if decoder.Decode(&document) != nil {
+		return data, false
+	}
+	var trailing interface{}
+	if decoder.Decode(&trailing) != io.EOF {
+		return data, false
+	}
+
+	matched := false
+	for _, path := range p.JSONPaths {
+		var found bool
+		document, found = p.redactJSONPath(document, splitJSONPath(path))
+		matched = matched || found
+	}
+	if len(p.JSONKeys) > 0 {
+		matched = p.redactJSONKeys(document) || matched
+	}
+	if !matched {
+		return data, true
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if encoder.Encode(document) != nil {
+		return data, true
+	}
+	// Keep the whitespace that followed the document, the encoder adds a newline
+	encoded := bytes.TrimRight(buffer.Bytes(), "\n")
+	trimmed := bytes.TrimRight(data, " \t\r\n")
+	return append(encoded, data[len(trimmed):]...), true
+}
+
+// splitJSONPath splits a dotted JSON path into segments.
+func splitJSONPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// redactJSONPath redacts the value at segments inside node and returns the new node,
+// and whether a value matched. RedactDrop removes matched fields and array items.
+func (p *RedactionPolicy) redactJSONPath(node interface{}, segments []string) (interface{}, bool) {
+	if len(segments) == 0 {
+		return node, false
+	}
+	segment, rest := segments[0], segments[1:]
+
+	matched := false
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if segment != "*" && segment != key {
+				continue
+			}
+			if len(rest) > 0 {
+				var found bool
+				value[key], found = p.redactJSONPath(child, rest)
+				matched = matched || found
+				continue
+			}
+			matched = true
+			if p.Strategy == RedactDrop {
+				delete(value, key)
+				continue
+			}
+			value[key] = p.replace(jsonScalarString(child))
+		}
+	case []interface{}:
+		kept := value[:0]
+		for index, child := range value {
+			if segment != "*" && segment != strconv.Itoa(index) {
+				kept = append(kept, child)
+				continue
+			}
+			if len(rest) > 0 {
+				child, found := p.redactJSONPath(child, rest)
+				matched = matched || found
+				kept = append(kept, child)
+				continue
+			}
+			matched = true
+			if p.Strategy == RedactDrop {
+				continue
+			}
+			kept = append(kept, p.replace(jsonScalarString(child)))
+		}
+		return kept, matched
+	}
+	return node, matched
+}
+
+// redactJSONKeys redacts every field named in JSONKeys, at any depth, and reports
+// whether a field matched.
+func (p *RedactionPolicy) redactJSONKeys(node interface{}) bool {
+	matched := false
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if p.sensitiveJSONKey(key) {
+				matched = true
+				if p.Strategy == RedactDrop {
+					delete(value, key)
+				} else {
+					value[key] = p.replace(jsonScalarString(child))
+				}
+				continue
+			}
+			matched = p.redactJSONKeys(child) || matched
+		}
+	case []interface{}:
+		for _, child := range value {
+			matched = p.redactJSONKeys(child) || matched
+		}
+	}
+	return matched
+}
+
+// sensitiveJSONKey reports whether key is listed in JSONKeys.
+func (p *RedactionPolicy) sensitiveJSONKey(key string) bool {
+	for _, name := range p.JSONKeys {
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonScalarString returns the text used to hash a JSON value.
+func jsonScalarString(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case json.Number:
+		return typed.String()
+	default:
+		encoded, _ := json.Marshal(typed)
+		return string(encoded)
+	}
+}
+
+// redactRaw redacts a raw HTTP message. Header lines are redacted like headers, and
+// the body is swapped for the redacted body when it was stored verbatim. When the raw
+// body is transfer-encoded and redaction changed the body, the message is rebuilt with
+// the redacted body and a Content-Length instead of Transfer-Encoding; otherwise only
+// patterns are applied to the raw body. Content-Length is updated to the new body.
+func (p *RedactionPolicy) redactRaw(raw []byte, originalBody []byte, redactedBody []byte) []byte {
+	if len(raw) == 0 {
+		return raw
+	}
+
+	separator := []byte("\r\n\r\n")
+	index := bytes.Index(raw, separator)
+	if index < 0 {
+		separator = []byte("\n\n")
+		index = bytes.Index(raw, separator)
+	}
+	if index < 0 {
+		return []byte(p.redactPatterns(string(raw)))
+	}
+
+	head := string(raw[:index])
+	rawBody := raw[index+len(separator):]
+
+	newBody := rawBody
+	rebuilt := false
+	switch {
+	case bytes.Equal(rawBody, originalBody):
+		newBody = redactedBody
+	case !bytes.Equal(originalBody, redactedBody):
+		// The raw body is transfer-encoded (chunked, for instance), so the redacted
+		// body can't be found in it: the message is rebuilt around the redacted body
+		newBody = redactedBody
+		rebuilt = true
+	case utf8.Valid(rawBody):
+		newBody = []byte(p.redactPatterns(string(rawBody)))
+	}
+
+	lineBreak := "\n"
+	if strings.Contains(head, "\r\n") {
+		lineBreak = "\r\n"
+	}
+
+	lines := strings.Split(head, lineBreak)
+	output := make([]string, 0, len(lines)+1)
+	hasLength := false
+	for number, line := range lines {
+		name, value, found := strings.Cut(line, ":")
+		if number == 0 || !found {
+			output = append(output, line)
+			continue
+		}
+		name = strings.TrimSpace(name)
+		switch {
+		case p.sensitiveHeader(name):
+			if p.Strategy == RedactDrop {
+				continue
+			}
+			output = append(output, name+": "+p.replace(strings.TrimSpace(value)))
+		case strings.EqualFold(name, "Transfer-Encoding") && rebuilt:
+			continue
+		case strings.EqualFold(name, "Content-Length"):
+			hasLength = true
+			if len(newBody) != len(rawBody) || rebuilt {
+				output = append(output, name+": "+strconv.Itoa(len(newBody)))
+			} else {
+				output = append(output, p.redactPatterns(line))
+			}
+		default:
+			output = append(output, p.redactPatterns(line))
+		}
+	}
+	if rebuilt && !hasLength {
+		output = append(output, "Content-Length: "+strconv.Itoa(len(newBody)))
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(strings.Join(output, lineBreak))
+	buffer.Write(separator)
+	buffer.Write(newBody)
+	return buffer.Bytes()
+}
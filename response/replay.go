@@ -0,0 +1,400 @@
+package response
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	urlPack "net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// Replay Transport
+// ----------------------------------------------------------------------
+
+// ReplayMode defines how a ReplayTransport answers requests.
+type ReplayMode int
+
+const (
+	// ReplayOnly answers every request from the pack and never reaches the network.
+	ReplayOnly ReplayMode = iota
+	// RecordMissing answers from the pack and forwards unmatched requests upstream,
+	// recording the new exchange into the pack.
+	RecordMissing
+	// RecordAlways forwards every request upstream and records every exchange.
+	RecordAlways
+)
+
+// String returns the name of the replay mode.
+func (m ReplayMode) String() string {
+	switch m {
+	case ReplayOnly:
+		return "replay only"
+	case RecordMissing:
+		return "record missing"
+	case RecordAlways:
+		return "always record"
+	default:
+		return fmt.Sprintf("ReplayMode(%d)", int(m))
+	}
+}
+
+// RequestMatcher reports whether an incoming request matches a recorded round.
+// body is the already-read request body and recorded is the round being considered.
+// recorded.Request is nil when the round was stored without request information.
+type RequestMatcher func(req *http.Request, body []byte, recorded *Response) bool
+
+// MatchQuery matches when the query parameters of the request and of the recorded
+// URL are equal, ignoring parameter order.
+func MatchQuery() RequestMatcher {
+	return func(req *http.Request, body []byte, recorded *Response) bool {
+		recordedURL, err := urlPack.Parse(recorded.Url)
+		if err != nil {
+			return false
+		}
+		return reflect.DeepEqual(normalizeQuery(req.URL.Query()), normalizeQuery(recordedURL.Query()))
+	}
+}
+
+// MatchHeaders matches when every named header has the same value in the request
+// and in the recorded request. Rounds without a recorded request always match.
+func MatchHeaders(names ...string) RequestMatcher {
+	return func(req *http.Request, body []byte, recorded *Response) bool {
+		if recorded.Request == nil {
+			return true
+		}
+		for _, name := range names {
+			canonical := http.CanonicalHeaderKey(name)
+			if strings.Join(req.Header.Values(canonical), ", ") != recorded.Request.Headers[canonical] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchBody matches when the request body equals the recorded request body.
+// Rounds without a recorded request always match.
+func MatchBody() RequestMatcher {
+	return func(req *http.Request, body []byte, recorded *Response) bool {
+		if recorded.Request == nil {
+			return true
+		}
+		return bytes.Equal(body, recorded.Request.Body)
+	}
+}
+
+// DefaultMatchers are used by a ReplayTransport created without matchers.
+// Method and URL (scheme, host and path) are always compared.
+func DefaultMatchers() []RequestMatcher {
+	return []RequestMatcher{MatchQuery()}
+}
+
+// normalizeQuery returns a copy of values with empty maps turned into nil, so that
+// "no query" compares equal regardless of how it was parsed.
+func normalizeQuery(values urlPack.Values) urlPack.Values {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// ReplayMismatchError is returned by ReplayTransport when no recorded round matches
+// a request. Candidates holds the closest recorded "METHOD URL" entries.
+type ReplayMismatchError struct {
+	Method     string
+	Url        string
+	Candidates []string
+}
+
+// Error returns the mismatch description, listing the closest candidates.
+func (e *ReplayMismatchError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("replay: no recorded response for %s %s", e.Method, e.Url))
+	if len(e.Candidates) == 0 {
+		sb.WriteString(" (pack is empty)")
+		return sb.String()
+	}
+	sb.WriteString("; closest candidates:")
+	for _, candidate := range e.Candidates {
+		sb.WriteString("\n\t")
+		sb.WriteString(candidate)
+	}
+	return sb.String()
+}
+
+// ReplayTransport is an http.RoundTripper that answers requests from a Pack.
+// Rounds recorded for the same request are returned in order on repeated calls;
+// once they are exhausted the last round keeps being returned, until a new round
+// is recorded.
+type ReplayTransport struct {
+	Pack          Pack
+	Mode          ReplayMode
+	Matchers      []RequestMatcher
+	Upstream      http.RoundTripper
+	MaxCandidates int
+	mu            sync.Mutex
+	cursors       map[string]int
+}
+
+// NewReplayTransport creates a ReplayTransport over pack. When no matchers are
+// given, DefaultMatchers are used. Recorded traffic goes through http.DefaultTransport
+// unless Upstream is changed.
+func NewReplayTransport(pack Pack, mode ReplayMode, matchers ...RequestMatcher) *ReplayTransport {
+	if len(matchers) == 0 {
+		matchers = DefaultMatchers()
+	}
+	return &ReplayTransport{
+		Pack:          pack,
+		Mode:          mode,
+		Matchers:      matchers,
+		Upstream:      http.DefaultTransport,
+		MaxCandidates: 3,
+		cursors:       map[string]int{},
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Pack == nil {
+		return nil, fmt.Errorf("replay: pack is nil")
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Mode == RecordAlways {
+		return t.record(req, body)
+	}
+
+	response, err := t.lookup(req, body)
+	if err == nil {
+		return response.ToHTTPResponse(req), nil
+	}
+
+	if t.Mode == RecordMissing {
+		return t.record(req, body)
+	}
+	return nil, err
+}
+
+// lookup finds the next round matching req. Only the rounds of the keys req can
+// match are read, so a CompressResponsePack decompresses those alone.
+func (t *ReplayTransport) lookup(req *http.Request, body []byte) (*Response, error) {
+	keys := t.Pack.GetKeysOfResponses()
+	sort.Strings(keys)
+
+	// Keys are URLs by default; packs built with another KeyFunc are looked up
+	// with the key of the request, then every round is matched on its own URL
+	requestKey := t.Pack.Key(&Response{Url: req.URL.String(), Host: req.URL.Host, Method: codes.Method(req.Method)})
+
+	for _, key := range keys {
+		if key != requestKey && !sameEndpoint(req.URL, key) {
+			continue
+		}
+		rounds, err := t.Pack.History(key)
+		if err != nil {
+			continue
+		}
+
+		var matched []*Response
+		for _, round := range rounds {
+			if sameEndpoint(req.URL, round.Response.Url) && t.matches(req, body, round.Response) {
+				matched = append(matched, round.Response)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		// The cursor counts the rounds served for the request, so rounds recorded
+		// meanwhile are served next instead of starting over
+		signature := requestSignature(key, req, body)
+		t.mu.Lock()
+		served := t.cursors[signature]
+		t.cursors[signature] = served + 1
+		t.mu.Unlock()
+
+		return matched[min(served, len(matched)-1)], nil
+	}
+
+	return nil, &ReplayMismatchError{
+		Method:     req.Method,
+		Url:        req.URL.String(),
+		Candidates: t.closestCandidates(req, keys),
+	}
+}
+
+// matches runs method comparison and every matcher against round.
+func (t *ReplayTransport) matches(req *http.Request, body []byte, round *Response) bool {
+	if !strings.EqualFold(string(round.Method), req.Method) {
+		return false
+	}
+	for _, matcher := range t.Matchers {
+		if !matcher(req, body, round) {
+			return false
+		}
+	}
+	return true
+}
+
+// record forwards req upstream and stores the exchange in the pack.
+func (t *ReplayTransport) record(req *http.Request, body []byte) (*http.Response, error) {
+	upstream := t.Upstream
+	if upstream == nil {
+		upstream = http.DefaultTransport
+	}
+
+	outgoing := req.Clone(req.Context())
+	outgoing.Body = io.NopCloser(bytes.NewReader(body))
+	outgoing.ContentLength = int64(len(body))
+
+	start := time.Now()
+	httpResponse, err := upstream.RoundTrip(outgoing)
+	if err != nil {
+		return nil, fmt.Errorf("replay: upstream request failed: %w", err)
+	}
+
+	response, err := NewResponseFromHTTPResponse(httpResponse)
+	if err != nil {
+		return nil, fmt.Errorf("replay: failed to record response: %w", err)
+	}
+	response.Url = req.URL.String()
+	response.Host = req.URL.Host
+	response.Method = codes.Method(req.Method)
+	response.Request = newRecordedRequest(req, body)
+	response.Timing = &Timing{Start: start, Duration: time.Since(start)}
+
+	err = t.Pack.AddResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("replay: failed to store response: %w", err)
+	}
+
+	return httpResponse, nil
+}
+
+// closestCandidates returns the recorded "METHOD URL" entries closest to req. The
+// keys are ranked first, and only the rounds of the closest keys are read.
+func (t *ReplayTransport) closestCandidates(req *http.Request, keys []string) []string {
+	target := req.Method + " " + req.URL.String()
+
+	limit := t.MaxCandidates
+	if limit <= 0 {
+		limit = 3
+	}
+
+	ranked := append([]string(nil), keys...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return keyDistance(req, target, ranked[i]) < keyDistance(req, target, ranked[j])
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	seen := map[string]bool{}
+	var candidates []string
+	for _, key := range ranked {
+		rounds, err := t.Pack.History(key)
+		if err != nil {
+			continue
+		}
+		for _, round := range rounds {
+			candidate := string(round.Response.Method) + " " + round.Response.Url
+			if !seen[candidate] {
+				seen[candidate] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return levenshtein(target, candidates[i]) < levenshtein(target, candidates[j])
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// keyDistance returns the edit distance between req and a pack key, a URL or a
+// "METHOD URL" key being compared with the URL or the method and URL of req.
+func keyDistance(req *http.Request, target string, key string) int {
+	if _, url, found := strings.Cut(key, " "); found && strings.Contains(url, "://") {
+		return levenshtein(target, key)
+	}
+	return levenshtein(req.URL.String(), key)
+}
+
+// readRequestBody reads and closes the request body, returning its bytes.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return body, nil
+}
+
+// newRecordedRequest copies the relevant parts of req into a RecordedRequest.
+func newRecordedRequest(req *http.Request, body []byte) *RecordedRequest {
+	headers := make(map[string]string, len(req.Header))
+	for name, values := range req.Header {
+		headers[name] = strings.Join(values, ", ")
+	}
+	return &RecordedRequest{
+		Method:  codes.Method(req.Method),
+		Url:     req.URL.String(),
+		Headers: headers,
+		Body:    body,
+	}
+}
+
+// sameEndpoint compares scheme, host and path of u with the stored URL.
+func sameEndpoint(u *urlPack.URL, key string) bool {
+	stored, err := urlPack.Parse(key)
+	if err != nil {
+		return u.String() == key
+	}
+	return strings.EqualFold(u.Scheme, stored.Scheme) &&
+		strings.EqualFold(u.Host, stored.Host) &&
+		u.Path == stored.Path
+}
+
+// requestSignature identifies the request a replay cursor belongs to: the pack
+// key, method, URL and body of the request.
+func requestSignature(key string, req *http.Request, body []byte) string {
+	return fmt.Sprintf("%s|%s %s|%x", key, req.Method, req.URL.String(), hashBody(body))
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
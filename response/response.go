@@ -49,10 +49,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httputil"
 	urlPack "net/url"
+	"os"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/JuniorVieira99/jr_httpcodes/codes"
@@ -71,6 +76,27 @@ type Response struct {
 	Body        []byte            `json:"body"`
 	BodyLength  uint64            `json:"bodyLength"`
 	RawResponse []byte            `json:"rawResponse"`
+	Request     *RecordedRequest  `json:"request,omitempty"`
+	Timing      *Timing           `json:"timing,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	rawDropped  bool
+	rawHead     bool
+}
+
+// Timing holds when the request of a Response was sent and how long the exchange took.
+type Timing struct {
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+}
+
+// RecordedRequest holds the request that produced a Response, when it is known.
+// It is filled by the recording components (e.g. ReplayTransport) and is used to
+// match incoming requests against stored rounds.
+type RecordedRequest struct {
+	Method  codes.Method      `json:"method"`
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
 }
 
 type ConfigResponse struct {
@@ -134,9 +160,53 @@ func (r *Response) DetailedStatusCode() string {
 	return r.StatusCode.String()
 }
 
-// ReadRawResponse returns the raw response data as a string.
+// ReadRawResponse returns the raw response data as a string, regenerated when it
+// was dropped, see DropRawResponse.
 func (r *Response) ReadRawResponse() string {
-	return string(r.RawResponse)
+	return string(r.RawBytes())
+}
+
+// Clone returns a deep copy of the Response, request, timing and tags included.
+func (r *Response) Clone() *Response {
+	if r == nil {
+		return nil
+	}
+
+	clone := *r
+	clone.Headers = make(map[string]string, len(r.Headers))
+	for key, value := range r.Headers {
+		clone.Headers[key] = value
+	}
+	clone.Body = append([]byte(nil), r.Body...)
+	if r.RawResponse != nil {
+		clone.RawResponse = append([]byte(nil), r.RawBytes()...)
+		clone.rawHead = false
+		if r.BodyShared() {
+			clone.shareBody()
+		}
+	}
+
+	if r.Request != nil {
+		request := *r.Request
+		request.Headers = make(map[string]string, len(r.Request.Headers))
+		for key, value := range r.Request.Headers {
+			request.Headers[key] = value
+		}
+		if r.Request.Body != nil {
+			request.Body = append([]byte(nil), r.Request.Body...)
+		}
+		clone.Request = &request
+	}
+
+	if r.Timing != nil {
+		timing := *r.Timing
+		clone.Timing = &timing
+	}
+	if r.Tags != nil {
+		clone.Tags = append([]string(nil), r.Tags...)
+	}
+
+	return &clone
 }
 
 // Print prints a string representation of the Response struct to the console.
@@ -183,12 +253,13 @@ func (r *Response) ToReadableJSON() ([]byte, error) {
 	}
 
 	// Try to convert rawResponse to a readable string first
+	raw := r.RawBytes()
 	var rawResponseContent string
-	if utf8.Valid(r.RawResponse) {
-		rawResponseContent = string(r.RawResponse)
+	if utf8.Valid(raw) {
+		rawResponseContent = string(raw)
 	} else {
 		// Fall back to base64
-		rawResponseContent = base64.StdEncoding.EncodeToString(r.RawResponse)
+		rawResponseContent = base64.StdEncoding.EncodeToString(raw)
 	}
 
 	// Create a temporary struct to handle encoded binary data
@@ -220,7 +291,7 @@ func (r *Response) ToReadableJSON() ([]byte, error) {
 	if !utf8.Valid(r.Body) {
 		tempData.Encoding.Body = "base64"
 	}
-	if !utf8.Valid(r.RawResponse) {
+	if !utf8.Valid(raw) {
 		tempData.Encoding.RawResponse = "base64"
 	}
 
@@ -258,6 +329,7 @@ func NewResponseFromJSON(data []byte) (*Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal JSON into Response: %w", err)
 	}
+	outputResponse.shareBody()
 
 	return &outputResponse, nil
 
@@ -310,6 +382,93 @@ func NewResponse(
 	}, nil
 }
 
+// NewResponseFromHTTPResponse reads the given *http.Response and converts it into a
+// Response. The body of httpResponse is fully consumed and replaced by a new reader
+// over the same bytes, so the caller can still read it afterwards. The raw response
+// is rebuilt with httputil.DumpResponse.
+func NewResponseFromHTTPResponse(httpResponse *http.Response) (*Response, error) {
+	if httpResponse == nil {
+		return nil, fmt.Errorf("http response is nil")
+	}
+
+	var body []byte
+	if httpResponse.Body != nil {
+		data, err := io.ReadAll(httpResponse.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		httpResponse.Body.Close()
+		body = data
+	}
+	httpResponse.Body = io.NopCloser(bytes.NewReader(body))
+
+	raw, err := httputil.DumpResponse(httpResponse, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump response: %w", err)
+	}
+	httpResponse.Body = io.NopCloser(bytes.NewReader(body))
+
+	headers := make(map[string]string)
+	for name, values := range httpResponse.Header {
+		headers[name] = strings.Join(values, ", ")
+	}
+
+	var url, host string
+	method := codes.GET
+	if httpResponse.Request != nil {
+		if httpResponse.Request.URL != nil {
+			url = httpResponse.Request.URL.String()
+			host = httpResponse.Request.URL.Host
+		}
+		if httpResponse.Request.Host != "" {
+			host = httpResponse.Request.Host
+		}
+		if httpResponse.Request.Method != "" {
+			method = codes.Method(httpResponse.Request.Method)
+		}
+	}
+
+	response, err := NewResponse(
+		url,
+		host,
+		method,
+		codes.StatusCode(httpResponse.StatusCode),
+		headers,
+		body,
+		uint64(len(body)),
+		raw,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create response: %w", err)
+	}
+
+	return response, nil
+}
+
+// ToHTTPResponse converts the Response back into an *http.Response that answers req.
+// The returned body reads from a copy of Body, and ContentLength matches its size.
+func (r *Response) ToHTTPResponse(req *http.Request) *http.Response {
+	header := make(http.Header, len(r.Headers))
+	for key, value := range r.Headers {
+		header.Set(key, value)
+	}
+
+	body := make([]byte, len(r.Body))
+	copy(body, r.Body)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(int(r.StatusCode))),
+		StatusCode:    int(r.StatusCode),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
 // NewResponseFromConfig creates a new Response instance using the provided ResponseConfig.
 // It returns a pointer to the Response struct and a possible error if the Response cannot
 // be created. This function leverages the NewResponse function to perform validation and
@@ -323,19 +482,42 @@ func NewResponseFromConfig(config ConfigResponse) (*Response, error) {
 
 // ResponsePack A struct with many Responses objects
 type ResponsePack struct {
-	Responses    map[string]map[string]*Response `json:"responses"` // map[URL][round]Response
+	Responses    map[string]map[string]*Response `json:"responses"`           // map[URL][round]Response
+	LastRound    map[string]int                  `json:"lastRound,omitempty"` // map[URL]highest round number handed out
 	Total        uint64                          `json:"total"`
 	Success      uint64                          `json:"success"`
 	Failure      uint64                          `json:"failure"`
+	Ignored      uint64                          `json:"ignored"`
 	SuccessRatio float64                         `json:"successRatio"`
 	FailureRatio float64                         `json:"failureRatio"`
+	Evicted      uint64                          `json:"evicted,omitempty"` // rounds evicted over the lifetime of the pack, see PackLimits
 	Info         map[string]string               `json:"info"`
-	mu           sync.RWMutex
+	Stats        PackStats                       `json:"stats"`
+	Redaction    *RedactionPolicy                `json:"-"`
+	// DropRawResponse stores the rounds without their RawResponse, which is
+	// regenerated on demand, see Response.DropRawResponse.
+	DropRawResponse bool `json:"-"`
+	// graphQLErrorsAsFailure counts successful responses whose body is a GraphQL
+	// response with errors as failures. It only changes through
+	// SetGraphQLErrorsAsFailure, which recounts the stored rounds.
+	graphQLErrorsAsFailure bool
+	bodySizes              samples[int64]
+	latencies              samples[time.Duration]
+	distributionsStale     bool
+	order                  map[string][]int
+	key                    KeyFunc
+	classifier             Classifier
+	indexes                *packIndexes
+	limiter                *packLimiter
+	memory                 map[string]MemoryUsage
+	blobs                  *blobStore
+	mu                     sync.RWMutex
 }
 
-// GetResponse takes a URL and retrieves a slice of Response objects from the ResponsePack.
-// If a response does not exist for a URL, it returns nil.
-func (r *ResponsePack) GetResponse(url string) ([]*Response, error) {
+// GetResponse takes a key, the URL unless the pack was built with another KeyFunc, and
+// retrieves a slice of Response objects from the ResponsePack in round order.
+// If a response does not exist for the key, it returns an error.
+func (r *ResponsePack) GetResponse(key string) ([]*Response, error) {
 
 	// Output slice
 	var resultSlice []*Response
@@ -344,80 +526,51 @@ func (r *ResponsePack) GetResponse(url string) ([]*Response, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	result, ok := r.Responses[url]
+	result, ok := r.Responses[key]
 
 	// If not found
 	if !ok {
-		return nil, fmt.Errorf("response not found for URL: %s", url)
+		return nil, fmt.Errorf("response not found for key: %s", key)
 	}
+	r.limiter.touch(key)
 
-	// Convert map to slice
-	for _, value := range result {
-		resultSlice = append(resultSlice, value)
+	// Convert map to slice, in round order
+	for _, seq := range orderedSeqs(result, r.order[key]) {
+		resultSlice = append(resultSlice, result[roundKey(seq)])
 	}
 
 	return resultSlice, nil
 }
 
-// BatchGetResponse retrieves the Response objects associated with a given slice of URLs
-// from the ResponsePack concurrently. It handles both direct URL lookups and URLs with
-// round suffixes, returning a map where the outer key is the URL, and the inner map has
-// keys representing the round number (e.g., "round_1") and values as the Response objects.
-// The function also returns a slice of errors if any GetResponse operation fails.
-func (r *ResponsePack) BatchGetResponse(urls []string) (map[string]map[string]*Response, []error) {
-	// Output map
-	output := map[string]map[string]*Response{}
-	// Output errors
-	var errors []error
-
-	// Channels
-	errCh := make(chan error, len(urls))
-	resultCh := make(chan []*Response, len(urls))
-
-	// WaitGroup
-	var wg sync.WaitGroup
-
-	// Process each URL just once
-	for _, url := range urls {
-		wg.Add(1)
-		go func(url string) {
-			defer wg.Done()
-			// Get response
-			mapRes, err := r.GetResponse(url)
-			if err != nil {
-				errCh <- err
-			}
-			if mapRes != nil {
-				resultCh <- mapRes
-			}
-		}(url)
-	}
-
-	wg.Wait()
-	close(errCh)
-	close(resultCh)
-
-	for err := range errCh {
-		errors = append(errors, err)
-	}
-
-	if len(errors) == 0 {
-		errors = nil
+// roundNumber extracts N from a "round_N" key. Keys that do not follow the
+// pattern return 0.
+func roundNumber(key string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(key, "round_"))
+	if err != nil {
+		return 0
 	}
+	return n
+}
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	for result := range resultCh {
-		urlKey := result[0].Url
-		output[urlKey] = map[string]*Response{}
-
-		for index, response := range result {
-			newKey := fmt.Sprintf("round_%d", index)
-			output[urlKey][newKey] = response
-		}
+// sortedRoundKeys returns the keys of a rounds map ordered by round number.
+func sortedRoundKeys[T any](rounds map[string]T) []string {
+	keys := make([]string, 0, len(rounds))
+	for key := range rounds {
+		keys = append(keys, key)
 	}
+	sort.Slice(keys, func(i, j int) bool {
+		return roundNumber(keys[i]) < roundNumber(keys[j])
+	})
+	return keys
+}
 
-	return output, errors
+// BatchGetResponse retrieves the Response objects associated with a given slice of keys
+// from the ResponsePack concurrently, returning a map where the outer key is the pack key,
+// and the inner map has keys carrying the sequence number of the round (e.g., "round_1")
+// and values as the Response objects. The function also returns a slice of errors, nil
+// when every key was found; the keys found are returned either way.
+func (r *ResponsePack) BatchGetResponse(urls []string) (map[string]map[string]*Response, []error) {
+	return batchHistory(urls, r.History)
 }
 
 // GetKeysOfResponses returns a slice containing all the keys present in the Responses map of the ResponsePack.
@@ -434,53 +587,56 @@ func (p *ResponsePack) GetKeysOfResponses() []string {
 	return keys
 }
 
-// AddResponse adds a Response object to the ResponsePack struct.
+// AddResponse adds a Response object to the ResponsePack struct as the next round of
+// its key, see KeyFunc and Round.
 func (p *ResponsePack) AddResponse(response *Response) error {
 	if response == nil {
 		return fmt.Errorf("response is nil")
 	}
 
 	p.mu.Lock()
-	defer p.mu.Unlock()
 
-	ok := codes.IsSuccess(response.StatusCode)
-
-	if ok {
-		p.Success++
-	} else {
-		p.Failure++
+	// Redact before storing, the caller's response is left untouched
+	if p.Redaction != nil {
+		response = p.Redaction.Redact(response)
+	}
+	if p.DropRawResponse {
+		response = response.withoutRaw()
 	}
+	if err := p.limiter.fits(responseBytes(response)); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	response = p.dedupBody(response)
+
+	key := p.keyOf(response)
+	p.count(response)
+	p.track(key, response)
 	p.Total++
 
 	// Recalculate ratios directly after updating metrics
-	if p.Total > 0 {
-		if p.Success != 0 {
-			p.SuccessRatio = float64(p.Success) / float64(p.Total)
-		}
-		if p.Failure != 0 {
-			p.FailureRatio = float64(p.Failure) / float64(p.Total)
-		}
-	}
-
-	var round int = 0
+	p.updateRatios()
 
-	// Check if response already exists
-	_, ok = p.Responses[response.Url]
-	// If url does not exists, create inner map
-	if !ok {
-		p.Responses[response.Url] = make(map[string]*Response)
-		p.Responses[response.Url]["round_1"] = response
-		return nil
+	if p.LastRound == nil {
+		p.LastRound = map[string]int{}
+	}
+	if p.order == nil {
+		p.order = map[string][]int{}
 	}
+	// Store under the next sequence number of the URL
+	seq := appendRound(p.Responses, p.LastRound, p.order, key, response)
+	p.index(key, seq, response)
 
-	// Get round
-	for range p.Responses[response.Url] {
-		round++
+	// Evict what no longer fits, the callback runs unlocked
+	var evictions []Eviction
+	if p.limiter != nil {
+		p.limiter.added(roundRef{key, seq}, responseBytes(response))
+		evictions = p.enforceLimits([]string{key})
 	}
-	// Make new key
-	newKey := fmt.Sprintf("round_%d", round+1)
-	// Add response with new key
-	p.Responses[response.Url][newKey] = response
+	onEvict := p.onEvict()
+	p.mu.Unlock()
+
+	notifyEvictions(onEvict, evictions)
 	return nil
 }
 
@@ -498,15 +654,80 @@ func (p *ResponsePack) BatchAddResponse(responses []*Response) []error {
 
 	wg := sync.WaitGroup{}
 
+	responseCh := make(chan *Response, len(responses))
 	for i := 0; i < maxWorkers; i++ {
 		wg.Add(1)
-		go func(response *Response) {
+		go func() {
+			defer wg.Done()
+			for response := range responseCh {
+				err := p.AddResponse(response)
+				if err != nil {
+					errCh <- err
+				}
+			}
+		}()
+	}
+
+	// Send work to workers
+	for _, response := range responses {
+		responseCh <- response
+	}
+
+	close(responseCh)
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		errSlice = append(errSlice, err)
+	}
+
+	if len(errSlice) > 0 {
+		return errSlice
+	}
+
+	return nil
+}
+
+// DeleteResponse removes every round of key from the ResponsePack and updates the
+// counters, ratios and statistics. The sequence numbers of key are not reused by
+// the rounds added afterwards. It returns an error if no response exists for key.
+func (p *ResponsePack) DeleteResponse(key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rounds, ok := p.Responses[key]
+	if !ok {
+		return fmt.Errorf("response not found for key: %s", key)
+	}
+	for round, response := range rounds {
+		p.forget(key, response)
+		p.unindex(key, roundNumber(round), response)
+		if p.limiter != nil {
+			p.limiter.removed(roundRef{key, roundNumber(round)})
+		}
+	}
+	delete(p.Responses, key)
+	delete(p.order, key)
+	p.updateRatios()
+	return nil
+}
+
+// BatchDeleteResponse removes the rounds of every key of keys concurrently. It
+// returns a slice of errors for the keys that were not found.
+func (p *ResponsePack) BatchDeleteResponse(keys []string) []error {
+	errCh := make(chan error, len(keys))
+	errSlice := make([]error, 0)
+	wg := sync.WaitGroup{}
+
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key string) {
 			defer wg.Done()
-			err := p.AddResponse(response)
+			err := p.DeleteResponse(key)
 			if err != nil {
 				errCh <- err
 			}
-		}(responses[i])
+		}(key)
 	}
 
 	wg.Wait()
@@ -523,21 +744,156 @@ func (p *ResponsePack) BatchAddResponse(responses []*Response) []error {
 	return nil
 }
 
-// Calculate recalculates the success and failure ratios of the ResponsePack.
+// Clear removes every round from the ResponsePack and resets the counters, ratios
+// and statistics. Info and the settings of the pack are kept.
+func (p *ResponsePack) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Responses = map[string]map[string]*Response{}
+	p.LastRound = map[string]int{}
+	p.order = map[string][]int{}
+	if p.limiter != nil {
+		p.limiter.reset()
+	}
+	if p.blobs != nil {
+		p.blobs.reset()
+	}
+	p.recount()
+}
+
+// Calculate recounts Total, Success, Failure and Ignored from the stored rounds
+// under the current Classifier, recalculates the success and failure ratios and
+// rebuilds Stats.
 func (p *ResponsePack) Calculate() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.Total == 0 {
-		return
+	p.recount()
+}
+
+// Classifier returns the Classifier deciding what counts as Success, Failure or
+// Ignored, nil for SuccessClassifier.
+func (p *ResponsePack) Classifier() Classifier {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.classifier
+}
+
+// SetClassifier replaces the Classifier of the pack and recalculates the statistics
+// of the stored rounds under it. A nil classifier restores SuccessClassifier. The
+// classifier is only changed through SetClassifier, so the counters and the
+// failures index always match it.
+func (p *ResponsePack) SetClassifier(classifier Classifier) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.classifier = classifier
+	p.recount()
+}
+
+// classify returns the Outcome of response under the pack's Classifier, a success
+// with GraphQL errors becoming a failure when SetGraphQLErrorsAsFailure enabled it.
+func (p *ResponsePack) classify(response *Response) Outcome {
+	classifier := p.classifier
+	if classifier == nil {
+		classifier = SuccessClassifier()
 	}
-	if p.Success != 0 {
-		p.SuccessRatio = float64(p.Success) / float64(p.Total)
+	outcome := classifier.Classify(response)
+	if outcome == OutcomeSuccess && p.graphQLErrorsAsFailure && response.HasGraphQLErrors() {
+		return OutcomeFailure
 	}
-	if p.Failure != 0 {
-		p.FailureRatio = float64(p.Failure) / float64(p.Total)
+	return outcome
+}
+
+// count adds response to the Success, Failure or Ignored counter.
+func (p *ResponsePack) count(response *Response) {
+	switch p.classify(response) {
+	case OutcomeSuccess:
+		p.Success++
+	case OutcomeIgnored:
+		p.Ignored++
+	default:
+		p.Failure++
 	}
 }
 
+// uncount removes response from the Success, Failure or Ignored counter.
+func (p *ResponsePack) uncount(response *Response) {
+	counter := &p.Failure
+	switch p.classify(response) {
+	case OutcomeSuccess:
+		counter = &p.Success
+	case OutcomeIgnored:
+		counter = &p.Ignored
+	}
+	if *counter > 0 {
+		*counter--
+	}
+}
+
+// forget removes a stored round of key from the counters and statistics. The
+// caller holds the write lock and updates the ratios.
+func (p *ResponsePack) forget(key string, response *Response) {
+	p.uncount(response)
+	p.untrack(key, response)
+	p.releaseBody(response)
+	if p.Total > 0 {
+		p.Total--
+	}
+}
+
+// recount resets the counters, statistics and indexes and counts every stored round
+// again.
+func (p *ResponsePack) recount() {
+	p.Total, p.Success, p.Failure, p.Ignored = 0, 0, 0, 0
+	p.resetStats()
+	for key, rounds := range p.Responses {
+		for _, response := range rounds {
+			p.count(response)
+			p.track(key, response)
+			p.Total++
+		}
+	}
+	p.updateRatios()
+	p.reindex()
+}
+
+// updateRatios recalculates the ratios over the classified, i.e. not ignored, rounds.
+func (p *ResponsePack) updateRatios() {
+	p.SuccessRatio, p.FailureRatio = 0, 0
+	classified := p.Success + p.Failure
+	if classified == 0 {
+		return
+	}
+	p.SuccessRatio = float64(p.Success) / float64(classified)
+	p.FailureRatio = float64(p.Failure) / float64(classified)
+}
+
+// GraphQLErrorsAsFailure reports whether a successful status carrying a GraphQL
+// errors array counts as a failure.
+func (p *ResponsePack) GraphQLErrorsAsFailure() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.graphQLErrorsAsFailure
+}
+
+// SetGraphQLErrorsAsFailure sets whether a successful status carrying a GraphQL
+// errors array counts as a failure and recalculates the statistics of the stored
+// rounds accordingly. The option only changes through SetGraphQLErrorsAsFailure, so
+// the counters and the failures index always match it.
+func (p *ResponsePack) SetGraphQLErrorsAsFailure(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.graphQLErrorsAsFailure = enabled
+	p.recount()
+}
+
+// SetRedactionPolicy sets the policy applied to every Response added afterwards.
+// A nil policy disables redaction. Responses already stored are not changed.
+func (p *ResponsePack) SetRedactionPolicy(policy *RedactionPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Redaction = policy
+}
+
 // AddInfo adds a key-value pair to the info map of the ResponsePack struct.
 func (p *ResponsePack) AddInfo(key string, value string) {
 	p.Info[key] = value
@@ -551,20 +907,27 @@ func (p *ResponsePack) ToString() string {
 	var str strings.Builder
 	str.Grow(256)
 
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refreshDistributions()
 
 	str.WriteString(fmt.Sprintf("Total: %d", p.Total))
 	str.WriteString(fmt.Sprintf("\nSuccess: %d", p.Success))
 	str.WriteString(fmt.Sprintf("\nFailure: %d", p.Failure))
+	str.WriteString(fmt.Sprintf("\nIgnored: %d", p.Ignored))
 	str.WriteString(fmt.Sprintf("\nSuccessRatio: %f", p.SuccessRatio))
 	str.WriteString(fmt.Sprintf("\nFailureRatio: %f", p.FailureRatio))
+	if p.Evicted > 0 {
+		str.WriteString(fmt.Sprintf("\nEvicted: %d", p.Evicted))
+	}
 	str.WriteString("\nInfo:")
 
 	for key, value := range p.Info {
 		str.WriteString(fmt.Sprintf("\n\t%s: %s", key, value))
 	}
 
+	str.WriteString(p.Stats.ToString())
+
 	return str.String()
 }
 
@@ -584,9 +947,20 @@ func (p *ResponsePack) Len() int {
 	return len(p.Responses)
 }
 
-// GetErrorReport returns a map of strings to strings, where each key is a URL and
-// each value is the status code of the response. The map only contains URLs for
-// which the status code was not successful.
+// ErrorReportEntry is a failed round of an error report. Problem holds the RFC 9457
+// problem details carried by the body of the response, if any, and GraphQLErrors
+// the errors of a GraphQL response body.
+type ErrorReportEntry struct {
+	*Response
+	Problem       *ProblemDetails `json:"problem,omitempty"`
+	GraphQLErrors []GraphQLError  `json:"graphqlErrors,omitempty"`
+}
+
+// GetErrorReport returns a map of maps, where each key is a URL and each value maps
+// the failed rounds of that URL to their Response. The map only contains URLs for
+// which the Classifier counted at least one round as a failure, GraphQL errors
+// included when SetGraphQLErrorsAsFailure enabled them. GetErrorReportEntries returns the
+// same rounds with their problem details and GraphQL errors.
 //
 // The function will return an error if the ResponsePack is nil or if there are no
 // responses stored in the pack.
@@ -594,6 +968,24 @@ func (p *ResponsePack) Len() int {
 // Note: The function acquires a read lock on the ResponsePack's mutex to ensure
 // thread-safe access to the Responses map.
 func (p *ResponsePack) GetErrorReport() (map[string]map[string]*Response, error) {
+	entries, err := p.GetErrorReportEntries()
+	if err != nil {
+		return nil, err
+	}
+	output := make(map[string]map[string]*Response, len(entries))
+	for outKey, outValue := range entries {
+		output[outKey] = make(map[string]*Response, len(outValue))
+		for inKey, entry := range outValue {
+			output[outKey][inKey] = entry.Response
+		}
+	}
+	return output, nil
+}
+
+// GetErrorReportEntries returns the failed rounds of GetErrorReport as
+// ErrorReportEntry values, carrying the problem details and GraphQL errors of their
+// body. It returns the errors of GetErrorReport.
+func (p *ResponsePack) GetErrorReportEntries() (map[string]map[string]*ErrorReportEntry, error) {
 
 	if p == nil {
 		return nil, fmt.Errorf("response pack is nil")
@@ -605,13 +997,31 @@ func (p *ResponsePack) GetErrorReport() (map[string]map[string]*Response, error)
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	output := map[string]map[string]*Response{}
+	output := map[string]map[string]*ErrorReportEntry{}
+	report := func(outKey, inKey string, inValue *Response) {
+		if output[outKey] == nil {
+			output[outKey] = make(map[string]*ErrorReportEntry)
+		}
+		entry := &ErrorReportEntry{Response: inValue}
+		entry.Problem, _ = inValue.Problem()
+		if graphQL, err := inValue.GraphQL(); err == nil {
+			entry.GraphQLErrors = graphQL.Errors
+		}
+		output[outKey][inKey] = entry
+	}
+
+	// An indexed pack knows its failed rounds
+	if p.indexes != nil {
+		for ref := range p.indexes.failures {
+			report(ref.key, roundKey(ref.seq), p.Responses[ref.key][roundKey(ref.seq)])
+		}
+		return output, nil
+	}
 
 	for outKey, outValue := range p.Responses {
 		for inKey, inValue := range outValue {
-			if !codes.IsSuccess(inValue.StatusCode) {
-				output[outKey] = make(map[string]*Response)
-				output[outKey][inKey] = inValue
+			if p.classify(inValue) == OutcomeFailure {
+				report(outKey, inKey, inValue)
 			}
 		}
 	}
@@ -621,35 +1031,195 @@ func (p *ResponsePack) GetErrorReport() (map[string]map[string]*Response, error)
 
 // GetErrorReportString returns a string representation of the error report
 // for the ResponsePack. It includes URLs and their corresponding status codes
-// for responses that were not successful. The function will return an error
-// string if the ResponsePack is nil or if there are no failed responses. It
-// locks the mutex for reading to ensure thread-safe access to the Responses map.
+// for responses that were not successful, followed by the problem title and
+// detail when the response carries problem details, and by one line per GraphQL
+// error with its message and path. URLs and rounds are listed
+// in order. The function will return an error string if the ResponsePack is nil
+// or if there are no failed responses. It locks the mutex for reading to ensure
+// thread-safe access to the Responses map.
 func (p *ResponsePack) GetErrorReportString() (string, error) {
 	var str strings.Builder
-	reportMap, err := p.GetErrorReport()
+	reportMap, err := p.GetErrorReportEntries()
 
 	if err != nil {
 		str.WriteString(err.Error())
 		return str.String(), err
 	}
 	str.WriteString("Error Report:\n")
-	for key, value := range reportMap {
+	for _, key := range sortedKeys(reportMap) {
+		value := reportMap[key]
 		str.WriteString("URL: ")
 		str.WriteString(key)
 		str.WriteString("\n")
-		for inKey, inValue := range value {
-			str.WriteString(fmt.Sprintf("\t%s: %d\n", inKey, inValue.StatusCode))
+		for _, inKey := range sortedRoundKeys(value) {
+			inValue := value[inKey]
+			str.WriteString(fmt.Sprintf("\t%s: %d", inKey, inValue.StatusCode))
+			if inValue.Problem != nil && inValue.Problem.Summary() != "" {
+				str.WriteString(" - ")
+				str.WriteString(inValue.Problem.Summary())
+			}
+			str.WriteString("\n")
+			for _, graphQLError := range inValue.GraphQLErrors {
+				str.WriteString("\t\t- ")
+				str.WriteString(graphQLError.String())
+				str.WriteString("\n")
+			}
 		}
 	}
 
 	return str.String(), nil
 }
 
+// ToJSON converts the ResponsePack struct, rounds and statistics included, to a JSON-encoded byte slice.
+func (p *ResponsePack) ToJSON() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refreshDistributions()
+	return json.Marshal(p)
+}
+
+// NewResponsePackFromJSON takes a JSON-encoded byte slice produced by ResponsePack.ToJSON
+// and decodes it into a new ResponsePack.
+func NewResponsePackFromJSON(data []byte) (*ResponsePack, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("input JSON data is empty")
+	}
+
+	pack := NewResponsePack()
+	err := json.Unmarshal(data, pack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON into ResponsePack: %w", err)
+	}
+
+	if pack.Responses == nil {
+		pack.Responses = map[string]map[string]*Response{}
+	}
+	if pack.Info == nil {
+		pack.Info = map[string]string{}
+	}
+	// Packs written before LastRound existed get it from their round keys
+	if pack.LastRound == nil {
+		pack.LastRound = map[string]int{}
+	}
+	pack.order = rebuildOrder(pack.Responses, pack.LastRound)
+
+	// Stats are rebuilt from the rounds, the samples behind them are not encoded
+	pack.resetStats()
+	for key, rounds := range pack.Responses {
+		for _, response := range rounds {
+			response.shareBody()
+			pack.track(key, response)
+		}
+	}
+
+	return pack, nil
+}
+
+// LoadResponsePackFile reads a JSON file written from ResponsePack.ToJSON and decodes it into a new ResponsePack.
+func LoadResponsePackFile(path string) (*ResponsePack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack file: %w", err)
+	}
+	return NewResponsePackFromJSON(data)
+}
+
+// NewResponsePackFromJSONWithConfig decodes data like NewResponsePackFromJSON into a
+// pack with the settings of config. The rounds keep their stored keys and sequence
+// numbers, so config.KeyFunc should be the key function of the saved pack: new rounds
+// are then keyed like the stored ones. The counters, statistics and indexes are
+// recomputed under config, stored bodies are deduplicated or stripped of their raw
+// message as config asks, and config.Limits are enforced. Stored rounds are not
+// redacted again; config.Redaction applies to the rounds added afterwards.
+func NewResponsePackFromJSONWithConfig(data []byte, config ConfigResponsePack) (*ResponsePack, error) {
+	decoded, err := NewResponsePackFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := config.Limits
+	config.Limits = PackLimits{}
+	pack := NewResponsePackFromConfig(config)
+	for key, value := range decoded.Info {
+		pack.Info[key] = value
+	}
+	pack.LastRound = decoded.LastRound
+	pack.order = decoded.order
+	pack.Evicted = decoded.Evicted
+	for key, rounds := range decoded.Responses {
+		stored := make(map[string]*Response, len(rounds))
+		for round, response := range rounds {
+			if pack.DropRawResponse {
+				response = response.withoutRaw()
+			}
+			stored[round] = pack.dedupBody(response)
+		}
+		pack.Responses[key] = stored
+	}
+	pack.recount()
+
+	pack.SetLimits(limits)
+	return pack, nil
+}
+
+// LoadResponsePackFileWithConfig reads a JSON file written from ResponsePack.ToJSON
+// and decodes it with NewResponsePackFromJSONWithConfig.
+func LoadResponsePackFileWithConfig(path string, config ConfigResponsePack) (*ResponsePack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack file: %w", err)
+	}
+	return NewResponsePackFromJSONWithConfig(data, config)
+}
+
+// ConfigResponsePack holds the settings of a ResponsePack created with
+// NewResponsePackFromConfig. Zero values keep the defaults of NewResponsePack.
+type ConfigResponsePack struct {
+	KeyFunc                KeyFunc
+	Classifier             Classifier
+	Redaction              *RedactionPolicy
+	GraphQLErrorsAsFailure bool
+	DropRawResponse        bool
+	// DedupBodies stores one copy of each distinct body, see DedupStats.
+	//
+	// The stored Body slices are then read-only: the rounds with the same body,
+	// including the ones already returned by GetResponse, History, GetRound or
+	// Latest, share one slice, so changing its bytes in place changes the body of
+	// every one of them. Clone a round to modify its body; appending to a Body copies
+	// it and is safe.
+	DedupBodies bool
+	Info        map[string]string
+	// Indexes are the secondary indexes of the pack, see IndexField.
+	Indexes []IndexField
+	// Limits bound the rounds the pack retains, see PackLimits.
+	Limits PackLimits
+}
+
+// NewResponsePackFromConfig returns a new, empty ResponsePack with the settings of config.
+func NewResponsePackFromConfig(config ConfigResponsePack) *ResponsePack {
+	pack := NewResponsePack()
+	pack.key = config.KeyFunc
+	pack.classifier = config.Classifier
+	pack.Redaction = config.Redaction
+	pack.graphQLErrorsAsFailure = config.GraphQLErrorsAsFailure
+	pack.DropRawResponse = config.DropRawResponse
+	pack.blobs = newBlobStore(config.DedupBodies)
+	pack.Stats.Dedup = pack.blobs.dedupStats()
+	pack.indexes = newPackIndexes(config.Indexes)
+	pack.limiter = newPackLimiter(config.Limits)
+	for key, value := range config.Info {
+		pack.Info[key] = value
+	}
+	return pack
+}
+
 // NewResponsePack returns a new ResponsePack instance with zero values for all fields.
 func NewResponsePack() *ResponsePack {
 	return &ResponsePack{
 		Responses:    map[string]map[string]*Response{}, // keys: Urls, values: map[string]Responses
 		Info:         map[string]string{},               // keys: string, values: string
+		LastRound:    map[string]int{},
+		Stats:        newPackStats(),
 		Total:        0,
 		Success:      0,
 		Failure:      0,
@@ -740,6 +1310,9 @@ func responseParser(data *[]byte, url string) (*Response, error) {
 		return nil, fmt.Errorf("failed to create response: %w", err)
 	}
 
+	// Keep a single copy of the body when the raw message carries it as is
+	response.shareBody()
+
 	// Close response body
 	err = httpResponse.Body.Close()
 	if err != nil {
@@ -812,6 +1385,7 @@ func NewResponseFromCompressed(compressedData []byte) (*Response, error) {
 	if err := json.Unmarshal(jsonData, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	response.shareBody()
 
 	return &response, nil
 }
@@ -821,21 +1395,36 @@ func NewResponseFromCompressed(compressedData []byte) (*Response, error) {
 
 type CompressResponsePack struct {
 	CompressedResponses map[string]map[string][]byte
-	MetaInfo            map[string]string
-	mu                  sync.RWMutex
+	// LastRound is the highest sequence number handed out per URL, see Round.
+	LastRound map[string]int
+	MetaInfo  map[string]string
+	// Evicted counts the rounds evicted over the lifetime of the pack, see PackLimits.
+	Evicted   uint64           `json:",omitempty"`
+	Redaction *RedactionPolicy `json:"-"`
+	order     map[string][]int
+	key       KeyFunc
+	limiter   *packLimiter
+	total     MemoryUsage
+	memory    map[string]MemoryUsage
+	sizes     map[roundRef]int64
+	blobs     *blobStore
+	bodies    map[roundRef]sharedBody
+	mu        sync.RWMutex
 }
 
 // NewCompressResponsePack creates a new CompressResponsePack, initializing the CompressedResponses sync.Map.
 func NewCompressResponsePack() *CompressResponsePack {
 	return &CompressResponsePack{
 		CompressedResponses: make(map[string]map[string][]byte),
+		LastRound:           make(map[string]int),
+		order:               make(map[string][]int),
 		MetaInfo:            make(map[string]string),
 		mu:                  sync.RWMutex{},
 	}
 }
 
-// AddResponse compresses the given Response object and adds it to the CompressedResponses map,
-// handling duplicate URL entries by appending a round suffix. It returns an error if compression fails.
+// AddResponse compresses the given Response object and adds it to the CompressedResponses map
+// as the next round of its key, see KeyFunc and Round. It returns an error if compression fails.
 func (r *CompressResponsePack) AddResponse(response *Response) error {
 
 	if response == nil {
@@ -846,31 +1435,51 @@ func (r *CompressResponsePack) AddResponse(response *Response) error {
 		return fmt.Errorf("response pack is nil")
 	}
 
-	compressedData, err := response.Compress()
+	r.mu.RLock()
+	policy := r.Redaction
+	r.mu.RUnlock()
+
+	// Redact before compressing, the caller's response is left untouched
+	if policy != nil {
+		response = policy.Redact(response)
+	}
+
+	// Shared bodies are compressed once, apart from the rounds
+	stored, pending := r.splitBody(response)
+	compressedData, err := stored.Compress()
 	if err != nil {
 		return err
 	}
+	logical := response.Size()
 
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
-	// Check if the URL already exists in the map
-	_, ok := r.CompressedResponses[response.Url]
-
-	if !ok {
-		// If not, create a new map for the URL
-		r.CompressedResponses[response.Url] = map[string][]byte{}
-		r.CompressedResponses[response.Url]["round_1"] = compressedData
-		return nil
+	if err := r.limiter.fits(int64(len(compressedData))); err != nil {
+		r.mu.Unlock()
+		return err
+	}
+	if r.LastRound == nil {
+		r.LastRound = map[string]int{}
 	}
+	if r.order == nil {
+		r.order = map[string][]int{}
+	}
+	// Store under the next sequence number of the URL
+	key := r.Key(response)
+	seq := appendRound(r.CompressedResponses, r.LastRound, r.order, key, compressedData)
+	r.referenceBody(roundRef{key, seq}, pending, response.Body)
+	r.account(key, seq, logical)
 
-	// If the URL already exists, append a round suffix
-	var round int = 0
-	for range r.CompressedResponses[response.Url] {
-		round++
+	// Evict what no longer fits, the callback runs unlocked
+	var evictions []compressedEviction
+	if r.limiter != nil {
+		r.limiter.added(roundRef{key, seq}, int64(len(compressedData)))
+		evictions = r.enforceLimits([]string{key})
 	}
-	r.CompressedResponses[response.Url][fmt.Sprintf("round_%d", round+1)] = compressedData
+	onEvict := r.onEvict()
+	r.mu.Unlock()
 
+	notifyCompressedEvictions(onEvict, evictions)
 	return nil
 }
 
@@ -920,6 +1529,19 @@ func (r *CompressResponsePack) BatchAddResponse(responses []*Response) []error {
 	return nil
 }
 
+// GetKeysOfResponses returns a slice containing all the URLs stored in the CompressedResponses map.
+func (r *CompressResponsePack) GetKeysOfResponses() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]string, 0, len(r.CompressedResponses))
+	for key := range r.CompressedResponses {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
 // GetResponseCount returns the total number of responses stored in the CompressedResponses map.
 func (r *CompressResponsePack) GetResponseCount() int {
 	var counter int = 0
@@ -931,23 +1553,31 @@ func (r *CompressResponsePack) GetResponseCount() int {
 	return counter
 }
 
-// GetResponse takes a URL and retrieves a Response from the CompressedResponses map.
-// The function handles both direct lookups and URLs with round suffixes.
+// GetResponse takes a key, the URL unless the pack was built with another KeyFunc,
+// and retrieves its rounds from the CompressedResponses map in round order.
 // It returns the Response object and an error if the response is not found or if decompression fails.
-func (r *CompressResponsePack) GetResponse(url string) ([]*Response, error) {
+func (r *CompressResponsePack) GetResponse(key string) ([]*Response, error) {
 	// Find compressed data
 
 	r.mu.RLock()
-	responses, ok := r.CompressedResponses[url]
-	r.mu.RUnlock()
+	responses, ok := r.CompressedResponses[key]
 	if !ok {
-		return nil, fmt.Errorf("response not found for URL: %s", url)
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("response not found for key: %s", key)
+	}
+	r.limiter.touch(key)
+	// Copy the rounds while holding the lock, decompress afterwards
+	compressed := make([]compressedRound, 0, len(responses))
+	for _, seq := range orderedSeqs(responses, r.order[key]) {
+		compressed = append(compressed, r.copyRound(key, seq))
 	}
+	r.mu.RUnlock()
+
 	var responseSlice []*Response
 
-	for _, value := range responses {
+	for _, value := range compressed {
 		// Decompress
-		response, err := NewResponseFromCompressed(value)
+		response, err := value.decompress()
 		if err != nil {
 			return nil, err
 		}
@@ -956,97 +1586,67 @@ func (r *CompressResponsePack) GetResponse(url string) ([]*Response, error) {
 	return responseSlice, nil
 }
 
-// BatchGetResponse takes a slice of URLs and retrieves the corresponding
-// Response objects from the CompressResponsePack concurrently. It handles
-// both direct lookups and URLs with round suffixes. The function returns a
-// map of maps, where the outer key is the URL and the inner key is the round
-// number and the value is the Response object. The function also returns a
+// BatchGetResponse takes a slice of keys and retrieves the corresponding
+// Response objects from the CompressResponsePack concurrently. The function
+// returns a map of maps, where the outer key is the pack key, the inner key carries
+// the sequence number of the round (e.g., "round_1") and the value is the
+// Response object. The function also returns a
 // slice of errors if any of the GetResponse operations fail.
 func (r *CompressResponsePack) BatchGetResponse(urls []string) (map[string]map[string]*Response, []error) {
-	errCh := make(chan error, len(urls))
-	respCh := make(chan []*Response, len(urls))
-	errSlice := make([]error, 0)
-
-	wg := sync.WaitGroup{}
-
-	for _, url := range urls {
-		wg.Add(1)
-		go func(url string) {
-			defer wg.Done()
-			resp, err := r.GetResponse(url)
-			if err != nil {
-				errCh <- err
-			} else if resp != nil {
-				respCh <- resp
-			}
-		}(url)
-	}
-
-	wg.Wait()
-	close(errCh)
-	close(respCh)
-
-	for err := range errCh {
-		errSlice = append(errSlice, err)
-	}
-
+	responses, errSlice := batchHistory(urls, r.History)
 	if len(errSlice) > 0 {
 		return nil, errSlice
 	}
-
-	responses := make(map[string]map[string]*Response)
-
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	for resp := range respCh {
-		for index, response := range resp {
-			responses[response.Url] = make(map[string]*Response)
-			newKey := fmt.Sprintf("round_%d", index+1)
-			responses[response.Url][newKey] = response
-		}
-	}
 	return responses, nil
 }
 
-// DeleteResponse takes a URL and removes the corresponding Responses from the
-// CompressedResponses map. If a response does not exist for a URL, it returns an
+// DeleteResponse takes a key and removes the corresponding Responses from the
+// CompressedResponses map. If a response does not exist for the key, it returns an
 // error. The function also resets the CompressedResponses map to an empty map if
 // no responses remain after deletion.
-func (r *CompressResponsePack) DeleteResponse(url string) error {
+func (r *CompressResponsePack) DeleteResponse(key string) error {
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	_, ok := r.CompressedResponses[url]
+	rounds, ok := r.CompressedResponses[key]
 	if !ok {
-		return fmt.Errorf("response not found for URL: %s", url)
+		return fmt.Errorf("response not found for key: %s", key)
 	}
-	delete(r.CompressedResponses, url)
+	for round := range rounds {
+		r.unaccount(key, roundNumber(round))
+		r.releaseBody(roundRef{key, roundNumber(round)})
+		if r.limiter != nil {
+			r.limiter.removed(roundRef{key, roundNumber(round)})
+		}
+	}
+	delete(r.CompressedResponses, key)
+	// LastRound is kept so that the sequence numbers of the URL are not reused
+	delete(r.order, key)
 	if len(r.CompressedResponses) == 0 {
 		r.CompressedResponses = make(map[string]map[string][]byte)
 	}
 	return nil
 }
 
-// BatchDeleteResponse takes a slice of URLs and removes the corresponding
+// BatchDeleteResponse takes a slice of keys and removes the corresponding
 // Responses from the CompressedResponses map concurrently. If a response
-// does not exist for a URL, it returns an error. The function returns a
+// does not exist for a key, it returns an error. The function returns a
 // slice of errors for any failed delete operations.
-func (r *CompressResponsePack) BatchDeleteResponse(urls []string) []error {
-	errCh := make(chan error, len(urls))
+func (r *CompressResponsePack) BatchDeleteResponse(keys []string) []error {
+	errCh := make(chan error, len(keys))
 	errSlice := make([]error, 0)
 	wg := sync.WaitGroup{}
 
-	for _, url := range urls {
+	for _, key := range keys {
 		wg.Add(1)
-		go func(url string) {
+		go func(key string) {
 			defer wg.Done()
-			err := r.DeleteResponse(url)
+			err := r.DeleteResponse(key)
 			if err != nil {
 				errCh <- err
 			}
-		}(url)
+		}(key)
 	}
 
 	wg.Wait()
@@ -1063,6 +1663,14 @@ func (r *CompressResponsePack) BatchDeleteResponse(urls []string) []error {
 	return nil
 }
 
+// SetRedactionPolicy sets the policy applied to every Response added afterwards.
+// A nil policy disables redaction. Responses already stored are not changed.
+func (r *CompressResponsePack) SetRedactionPolicy(policy *RedactionPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Redaction = policy
+}
+
 // AddInfo adds a key-value pair to the info map of the CompressResponsePack struct.
 func (r *CompressResponsePack) AddInfo(key string, value string) {
 	r.mu.Lock()
@@ -1087,4 +1695,132 @@ func (r *CompressResponsePack) Clear() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.CompressedResponses = map[string]map[string][]byte{}
+	r.LastRound = map[string]int{}
+	r.order = map[string][]int{}
+	r.resetMemory()
+	r.resetBodies()
+	if r.limiter != nil {
+		r.limiter.reset()
+	}
+}
+
+// ToString returns a string representation of the CompressResponsePack: its keys,
+// rounds, memory usage and meta info.
+func (r *CompressResponsePack) ToString() string {
+
+	var str strings.Builder
+	str.Grow(256)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	str.WriteString(fmt.Sprintf("Keys: %d", len(r.CompressedResponses)))
+	str.WriteString(fmt.Sprintf("\nRounds: %d", r.total.Rounds))
+	if r.Evicted > 0 {
+		str.WriteString(fmt.Sprintf("\nEvicted: %d", r.Evicted))
+	}
+	str.WriteString("\nMemory: " + r.total.String())
+	if r.blobs != nil {
+		str.WriteString("\nDedup: " + r.blobs.stats.String())
+	}
+	str.WriteString("\nMetaInfo:")
+
+	for key, value := range r.MetaInfo {
+		str.WriteString(fmt.Sprintf("\n\t%s: %s", key, value))
+	}
+
+	return str.String()
+}
+
+// Print prints a string representation of the CompressResponsePack struct to the console.
+func (r *CompressResponsePack) Print() {
+	fmt.Println(r.ToString())
+}
+
+// ConfigCompressResponsePack holds the settings of a CompressResponsePack created
+// with NewCompressResponsePackFromConfig. Zero values keep the defaults of
+// NewCompressResponsePack.
+type ConfigCompressResponsePack struct {
+	KeyFunc   KeyFunc
+	Redaction *RedactionPolicy
+	MetaInfo  map[string]string
+	// Limits bound the rounds the pack retains, see PackLimits.
+	Limits PackLimits
+	// DedupBodies stores one gzipped copy of each distinct body, see DedupStats.
+	DedupBodies bool
+}
+
+// NewCompressResponsePackFromConfig returns a new, empty CompressResponsePack with
+// the settings of config.
+func NewCompressResponsePackFromConfig(config ConfigCompressResponsePack) *CompressResponsePack {
+	pack := NewCompressResponsePack()
+	pack.key = config.KeyFunc
+	pack.Redaction = config.Redaction
+	pack.limiter = newPackLimiter(config.Limits)
+	pack.blobs = newBlobStore(config.DedupBodies)
+	for key, value := range config.MetaInfo {
+		pack.MetaInfo[key] = value
+	}
+	return pack
+}
+
+// Pack interface
+// ----------------------------------------------------------------------
+
+// Pack is the common behaviour of ResponsePack and CompressResponsePack. Components
+// that only need to store and read rounds (replay, mock server, proxy, ...) accept
+// a Pack so they work with both kinds of pack.
+type Pack interface {
+	AddResponse(response *Response) error
+	GetResponse(key string) ([]*Response, error)
+	GetKeysOfResponses() []string
+	History(key string) ([]Round, error)
+	Key(response *Response) string
+}
+
+var (
+	_ Pack = (*ResponsePack)(nil)
+	_ Pack = (*CompressResponsePack)(nil)
+)
+
+// forEachRound calls fn for every round of pack, keys in sorted order and rounds
+// in round order. key is the pack key of the round, not necessarily its URL, see
+// KeyFunc. round is the sequence number of the round, see Round. Iteration
+// stops at the first error returned by fn.
+func forEachRound(pack Pack, fn func(key string, round int, response *Response) error) error {
+	keys := pack.GetKeysOfResponses()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		rounds, err := pack.History(key)
+		if err != nil {
+			// Deleted since the keys were read
+			continue
+		}
+		for _, round := range rounds {
+			err = fn(key, round.Seq, round.Response)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// snapshotPack returns pack as a ResponsePack. A ResponsePack is returned as is,
+// other packs are copied round by round into a pack grouping rounds with the keys
+// of pack. The rounds were redacted by pack already, so they are not redacted again.
+func snapshotPack(pack Pack) (*ResponsePack, error) {
+	if responsePack, ok := pack.(*ResponsePack); ok {
+		return responsePack, nil
+	}
+
+	output := NewResponsePackFromConfig(ConfigResponsePack{KeyFunc: pack.Key})
+	err := forEachRound(pack, func(key string, round int, response *Response) error {
+		return output.AddResponse(response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
 }
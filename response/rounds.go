@@ -0,0 +1,357 @@
+package response
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Rounds
+// ----------------------------------------------------------------------
+
+// Round is a stored round of a pack key, see KeyFunc. Seq is its sequence number:
+// the rounds of a key are numbered from 1 in the order they were added, and a
+// number is never handed out twice, so Seq N is always stored under "round_N".
+type Round struct {
+	Seq      int       `json:"seq"`
+	Response *Response `json:"response"`
+}
+
+// roundKey returns the "round_N" key of the sequence number seq.
+func roundKey(seq int) string {
+	return fmt.Sprintf("round_%d", seq)
+}
+
+// orderedSeqs returns the sequence numbers of rounds in order. index, the order
+// kept by the pack, is used when it covers rounds; otherwise, for a pack decoded
+// or filled by hand, the keys are sorted.
+func orderedSeqs[T any](rounds map[string]T, index []int) []int {
+	if len(index) == len(rounds) {
+		return index
+	}
+	keys := sortedRoundKeys(rounds)
+	seqs := make([]int, len(keys))
+	for i, key := range keys {
+		seqs[i] = roundNumber(key)
+	}
+	return seqs
+}
+
+// appendRound stores value as the next round of key and returns its sequence
+// number, one past the highest number ever handed out for key. last and order are
+// the counters and the order kept by the pack. The caller holds the write lock.
+func appendRound[T any](storage map[string]map[string]T, last map[string]int, order map[string][]int, key string, value T) int {
+	rounds, ok := storage[key]
+	if !ok {
+		rounds = map[string]T{}
+		storage[key] = rounds
+	}
+
+	seqs := orderedSeqs(rounds, order[key])
+	seq := last[key]
+	if count := len(seqs); count > 0 {
+		seq = max(seq, seqs[count-1])
+	}
+	seq++
+
+	rounds[roundKey(seq)] = value
+	order[key] = append(seqs, seq)
+	last[key] = seq
+	return seq
+}
+
+// rebuildOrder rebuilds the order of every key of storage and raises last to the
+// highest stored sequence number, e.g. after decoding a pack.
+func rebuildOrder[T any](storage map[string]map[string]T, last map[string]int) map[string][]int {
+	order := make(map[string][]int, len(storage))
+	for key, rounds := range storage {
+		seqs := orderedSeqs(rounds, nil)
+		order[key] = seqs
+		if count := len(seqs); count > 0 {
+			last[key] = max(last[key], seqs[count-1])
+		}
+	}
+	return order
+}
+
+// removeRound removes the round seq of key, and key itself once it has no rounds
+// left. The counter of key is kept, so seq is not handed out again. The caller
+// holds the write lock.
+func removeRound[T any](storage map[string]map[string]T, order map[string][]int, key string, seq int) {
+	rounds := storage[key]
+	delete(rounds, roundKey(seq))
+	if len(rounds) == 0 {
+		delete(storage, key)
+		delete(order, key)
+		return
+	}
+
+	seqs := order[key]
+	for i, stored := range seqs {
+		if stored == seq {
+			order[key] = append(seqs[:i:i], seqs[i+1:]...)
+			return
+		}
+	}
+}
+
+// ResponsePack
+// ----------------------------------------------------------------------
+
+// History returns the rounds of key in order.
+func (p *ResponsePack) History(key string) ([]Round, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rounds, ok := p.Responses[key]
+	if !ok {
+		return nil, fmt.Errorf("response not found for key: %s", key)
+	}
+	p.limiter.touch(key)
+	seqs := orderedSeqs(rounds, p.order[key])
+	output := make([]Round, 0, len(seqs))
+	for _, seq := range seqs {
+		output = append(output, Round{Seq: seq, Response: rounds[roundKey(seq)]})
+	}
+	return output, nil
+}
+
+// GetRound returns the round of key with the sequence number n.
+func (p *ResponsePack) GetRound(key string, n int) (*Response, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	p.limiter.touch(key)
+	return p.storedRound(key, n)
+}
+
+// First returns the oldest stored round of key.
+func (p *ResponsePack) First(key string) (*Response, error) {
+	return p.edgeRound(key, true)
+}
+
+// Latest returns the most recent round of key.
+func (p *ResponsePack) Latest(key string) (*Response, error) {
+	return p.edgeRound(key, false)
+}
+
+// edgeRound returns the first or the last round of key.
+func (p *ResponsePack) edgeRound(key string, first bool) (*Response, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rounds, ok := p.Responses[key]
+	if !ok || len(rounds) == 0 {
+		return nil, fmt.Errorf("response not found for key: %s", key)
+	}
+	p.limiter.touch(key)
+	seqs := orderedSeqs(rounds, p.order[key])
+	if first {
+		return rounds[roundKey(seqs[0])], nil
+	}
+	return rounds[roundKey(seqs[len(seqs)-1])], nil
+}
+
+// DeleteRound removes the round of key with the sequence number n and updates the
+// counters, ratios and statistics. The key is removed with its last round. Its
+// sequence numbers are not reused, so the remaining rounds keep their keys.
+func (p *ResponsePack) DeleteRound(key string, n int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	response, err := p.storedRound(key, n)
+	if err != nil {
+		return err
+	}
+	p.forget(key, response)
+	p.unindex(key, n, response)
+	removeRound(p.Responses, p.order, key, n)
+	if p.limiter != nil {
+		p.limiter.removed(roundRef{key, n})
+	}
+	p.updateRatios()
+	return nil
+}
+
+// ReplaceRound replaces the round of key with the sequence number n by response,
+// which keeps the sequence number, and updates the counters, ratios and statistics.
+// The redaction policy of the pack is applied to response. It returns an error if
+// the round does not exist or if response belongs to another key.
+func (p *ResponsePack) ReplaceRound(key string, n int, response *Response) error {
+	if response == nil {
+		return fmt.Errorf("response is nil")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if responseKey := p.keyOf(response); responseKey != key {
+		return fmt.Errorf("response key %s does not match %s", responseKey, key)
+	}
+
+	previous, err := p.storedRound(key, n)
+	if err != nil {
+		return err
+	}
+	if p.Redaction != nil {
+		response = p.Redaction.Redact(response)
+	}
+	if p.DropRawResponse {
+		response = response.withoutRaw()
+	}
+	if err := p.limiter.fits(responseBytes(response)); err != nil {
+		return err
+	}
+	response = p.dedupBody(response)
+
+	p.forget(key, previous)
+	p.unindex(key, n, previous)
+	p.count(response)
+	p.track(key, response)
+	p.Total++
+	p.updateRatios()
+
+	p.Responses[key][roundKey(n)] = response
+	p.index(key, n, response)
+	if p.limiter != nil {
+		p.limiter.resized(roundRef{key, n}, responseBytes(response))
+	}
+	return nil
+}
+
+// storedRound returns the round of key with the sequence number n. The caller
+// holds the lock.
+func (p *ResponsePack) storedRound(key string, n int) (*Response, error) {
+	rounds, ok := p.Responses[key]
+	if !ok {
+		return nil, fmt.Errorf("response not found for key: %s", key)
+	}
+	response, ok := rounds[roundKey(n)]
+	if !ok {
+		return nil, fmt.Errorf("round %d not found for key: %s", n, key)
+	}
+	return response, nil
+}
+
+// CompressResponsePack
+// ----------------------------------------------------------------------
+
+// History returns the rounds of key in order, decompressed.
+func (r *CompressResponsePack) History(key string) ([]Round, error) {
+	r.mu.RLock()
+	rounds, ok := r.CompressedResponses[key]
+	if !ok {
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("response not found for key: %s", key)
+	}
+	r.limiter.touch(key)
+	// Copy the rounds while holding the lock, decompress afterwards
+	seqs := orderedSeqs(rounds, r.order[key])
+	compressed := make([]compressedRound, len(seqs))
+	for i, seq := range seqs {
+		compressed[i] = r.copyRound(key, seq)
+	}
+	seqs = append([]int(nil), seqs...)
+	r.mu.RUnlock()
+
+	output := make([]Round, 0, len(seqs))
+	for i, seq := range seqs {
+		response, err := compressed[i].decompress()
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, Round{Seq: seq, Response: response})
+	}
+	return output, nil
+}
+
+// GetRound returns the round of key with the sequence number n, decompressed.
+func (r *CompressResponsePack) GetRound(key string, n int) (*Response, error) {
+	r.mu.RLock()
+	rounds, ok := r.CompressedResponses[key]
+	if !ok {
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("response not found for key: %s", key)
+	}
+	r.limiter.touch(key)
+	if _, ok := rounds[roundKey(n)]; !ok {
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("round %d not found for key: %s", n, key)
+	}
+	compressed := r.copyRound(key, n)
+	r.mu.RUnlock()
+	return compressed.decompress()
+}
+
+// First returns the oldest stored round of key, decompressed.
+func (r *CompressResponsePack) First(key string) (*Response, error) {
+	return r.edgeRound(key, true)
+}
+
+// Latest returns the most recent round of key, decompressed.
+func (r *CompressResponsePack) Latest(key string) (*Response, error) {
+	return r.edgeRound(key, false)
+}
+
+// edgeRound returns the first or the last round of key, decompressed.
+func (r *CompressResponsePack) edgeRound(key string, first bool) (*Response, error) {
+	r.mu.RLock()
+	rounds, ok := r.CompressedResponses[key]
+	if !ok || len(rounds) == 0 {
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("response not found for key: %s", key)
+	}
+	r.limiter.touch(key)
+	seqs := orderedSeqs(rounds, r.order[key])
+	seq := seqs[len(seqs)-1]
+	if first {
+		seq = seqs[0]
+	}
+	compressed := r.copyRound(key, seq)
+	r.mu.RUnlock()
+
+	return compressed.decompress()
+}
+
+// batchHistory returns the rounds of every key of keys keyed by key and "round_N",
+// reading them concurrently with history. Keys that fail are reported as errors.
+func batchHistory(keys []string, history func(key string) ([]Round, error)) (map[string]map[string]*Response, []error) {
+	type result struct {
+		key    string
+		rounds []Round
+	}
+
+	errCh := make(chan error, len(keys))
+	resultCh := make(chan result, len(keys))
+	wg := sync.WaitGroup{}
+
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			rounds, err := history(key)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			resultCh <- result{key: key, rounds: rounds}
+		}(key)
+	}
+
+	wg.Wait()
+	close(errCh)
+	close(resultCh)
+
+	var errSlice []error
+	for err := range errCh {
+		errSlice = append(errSlice, err)
+	}
+
+	output := map[string]map[string]*Response{}
+	for result := range resultCh {
+		rounds := make(map[string]*Response, len(result.rounds))
+		for _, round := range result.rounds {
+			rounds[roundKey(round.Seq)] = round.Response
+		}
+		output[result.key] = rounds
+	}
+	return output, errSlice
+}
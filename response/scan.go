@@ -0,0 +1,257 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severity
+// ----------------------------------------------------------------------
+
+// Severity ranks findings reported by the scanners and auditors of this package.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+// String returns the lower-case name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// MarshalJSON encodes the severity as its name.
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Leak Scanner
+// ----------------------------------------------------------------------
+
+// ScanRule detects one kind of sensitive data. Every Pattern match is a candidate,
+// and Validate, when set, filters out false positives (e.g. the Luhn check).
+type ScanRule struct {
+	Name     string
+	Severity Severity
+	Pattern  *regexp.Regexp
+	Validate func(match string) bool
+}
+
+// Finding is a sensitive value found by a LeakScanner.
+//
+// Location is one of "url", "header:<Name>", "body", "request.header:<Name>" and
+// "request.body". Excerpt is the matched value, masked.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Url      string   `json:"url"`
+	Round    int      `json:"round"`
+	Location string   `json:"location"`
+	Excerpt  string   `json:"excerpt"`
+}
+
+// LeakScanner walks responses looking for leaked secrets and personal data.
+type LeakScanner struct {
+	Rules []ScanRule
+}
+
+// NewLeakScanner returns a scanner with DefaultScanRules.
+func NewLeakScanner() *LeakScanner {
+	return &LeakScanner{Rules: DefaultScanRules()}
+}
+
+// AddRule adds a rule to the scanner.
+func (s *LeakScanner) AddRule(rule ScanRule) {
+	s.Rules = append(s.Rules, rule)
+}
+
+// DefaultScanRules returns the built-in rules: API keys and tokens, JWTs, private
+// keys, credit-card numbers (Luhn-checked), emails and internal IP addresses.
+func DefaultScanRules() []ScanRule {
+	return []ScanRule{
+		{
+			Name:     "aws-access-key",
+			Severity: SeverityCritical,
+			Pattern:  regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		},
+		{
+			Name:     "github-token",
+			Severity: SeverityCritical,
+			Pattern:  regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+		},
+		{
+			Name:     "slack-token",
+			Severity: SeverityCritical,
+			Pattern:  regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`),
+		},
+		{
+			Name:     "api-key",
+			Severity: SeverityHigh,
+			Pattern:  regexp.MustCompile(`(?i)(?:api[_-]?key|secret|access[_-]?token)["']?\s*[:=]\s*["']?[A-Za-z0-9_\-]{16,}`),
+		},
+		{
+			Name:     "jwt",
+			Severity: SeverityHigh,
+			Pattern:  regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`),
+		},
+		{
+			Name:     "private-key",
+			Severity: SeverityCritical,
+			Pattern:  regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED )?PRIVATE KEY-----`),
+		},
+		{
+			Name:     "credit-card",
+			Severity: SeverityHigh,
+			Pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+			Validate: luhnValid,
+		},
+		{
+			Name:     "email",
+			Severity: SeverityLow,
+			Pattern:  regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`),
+		},
+		{
+			Name:     "internal-ip",
+			Severity: SeverityMedium,
+			Pattern:  regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`),
+			Validate: internalIPv4,
+		},
+	}
+}
+
+// ScanResponse scans a single Response. Findings carry response.Url and round 0.
+func (s *LeakScanner) ScanResponse(response *Response) []Finding {
+	if response == nil {
+		return nil
+	}
+	return s.scan(0, response)
+}
+
+// ScanPack scans every round of a ResponsePack or CompressResponsePack. Findings are
+// ordered by URL, round and location.
+func (s *LeakScanner) ScanPack(pack Pack) ([]Finding, error) {
+	if pack == nil {
+		return nil, fmt.Errorf("response pack is nil")
+	}
+
+	var findings []Finding
+	err := forEachRound(pack, func(key string, round int, response *Response) error {
+		findings = append(findings, s.scan(round, response)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// scan runs every rule over the URL, headers and bodies of response.
+func (s *LeakScanner) scan(round int, response *Response) []Finding {
+	var findings []Finding
+
+	add := func(location string, text string) {
+		for _, rule := range s.Rules {
+			if rule.Pattern == nil {
+				continue
+			}
+			for _, match := range rule.Pattern.FindAllString(text, -1) {
+				if rule.Validate != nil && !rule.Validate(match) {
+					continue
+				}
+				findings = append(findings, Finding{
+					Rule:     rule.Name,
+					Severity: rule.Severity,
+					Url:      response.Url,
+					Round:    round,
+					Location: location,
+					Excerpt:  maskExcerpt(match),
+				})
+			}
+		}
+	}
+
+	add("url", response.Url)
+	for _, name := range sortedKeys(response.Headers) {
+		add("header:"+name, response.Headers[name])
+	}
+	add("body", string(response.Body))
+
+	if response.Request != nil {
+		for _, name := range sortedKeys(response.Request.Headers) {
+			add("request.header:"+name, response.Request.Headers[name])
+		}
+		add("request.body", string(response.Request.Body))
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Location < findings[j].Location
+	})
+	return findings
+}
+
+// maskExcerpt keeps the first four and last two characters of a value and masks the rest.
+func maskExcerpt(value string) string {
+	runes := []rune(value)
+	if len(runes) <= 8 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + strings.Repeat("*", len(runes)-6) + string(runes[len(runes)-2:])
+}
+
+// luhnValid reports whether the digits of value pass the Luhn checksum.
+func luhnValid(value string) bool {
+	var digits []int
+	for _, char := range value {
+		if char >= '0' && char <= '9' {
+			digits = append(digits, int(char-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := digits[i]
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// internalIPv4 reports whether value is a private, loopback or link-local IPv4 address.
+func internalIPv4(value string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil || ip.To4() == nil {
+		return false
+	}
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()
+}
@@ -0,0 +1,294 @@
+package response
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pack Statistics
+// ----------------------------------------------------------------------
+
+// Distribution summarizes a set of samples. Percentiles use the nearest-rank method.
+type Distribution[T int64 | time.Duration] struct {
+	Count uint64 `json:"count"`
+	Min   T      `json:"min"`
+	Max   T      `json:"max"`
+	Mean  T      `json:"mean"`
+	P50   T      `json:"p50"`
+	P90   T      `json:"p90"`
+	P95   T      `json:"p95"`
+	P99   T      `json:"p99"`
+}
+
+// String returns the summary on one line.
+func (d Distribution[T]) String() string {
+	return fmt.Sprintf("min %v, mean %v, max %v, p50 %v, p90 %v, p95 %v, p99 %v (n=%d)",
+		d.Min, d.Mean, d.Max, d.P50, d.P90, d.P95, d.P99, d.Count)
+}
+
+// PackStats are the detailed statistics of a ResponsePack.
+//
+// StatusClasses is keyed "1xx" to "5xx" ("other" for codes outside), StatusCodes by
+// status code, Methods by method and Hosts by host. BodySize is computed over the
+// length of the stored bodies, in bytes, and Latency over the rounds that carry
+// Timing. Their Count and Mean follow every change; Min, Max and the percentiles are
+// computed from the stored rounds when the statistics are read with GetStats, ToJSON
+// or ToString, so read them through GetStats rather than the Stats field. Memory is the memory used by the rounds, see MemoryUsage, and Dedup
+// describes the bodies they share, see DedupStats.
+type PackStats struct {
+	StatusClasses map[string]uint64           `json:"statusClasses"`
+	StatusCodes   map[int]uint64              `json:"statusCodes"`
+	Methods       map[string]uint64           `json:"methods"`
+	Hosts         map[string]uint64           `json:"hosts"`
+	BodySize      Distribution[int64]         `json:"bodySize"`
+	Latency       Distribution[time.Duration] `json:"latency"`
+	Memory        MemoryUsage                 `json:"memory"`
+	Dedup         DedupStats                  `json:"dedup"`
+}
+
+// newPackStats returns empty statistics.
+func newPackStats() PackStats {
+	return PackStats{
+		StatusClasses: map[string]uint64{},
+		StatusCodes:   map[int]uint64{},
+		Methods:       map[string]uint64{},
+		Hosts:         map[string]uint64{},
+	}
+}
+
+// clone returns a deep copy of the statistics.
+func (s PackStats) clone() PackStats {
+	output := s
+	output.StatusClasses = copyCounts(s.StatusClasses)
+	output.StatusCodes = copyCounts(s.StatusCodes)
+	output.Methods = copyCounts(s.Methods)
+	output.Hosts = copyCounts(s.Hosts)
+	return output
+}
+
+// ToString returns the statistics as indented lines.
+func (s PackStats) ToString() string {
+	var sb strings.Builder
+	writeCounts := func(title string, counts map[string]uint64) {
+		sb.WriteString("\n" + title + ":")
+		for _, key := range sortedKeys(counts) {
+			sb.WriteString(fmt.Sprintf("\n\t%s: %d", key, counts[key]))
+		}
+	}
+
+	writeCounts("StatusClasses", s.StatusClasses)
+	statusCodes := make(map[string]uint64, len(s.StatusCodes))
+	for code, count := range s.StatusCodes {
+		statusCodes[fmt.Sprintf("%d", code)] = count
+	}
+	writeCounts("StatusCodes", statusCodes)
+	writeCounts("Methods", s.Methods)
+	writeCounts("Hosts", s.Hosts)
+	sb.WriteString("\nBodySize: " + s.BodySize.String())
+	if s.Latency.Count > 0 {
+		sb.WriteString("\nLatency: " + s.Latency.String())
+	}
+	sb.WriteString("\nMemory: " + s.Memory.String())
+	if s.Dedup.References > 0 {
+		sb.WriteString("\nDedup: " + s.Dedup.String())
+	}
+	return sb.String()
+}
+
+// statusClass returns "1xx" to "5xx" for a status code, "other" outside that range.
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "other"
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// copyCounts returns a copy of a counter map.
+func copyCounts[K comparable](counts map[K]uint64) map[K]uint64 {
+	output := make(map[K]uint64, len(counts))
+	for key, count := range counts {
+		output[key] = count
+	}
+	return output
+}
+
+// decrement lowers counts[key], removing the key when it reaches zero.
+func decrement[K comparable](counts map[K]uint64, key K) {
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
+
+// samples keeps the count and sum of a set of values, so that the Count and Mean of
+// a Distribution stay current as rounds come and go. Min, Max and the percentiles
+// are computed from the stored rounds when the statistics are read, see
+// refreshDistributions, so the pack keeps no copy of the values.
+type samples[T int64 | time.Duration] struct {
+	count uint64
+	sum   float64
+}
+
+// add counts value.
+func (s *samples[T]) add(value T) {
+	s.count++
+	s.sum += float64(value)
+}
+
+// remove uncounts value.
+func (s *samples[T]) remove(value T) {
+	if s.count == 0 {
+		return
+	}
+	s.count--
+	s.sum -= float64(value)
+}
+
+// update sets the Count and Mean of summary and returns it.
+func (s *samples[T]) update(summary Distribution[T]) Distribution[T] {
+	if s.count == 0 {
+		return Distribution[T]{}
+	}
+	summary.Count = s.count
+	summary.Mean = T(s.sum / float64(s.count))
+	return summary
+}
+
+// distribution summarizes values, sorting them in place.
+func distribution[T int64 | time.Duration](values []T) Distribution[T] {
+	count := len(values)
+	if count == 0 {
+		return Distribution[T]{}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	var sum float64
+	for _, value := range values {
+		sum += float64(value)
+	}
+	percentile := func(p float64) T {
+		rank := int(math.Ceil(p / 100 * float64(count)))
+		return values[max(rank-1, 0)]
+	}
+	return Distribution[T]{
+		Count: uint64(count),
+		Min:   values[0],
+		Max:   values[count-1],
+		Mean:  T(sum / float64(count)),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P95:   percentile(95),
+		P99:   percentile(99),
+	}
+}
+
+// roundDistributions returns the body size and latency distributions of rounds.
+func roundDistributions(rounds []*Response) (Distribution[int64], Distribution[time.Duration]) {
+	sizes := make([]int64, 0, len(rounds))
+	var latencies []time.Duration
+	for _, response := range rounds {
+		sizes = append(sizes, int64(len(response.Body)))
+		if response.Timing != nil {
+			latencies = append(latencies, response.Timing.Duration)
+		}
+	}
+	return distribution(sizes), distribution(latencies)
+}
+
+// refreshDistributions recomputes BodySize and Latency from the stored rounds when
+// rounds were added or removed since the last read. The caller holds the write lock.
+func (p *ResponsePack) refreshDistributions() {
+	if !p.distributionsStale {
+		return
+	}
+	rounds := make([]*Response, 0, p.bodySizes.count)
+	for _, stored := range p.Responses {
+		for _, response := range stored {
+			rounds = append(rounds, response)
+		}
+	}
+	p.Stats.BodySize, p.Stats.Latency = roundDistributions(rounds)
+	p.distributionsStale = false
+}
+
+// track adds response, a round of key, to the statistics. The caller holds the
+// write lock.
+func (p *ResponsePack) track(key string, response *Response) {
+	if p.Stats.StatusClasses == nil {
+		p.Stats = newPackStats()
+	}
+	if p.memory == nil {
+		p.memory = map[string]MemoryUsage{}
+	}
+	usage := response.MemoryUsage()
+	p.Stats.Memory.add(usage.Logical, usage.Stored)
+	addKeyMemory(p.memory, key, usage.Logical, usage.Stored)
+	p.Stats.StatusClasses[statusClass(int(response.StatusCode))]++
+	p.Stats.StatusCodes[int(response.StatusCode)]++
+	p.Stats.Methods[string(response.Method)]++
+	p.Stats.Hosts[packHost(response)]++
+
+	p.bodySizes.add(int64(len(response.Body)))
+	p.Stats.BodySize = p.bodySizes.update(p.Stats.BodySize)
+	if response.Timing != nil {
+		p.latencies.add(response.Timing.Duration)
+		p.Stats.Latency = p.latencies.update(p.Stats.Latency)
+	}
+	p.distributionsStale = true
+}
+
+// untrack removes response, a round of key, from the statistics. The caller holds
+// the write lock.
+func (p *ResponsePack) untrack(key string, response *Response) {
+	if p.Stats.StatusClasses == nil {
+		return
+	}
+	usage := response.MemoryUsage()
+	p.Stats.Memory.remove(usage.Logical, usage.Stored)
+	removeKeyMemory(p.memory, key, usage.Logical, usage.Stored)
+	decrement(p.Stats.StatusClasses, statusClass(int(response.StatusCode)))
+	decrement(p.Stats.StatusCodes, int(response.StatusCode))
+	decrement(p.Stats.Methods, string(response.Method))
+	decrement(p.Stats.Hosts, packHost(response))
+
+	p.bodySizes.remove(int64(len(response.Body)))
+	p.Stats.BodySize = p.bodySizes.update(p.Stats.BodySize)
+	if response.Timing != nil {
+		p.latencies.remove(response.Timing.Duration)
+		p.Stats.Latency = p.latencies.update(p.Stats.Latency)
+	}
+	p.distributionsStale = true
+}
+
+// resetStats clears the statistics. The caller holds the write lock.
+func (p *ResponsePack) resetStats() {
+	p.Stats = newPackStats()
+	p.Stats.Dedup = p.blobs.dedupStats()
+	p.memory = map[string]MemoryUsage{}
+	p.bodySizes = samples[int64]{}
+	p.latencies = samples[time.Duration]{}
+	p.distributionsStale = false
+}
+
+// packHost returns the host a response is counted under.
+func packHost(response *Response) string {
+	if response.Host != "" {
+		return response.Host
+	}
+	return hostOf(response.Url)
+}
+
+// GetStats returns a copy of the detailed statistics of the pack, with BodySize and
+// Latency computed from the stored rounds.
+func (p *ResponsePack) GetStats() PackStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Stats.StatusClasses == nil {
+		return newPackStats()
+	}
+	p.refreshDistributions()
+	return p.Stats.clone()
+}
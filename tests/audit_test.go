@@ -0,0 +1,130 @@
+package response_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func issueChecks(issues []response.AuditIssue) map[string][]string {
+	output := map[string][]string{}
+	for _, issue := range issues {
+		output[issue.Check] = append(output[issue.Check], issue.Message)
+	}
+	return output
+}
+
+func TestAuditResponseHardened(t *testing.T) {
+	resp := newTestResponse(t, "https://example.com/", "example.com", codes.GET, codes.OK, map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"Content-Security-Policy":   "default-src 'self'; frame-ancestors 'none'",
+		"X-Content-Type-Options":    "nosniff",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"Permissions-Policy":        "geolocation=()",
+		"Set-Cookie":                "id=1; Secure; HttpOnly; SameSite=Lax; Expires=Wed, 21 Oct 2026 07:28:00 GMT",
+	}, "<html></html>")
+
+	if issues := response.AuditResponse(resp); len(issues) != 0 {
+		t.Errorf("AuditResponse() = %+v, want no issues", issues)
+	}
+}
+
+func TestAuditResponseWeak(t *testing.T) {
+	resp := newTestResponse(t, "https://example.com/", "example.com", codes.GET, codes.OK, map[string]string{
+		"Strict-Transport-Security": "max-age=600",
+		"Content-Security-Policy":   "script-src 'self' 'unsafe-inline'",
+		"X-Content-Type-Options":    "sniff",
+		"Referrer-Policy":           "unsafe-url",
+		"X-Frame-Options":           "ALLOW-FROM https://other.com",
+		"Set-Cookie":                "a=1; SameSite=None, b=2; Secure; HttpOnly; SameSite=Strict",
+	}, "<html></html>")
+
+	checks := issueChecks(response.AuditResponse(resp))
+
+	for _, check := range []string{
+		response.CheckStrictTransport,
+		response.CheckContentSecurityPolicy,
+		response.CheckContentTypeOptions,
+		response.CheckReferrerPolicy,
+		response.CheckPermissionsPolicy,
+		response.CheckFrameOptions,
+		response.CheckCookie,
+	} {
+		if len(checks[check]) == 0 {
+			t.Errorf("no %s issue", check)
+		}
+	}
+
+	for _, message := range checks[response.CheckCookie] {
+		if strings.Contains(message, "cookie b ") {
+			t.Errorf("cookie b is hardened, got issue %q", message)
+		}
+	}
+	if !strings.Contains(strings.Join(checks[response.CheckCookie], "|"), "SameSite=None without Secure") {
+		t.Errorf("cookie issues = %v, want SameSite=None without Secure", checks[response.CheckCookie])
+	}
+
+	plain := newTestResponse(t, "http://example.com/", "example.com", codes.GET, codes.OK, nil, "<html></html>")
+	plainChecks := issueChecks(response.AuditResponse(plain))
+	if len(plainChecks[response.CheckTransport]) != 1 || len(plainChecks[response.CheckStrictTransport]) != 0 {
+		t.Errorf("plain HTTP checks = %v, want transport issue and no HSTS issue", plainChecks)
+	}
+}
+
+func TestAuditPackReport(t *testing.T) {
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newTestResponse(t, "https://a.example.com/x", "a.example.com", codes.GET, codes.OK, nil, "<html></html>"))
+	_ = pack.AddResponse(newTestResponse(t, "https://a.example.com/x", "a.example.com", codes.GET, codes.OK, nil, "<html></html>"))
+	_ = pack.AddResponse(newTestResponse(t, "https://b.example.com/y", "b.example.com", codes.GET, codes.OK, map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+	}, "<html></html>"))
+
+	report, err := response.AuditPack(pack)
+	if err != nil {
+		t.Fatalf("AuditPack() error = %v", err)
+	}
+
+	if len(report.Hosts) != 2 || report.Hosts[0].Host != "a.example.com" {
+		t.Fatalf("Hosts = %+v, want a.example.com and b.example.com", report.Hosts)
+	}
+
+	audit := report.Hosts[0].Urls[0]
+	if audit.Rounds != 2 {
+		t.Errorf("Rounds = %d, want 2", audit.Rounds)
+	}
+	if audit.Issues[0].Severity != response.SeverityHigh {
+		t.Errorf("first issue = %+v, want the high HSTS issue first", audit.Issues[0])
+	}
+	if audit.Affected["missing Strict-Transport-Security"] != 2 {
+		t.Errorf("Affected = %v, want HSTS issue on 2 rounds", audit.Affected)
+	}
+	if report.Summary["high"] != 1 {
+		t.Errorf("Summary = %v, want 1 high", report.Summary)
+	}
+
+	data, err := report.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report JSON is invalid: %v", err)
+	}
+
+	text := report.ToString()
+	for _, want := range []string{"Host: a.example.com", "URL: https://a.example.com/x (2 rounds)", "[high] strict-transport-security"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("ToString() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestSplitSetCookie(t *testing.T) {
+	cookies := response.SplitSetCookie("a=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT; Path=/, b=2")
+	if len(cookies) != 2 || !strings.HasPrefix(cookies[1], "b=2") || !strings.Contains(cookies[0], "2026") {
+		t.Errorf("SplitSetCookie() = %q", cookies)
+	}
+}
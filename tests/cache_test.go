@@ -0,0 +1,199 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+var cacheDate = time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+func newCacheResponse(t *testing.T, url string, status codes.StatusCode, headers map[string]string) *response.Response {
+	t.Helper()
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	if _, ok := headers["Date"]; !ok {
+		headers["Date"] = cacheDate.Format(http.TimeFormat)
+	}
+	return newTestResponse(t, url, "cdn.example.com", codes.GET, status, headers, "{}")
+}
+
+func cacheIssueCodes(policy *response.CachePolicy) map[string]bool {
+	output := map[string]bool{}
+	for _, issue := range policy.Issues {
+		output[issue.Code] = true
+	}
+	return output
+}
+
+func TestParseCacheControl(t *testing.T) {
+	cc := response.ParseCacheControl(`Public, max-age=60, private="Set-Cookie, X-Token", s-maxage=abc, max-age=5`)
+
+	if !cc.Has("public") || !cc.Has("PRIVATE") {
+		t.Errorf("Directives = %v", cc.Directives)
+	}
+	if maxAge, ok := cc.Seconds("max-age"); !ok || maxAge != time.Minute {
+		t.Errorf("max-age = %v, %v, want first occurrence 1m", maxAge, ok)
+	}
+	if _, ok := cc.Seconds("s-maxage"); ok {
+		t.Errorf("invalid s-maxage parsed")
+	}
+	if fields := cc.Fields("private"); len(fields) != 2 || fields[1] != "X-Token" {
+		t.Errorf("private fields = %q", fields)
+	}
+}
+
+func TestCachePolicyFreshness(t *testing.T) {
+	resp := newCacheResponse(t, "https://cdn.example.com/a", codes.OK, map[string]string{
+		"Cache-Control": "public, max-age=600, s-maxage=3600",
+		"Age":           "100",
+		"ETag":          `"v1"`,
+	})
+
+	shared := resp.CachePolicy(response.SharedCache)
+	if !shared.Storable || shared.FreshnessLifetime != time.Hour || shared.Age != 100*time.Second {
+		t.Errorf("shared policy = %+v, want storable, 1h lifetime, 100s age", shared)
+	}
+	if shared.TTL != time.Hour-100*time.Second || !shared.Fresh {
+		t.Errorf("TTL = %v, Fresh = %v", shared.TTL, shared.Fresh)
+	}
+
+	private := resp.CachePolicy(response.PrivateCache)
+	if private.FreshnessLifetime != 10*time.Minute {
+		t.Errorf("private lifetime = %v, want max-age", private.FreshnessLifetime)
+	}
+	if !private.FreshAt(private.ResponseTime.Add(time.Minute)) || private.FreshAt(private.ResponseTime.Add(9*time.Minute)) {
+		t.Errorf("FreshAt() does not account for resident time")
+	}
+
+	expires := newCacheResponse(t, "https://cdn.example.com/b", codes.OK, map[string]string{
+		"Cache-Control": "public",
+		"Expires":       cacheDate.Add(2 * time.Hour).Format(http.TimeFormat),
+	}).CachePolicy(response.SharedCache)
+	if expires.FreshnessLifetime != 2*time.Hour || expires.Heuristic {
+		t.Errorf("Expires lifetime = %v, want 2h", expires.FreshnessLifetime)
+	}
+}
+
+func TestCachePolicyHeuristic(t *testing.T) {
+	policy := newCacheResponse(t, "https://cdn.example.com/doc", codes.OK, map[string]string{
+		"Last-Modified": cacheDate.Add(-100 * time.Hour).Format(http.TimeFormat),
+	}).CachePolicy(response.PrivateCache)
+
+	if !policy.Storable || !policy.Heuristic || policy.FreshnessLifetime != 10*time.Hour {
+		t.Errorf("policy = %+v, want heuristic 10h lifetime", policy)
+	}
+	issues := cacheIssueCodes(policy)
+	if !issues[response.CacheHeuristic] || !issues[response.CacheNoHeaders] {
+		t.Errorf("issues = %v", issues)
+	}
+
+	created := newCacheResponse(t, "https://cdn.example.com/doc", codes.Created, nil).CachePolicy(response.PrivateCache)
+	if created.Storable {
+		t.Errorf("201 without freshness information is storable")
+	}
+}
+
+func TestCachePolicyStorability(t *testing.T) {
+	privateResp := newCacheResponse(t, "https://cdn.example.com/me", codes.OK, map[string]string{
+		"Cache-Control": "private, max-age=60",
+	})
+	if privateResp.CachePolicy(response.SharedCache).Storable {
+		t.Errorf("private response storable by shared cache")
+	}
+	if !privateResp.CachePolicy(response.PrivateCache).Storable {
+		t.Errorf("private response not storable by private cache")
+	}
+
+	authorized := newCacheResponse(t, "https://cdn.example.com/me", codes.OK, map[string]string{
+		"Cache-Control": "max-age=60",
+	})
+	authorized.Request = &response.RecordedRequest{Headers: map[string]string{"Authorization": "Bearer x"}}
+	if policy := authorized.CachePolicy(response.SharedCache); policy.Storable || policy.Reason != "request carries Authorization" {
+		t.Errorf("authorized policy = %+v", policy)
+	}
+
+	noStore := newCacheResponse(t, "https://cdn.example.com/x", codes.OK, map[string]string{
+		"Cache-Control": "no-store, max-age=60",
+	}).CachePolicy(response.PrivateCache)
+	if noStore.Storable || !cacheIssueCodes(noStore)[response.CacheConflicting] {
+		t.Errorf("no-store policy = %+v", noStore)
+	}
+}
+
+func TestCachePolicyMisconfigurations(t *testing.T) {
+	policy := newCacheResponse(t, "https://cdn.example.com/x", codes.OK, map[string]string{
+		"Cache-Control": "public, no-cache",
+		"Vary":          "*",
+		"Set-Cookie":    "id=1",
+		"Expires":       "0",
+	}).CachePolicy(response.SharedCache)
+
+	issues := cacheIssueCodes(policy)
+	for _, code := range []string{response.CacheNoValidators, response.CacheVaryStar, response.CacheSharedSetCookie, response.CacheInvalidExpires} {
+		if !issues[code] {
+			t.Errorf("no %s issue in %+v", code, policy.Issues)
+		}
+	}
+	if policy.Fresh || policy.FreshnessLifetime != 0 {
+		t.Errorf("invalid Expires must be treated as expired, got %+v", policy)
+	}
+}
+
+func TestCacheReportPack(t *testing.T) {
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newCacheResponse(t, "https://cdn.example.com/ok", codes.OK, map[string]string{
+		"Cache-Control": "public, max-age=60",
+		"ETag":          `"a"`,
+	}))
+	_ = pack.AddResponse(newCacheResponse(t, "https://cdn.example.com/secret", codes.OK, map[string]string{
+		"Cache-Control": "no-store",
+	}))
+	_ = pack.AddResponse(newCacheResponse(t, "https://cdn.example.com/cookie", codes.OK, map[string]string{
+		"Cache-Control": "max-age=60",
+		"Set-Cookie":    "id=1",
+	}))
+	_ = pack.AddResponse(newCacheResponse(t, "https://cdn.example.com/cookie", codes.OK, map[string]string{
+		"Cache-Control": "max-age=60",
+		"Set-Cookie":    "id=2",
+	}))
+
+	report, err := response.CacheReportPack(pack, response.SharedCache)
+	if err != nil {
+		t.Fatalf("CacheReportPack() error = %v", err)
+	}
+	if report.Total != 3 || report.Cacheable != 2 || report.Uncacheable != 1 || report.Misconfigured != 1 {
+		t.Errorf("report totals = %+v", report)
+	}
+	if len(report.Endpoints) != 2 || report.Endpoints[0].Url != "https://cdn.example.com/cookie" {
+		t.Fatalf("Endpoints = %+v, want /cookie and /secret", report.Endpoints)
+	}
+	if report.Endpoints[0].Rounds != 2 || report.Endpoints[0].Issues[0].Code != response.CacheSharedSetCookie {
+		t.Errorf("cookie endpoint = %+v", report.Endpoints[0])
+	}
+	if report.Endpoints[1].Storable || report.Endpoints[1].Reason != "no-store" {
+		t.Errorf("secret endpoint = %+v", report.Endpoints[1])
+	}
+
+	data, err := report.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded["scope"] != "shared" {
+		t.Fatalf("report JSON = %s, %v", data, err)
+	}
+	if text := report.ToString(); !strings.Contains(text, "Storable: false, no-store") || !strings.Contains(text, "(2/2)") {
+		t.Errorf("ToString() = %s", text)
+	}
+
+	if _, err := response.CacheReportPack(nil, response.SharedCache); err == nil {
+		t.Errorf("CacheReportPack(nil) error = nil")
+	}
+}
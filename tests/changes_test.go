@@ -0,0 +1,169 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func addPolledRound(t *testing.T, pack response.Pack, status codes.StatusCode, body string, headers map[string]string) {
+	t.Helper()
+	resp := newTestResponse(t, "https://api.example.com/feed", "api.example.com", codes.GET, status, headers, body)
+	if err := pack.AddResponse(resp); err != nil {
+		t.Fatalf("AddResponse() error = %v", err)
+	}
+}
+
+func TestTrackURLChangesETag(t *testing.T) {
+	pack := response.NewResponsePack()
+	addPolledRound(t, pack, codes.OK, "v1", map[string]string{"ETag": `"1"`})
+	addPolledRound(t, pack, codes.NotModified, "", map[string]string{"ETag": `"1"`})
+	addPolledRound(t, pack, codes.OK, "v1", map[string]string{"ETag": `W/"1"`})
+	addPolledRound(t, pack, codes.OK, "v2", map[string]string{"ETag": `"2"`})
+	addPolledRound(t, pack, codes.OK, "v3", map[string]string{"ETag": `"2"`})
+	addPolledRound(t, pack, codes.OK, "v3", map[string]string{"ETag": `"3"`})
+
+	changes, err := response.TrackURLChanges(pack, "https://api.example.com/feed")
+	if err != nil {
+		t.Fatalf("TrackURLChanges() error = %v", err)
+	}
+
+	if changes.Rounds != 6 || changes.Changes != 2 {
+		t.Fatalf("changes = %+v, want 6 rounds and 2 changes", changes)
+	}
+	if changes.Events[0].Round != 4 || changes.Events[0].Reason != response.ChangedETag || changes.Events[0].Before.ETag != `W/"1"` {
+		t.Errorf("first event = %+v, want ETag change on round 4", changes.Events[0])
+	}
+
+	if len(changes.Issues) != 2 {
+		t.Fatalf("Issues = %+v, want stale and unstable ETag", changes.Issues)
+	}
+	if changes.Issues[0].Code != response.ChangeStaleETag || changes.Issues[0].Round != 5 {
+		t.Errorf("first issue = %+v, want stale ETag on round 5", changes.Issues[0])
+	}
+	if changes.Issues[1].Code != response.ChangeUnstableETag || changes.Issues[1].Round != 6 {
+		t.Errorf("second issue = %+v, want unstable ETag on round 6", changes.Issues[1])
+	}
+
+	if changes.Conditional["If-None-Match"] != `"3"` || len(changes.Conditional) != 1 {
+		t.Errorf("Conditional = %v", changes.Conditional)
+	}
+
+	if _, err := response.TrackURLChanges(pack, "https://api.example.com/missing"); err == nil {
+		t.Errorf("TrackURLChanges() on a missing URL error = nil")
+	}
+}
+
+func TestTrackChangesLastModifiedAndBody(t *testing.T) {
+	modified := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	pack := response.NewCompressResponsePack()
+	addPolledRound(t, pack, codes.OK, "a", map[string]string{"Last-Modified": modified.Format(http.TimeFormat)})
+	addPolledRound(t, pack, codes.OK, "b", map[string]string{"Last-Modified": modified.Format(http.TimeFormat)})
+	addPolledRound(t, pack, codes.OK, "c", map[string]string{"Last-Modified": modified.Add(-time.Hour).Format(http.TimeFormat)})
+
+	plain := newTestResponse(t, "https://api.example.com/plain", "api.example.com", codes.GET, codes.OK, nil, "x")
+	_ = pack.AddResponse(plain)
+	plainAgain := plain.Clone()
+	plainAgain.Body = []byte("y")
+	_ = pack.AddResponse(plainAgain)
+
+	report, err := response.TrackChanges(pack)
+	if err != nil {
+		t.Fatalf("TrackChanges() error = %v", err)
+	}
+	if len(report.Urls) != 2 {
+		t.Fatalf("Urls = %+v", report.Urls)
+	}
+
+	feed := report.Urls[0]
+	if feed.Changes != 1 || feed.Events[0].Reason != response.ChangedLastModified {
+		t.Errorf("feed = %+v, want one Last-Modified change", feed)
+	}
+	codesFound := map[string]bool{}
+	for _, issue := range feed.Issues {
+		codesFound[issue.Code] = true
+	}
+	if !codesFound[response.ChangeStaleLastModified] || !codesFound[response.ChangeLastModifiedBack] {
+		t.Errorf("Issues = %+v", feed.Issues)
+	}
+	if feed.Conditional["If-Modified-Since"] != modified.Add(-time.Hour).Format(http.TimeFormat) {
+		t.Errorf("Conditional = %v", feed.Conditional)
+	}
+
+	if plainChanges := report.Urls[1]; plainChanges.Changes != 1 || plainChanges.Events[0].Reason != response.ChangedBody {
+		t.Errorf("plain = %+v, want one body change", plainChanges)
+	}
+
+	data, err := report.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report JSON is invalid: %v", err)
+	}
+	if text := report.ToString(); !strings.Contains(text, "(3 rounds, 1 changes)") || !strings.Contains(text, "round 2 stale-last-modified") {
+		t.Errorf("ToString() = %s", text)
+	}
+}
+
+func TestTrackChangesRoundNumbers(t *testing.T) {
+	pack := response.NewResponsePack()
+	addPolledRound(t, pack, codes.OK, "v1", nil)
+	addPolledRound(t, pack, codes.OK, "v1", nil)
+	addPolledRound(t, pack, codes.OK, "v2", nil)
+	if err := pack.DeleteRound("https://api.example.com/feed", 1); err != nil {
+		t.Fatalf("DeleteRound() error = %v", err)
+	}
+
+	report, err := response.TrackChanges(pack)
+	if err != nil || len(report.Urls) != 1 {
+		t.Fatalf("TrackChanges() = %+v, %v", report, err)
+	}
+	if events := report.Urls[0].Events; len(events) != 1 || events[0].Round != 3 {
+		t.Errorf("Events = %+v, want a change on round 3", events)
+	}
+}
+
+func TestTrackURLChangesWeakETag(t *testing.T) {
+	pack := response.NewResponsePack()
+	addPolledRound(t, pack, codes.OK, `{"a":1,"b":2}`, map[string]string{"ETag": `W/"1"`})
+	addPolledRound(t, pack, codes.OK, `{"b":2,"a":1}`, map[string]string{"ETag": `W/"1"`})
+	addPolledRound(t, pack, codes.OK, `{"a":2}`, map[string]string{"ETag": `"2"`})
+	addPolledRound(t, pack, codes.OK, `{"a":3}`, map[string]string{"ETag": `"2"`})
+	_ = pack.DeleteRound("https://api.example.com/feed", 1)
+
+	changes, err := response.TrackURLChanges(pack, "https://api.example.com/feed")
+	if err != nil {
+		t.Fatalf("TrackURLChanges() error = %v", err)
+	}
+	if len(changes.Issues) != 1 || changes.Issues[0].Code != response.ChangeStaleETag || changes.Issues[0].Round != 4 {
+		t.Errorf("Issues = %+v, want only the strong ETag reported stale on round 4", changes.Issues)
+	}
+	if len(changes.Events) != 1 || changes.Events[0].Round != 3 {
+		t.Errorf("Events = %+v, want the ETag change on round 3", changes.Events)
+	}
+}
+
+func TestResponseConditionalHeaders(t *testing.T) {
+	resp, _ := response.NewResponse("https://api.example.com/x", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"etag":          `"abc"`,
+		"Last-Modified": "Sun, 01 Mar 2026 08:00:00 GMT",
+	}, nil, 0, nil)
+
+	headers := resp.ConditionalHeaders()
+	if headers["If-None-Match"] != `"abc"` || headers["If-Modified-Since"] != "Sun, 01 Mar 2026 08:00:00 GMT" {
+		t.Errorf("ConditionalHeaders() = %v", headers)
+	}
+
+	bare, _ := response.NewResponse("https://api.example.com/x", "api.example.com", codes.GET, codes.OK, nil, nil, 0, nil)
+	if headers := bare.ConditionalHeaders(); len(headers) != 0 {
+		t.Errorf("ConditionalHeaders() = %v, want none", headers)
+	}
+}
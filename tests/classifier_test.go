@@ -0,0 +1,88 @@
+package response_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func TestBuiltinClassifiers(t *testing.T) {
+	ok := newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.OK, nil, `{"status":"ok"}`)
+	notModified := newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.NotModified, nil, ``)
+	notFound := newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.NotFound, nil, ``)
+	failedBody := newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.OK, nil, `{"status":"error"}`)
+
+	tests := []struct {
+		name       string
+		classifier response.Classifier
+		want       []response.Outcome
+	}{
+		{"2xx", response.SuccessClassifier(), []response.Outcome{response.OutcomeSuccess, response.OutcomeFailure, response.OutcomeFailure, response.OutcomeSuccess}},
+		{"2xx+3xx", response.SuccessOrRedirectClassifier(), []response.Outcome{response.OutcomeSuccess, response.OutcomeSuccess, response.OutcomeFailure, response.OutcomeSuccess}},
+		{"allow-list", response.StatusAllowListClassifier(codes.NotModified, codes.NotFound), []response.Outcome{response.OutcomeFailure, response.OutcomeSuccess, response.OutcomeSuccess, response.OutcomeFailure}},
+		{"body", response.BodyPredicateClassifier(nil, func(body []byte) bool {
+			return !bytes.Contains(body, []byte(`"error"`))
+		}), []response.Outcome{response.OutcomeSuccess, response.OutcomeFailure, response.OutcomeFailure, response.OutcomeFailure}},
+		{"ignore", response.IgnoreStatusClassifier(nil, codes.NotFound), []response.Outcome{response.OutcomeSuccess, response.OutcomeFailure, response.OutcomeIgnored, response.OutcomeSuccess}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for index, resp := range []*response.Response{ok, notModified, notFound, failedBody} {
+				if got := tt.classifier.Classify(resp); got != tt.want[index] {
+					t.Errorf("Classify(response %d) = %s, want %s", index, got, tt.want[index])
+				}
+			}
+		})
+	}
+}
+
+func TestResponsePackClassifier(t *testing.T) {
+	pack := response.NewResponsePackFromConfig(response.ConfigResponsePack{
+		Classifier: response.IgnoreStatusClassifier(response.SuccessOrRedirectClassifier(), codes.NotFound),
+		Info:       map[string]string{"env": "test"},
+	})
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.OK, nil, ``))
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.NotModified, nil, ``))
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/b", "example.com", codes.GET, codes.NotFound, nil, ``))
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/c", "example.com", codes.GET, codes.InternalServerError, nil, ``))
+
+	if pack.Total != 4 || pack.Success != 2 || pack.Failure != 1 || pack.Ignored != 1 {
+		t.Errorf("counters = %d/%d/%d/%d, want total 4, success 2, failure 1, ignored 1", pack.Total, pack.Success, pack.Failure, pack.Ignored)
+	}
+	if pack.SuccessRatio != 2.0/3.0 || pack.FailureRatio != 1.0/3.0 {
+		t.Errorf("ratios = %f/%f, want them over the 3 classified rounds", pack.SuccessRatio, pack.FailureRatio)
+	}
+	if pack.Info["env"] != "test" || !strings.Contains(pack.ToString(), "Ignored: 1") {
+		t.Errorf("ToString() = %s", pack.ToString())
+	}
+
+	report, err := pack.GetErrorReport()
+	if err != nil {
+		t.Fatalf("GetErrorReport() error = %v", err)
+	}
+	if len(report) != 1 || report["https://example.com/c"] == nil {
+		t.Errorf("GetErrorReport() = %v, want only /c", report)
+	}
+
+	// Recompute the stored rounds under the default classifier
+	pack.SetClassifier(nil)
+	if pack.Total != 4 || pack.Success != 1 || pack.Failure != 3 || pack.Ignored != 0 || pack.SuccessRatio != 0.25 {
+		t.Errorf("after SetClassifier(nil) = %d/%d/%d/%d, ratio %f", pack.Total, pack.Success, pack.Failure, pack.Ignored, pack.SuccessRatio)
+	}
+
+	pack.SetClassifier(response.StatusAllowListClassifier(codes.NotFound))
+	if pack.Success != 1 || pack.Failure != 3 || pack.FailureRatio != 0.75 || pack.Classifier() == nil {
+		t.Errorf("after SetClassifier() = %d/%d, ratio %f", pack.Success, pack.Failure, pack.FailureRatio)
+	}
+
+	// Rounds deleted after the change are uncounted under the classifier that counted them
+	_ = pack.DeleteResponse("https://example.com/c")
+	report, _ = pack.GetErrorReport()
+	if pack.Total != 3 || pack.Success+pack.Failure+pack.Ignored != pack.Total || report["https://example.com/c"] != nil {
+		t.Errorf("after DeleteResponse() = %d/%d/%d of %d, report %v", pack.Success, pack.Failure, pack.Ignored, pack.Total, report)
+	}
+}
@@ -0,0 +1,151 @@
+package response_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func corsCodes(result *response.CORSResult) map[string]bool {
+	output := map[string]bool{}
+	for _, issue := range result.Issues {
+		output[issue.Code] = true
+	}
+	return output
+}
+
+func TestAnalyzeCORSAllowed(t *testing.T) {
+	resp := newTestResponse(t, "https://api.example.com/items", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Vary":                             "Accept-Encoding, Origin",
+	}, "")
+
+	result := response.AnalyzeCORS(resp, response.CORSOptions{Origin: "https://app.example.com", Credentials: true})
+	if !result.CORS || !result.Allowed || len(result.Issues) != 0 {
+		t.Errorf("AnalyzeCORS() = %+v, want allowed without issues", result)
+	}
+
+	plain := response.AnalyzeCORS(newTestResponse(t, "https://api.example.com/items", "api.example.com", codes.GET, codes.OK, nil, ""), response.CORSOptions{})
+	if plain.CORS {
+		t.Errorf("CORS = true for a same-origin response")
+	}
+}
+
+func TestAnalyzeCORSWildcardWithCredentials(t *testing.T) {
+	resp := newTestResponse(t, "https://api.example.com/me", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"Access-Control-Allow-Origin":      "*",
+		"Access-Control-Allow-Credentials": "true",
+	}, "")
+
+	anonymous := response.AnalyzeCORS(resp, response.CORSOptions{Origin: "https://evil.example"})
+	if !anonymous.Allowed || !corsCodes(anonymous)[response.CORSWildcardCredentials] {
+		t.Errorf("anonymous result = %+v, want allowed with wildcard warning", anonymous)
+	}
+
+	resp.Request = &response.RecordedRequest{Headers: map[string]string{
+		"Origin": "https://evil.example",
+		"Cookie": "session=1",
+	}}
+	credentialed := response.AnalyzeCORS(resp, response.CORSOptions{})
+	if credentialed.Allowed || !credentialed.Credentials || credentialed.Origin != "https://evil.example" {
+		t.Errorf("credentialed result = %+v, want rejected request taken from Request", credentialed)
+	}
+	if len(credentialed.Issues) != 1 {
+		t.Errorf("Issues = %+v, want a single wildcard issue", credentialed.Issues)
+	}
+}
+
+func TestAnalyzeCORSOriginAndVary(t *testing.T) {
+	resp := newTestResponse(t, "https://api.example.com/items", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"Access-Control-Allow-Origin": "https://app.example.com",
+	}, "")
+
+	result := response.AnalyzeCORS(resp, response.CORSOptions{Origin: "https://other.example.com"})
+	issues := corsCodes(result)
+	if result.Allowed || !issues[response.CORSOriginMismatch] || !issues[response.CORSMissingVaryOrigin] {
+		t.Errorf("AnalyzeCORS() = %+v, want origin mismatch and missing Vary", result)
+	}
+
+	credentials := response.AnalyzeCORS(resp, response.CORSOptions{Origin: "https://app.example.com", Credentials: true})
+	if credentials.Allowed || !corsCodes(credentials)[response.CORSCredentialsNotAllowed] {
+		t.Errorf("AnalyzeCORS() = %+v, want credentials not allowed", credentials)
+	}
+}
+
+func TestAnalyzeCORSPreflight(t *testing.T) {
+	resp := newTestResponse(t, "https://api.example.com/items", "api.example.com", codes.OPTIONS, codes.NoContent, map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, PUT",
+		"Access-Control-Allow-Headers": "Content-Type",
+		"Vary":                         "Origin",
+	}, "")
+
+	ok := response.AnalyzeCORS(resp, response.CORSOptions{
+		Origin:         "https://app.example.com",
+		RequestMethod:  "PUT",
+		RequestHeaders: []string{"content-type"},
+	})
+	if !ok.Preflight || !ok.Allowed {
+		t.Errorf("AnalyzeCORS() = %+v, want accepted preflight", ok)
+	}
+
+	bad := response.AnalyzeCORS(resp, response.CORSOptions{
+		Origin:         "https://app.example.com",
+		RequestMethod:  "DELETE",
+		RequestHeaders: []string{"Content-Type", "X-Trace"},
+	})
+	issues := corsCodes(bad)
+	if bad.Allowed || !issues[response.CORSPreflightMethod] || !issues[response.CORSPreflightHeaders] {
+		t.Errorf("AnalyzeCORS() = %+v, want method and header mismatch", bad)
+	}
+}
+
+func TestAnalyzeCORSPack(t *testing.T) {
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newTestResponse(t, "https://api.example.com/a", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"Access-Control-Allow-Origin": "*",
+	}, ""))
+	_ = pack.AddResponse(newTestResponse(t, "https://api.example.com/b", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"Access-Control-Allow-Origin": "https://app.example.com",
+	}, ""))
+	_ = pack.AddResponse(newTestResponse(t, "https://api.example.com/c", "api.example.com", codes.GET, codes.OK, nil, ""))
+
+	report, err := response.AnalyzeCORSPack(pack, response.CORSOptions{Origin: "https://app.example.com"})
+	if err != nil {
+		t.Fatalf("AnalyzeCORSPack() error = %v", err)
+	}
+	if len(report.Hosts) != 1 {
+		t.Fatalf("Hosts = %+v, want one host", report.Hosts)
+	}
+
+	host := report.Hosts[0]
+	if host.Responses != 3 || host.CrossOrigin != 3 || host.Allowed != 2 || host.Rejected != 1 {
+		t.Errorf("summary = %+v, want 3 responses, 2 allowed, 1 rejected", host)
+	}
+	if host.Issues[response.CORSMissingVaryOrigin] != 1 || host.Issues[response.CORSMissingAllowOrigin] != 1 {
+		t.Errorf("Issues = %v", host.Issues)
+	}
+	if len(host.Results) != 2 || host.Results[0].Url != "https://api.example.com/b" {
+		t.Errorf("Results = %+v, want /b and /c", host.Results)
+	}
+
+	data, err := report.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report JSON is invalid: %v", err)
+	}
+	if text := report.ToString(); !strings.Contains(text, "Host: api.example.com") || !strings.Contains(text, "missing-vary-origin: 1") {
+		t.Errorf("ToString() = %s", text)
+	}
+
+	if _, err := response.AnalyzeCORSPack(nil, response.CORSOptions{}); err == nil {
+		t.Errorf("AnalyzeCORSPack(nil) error = nil")
+	}
+}
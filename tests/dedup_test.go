@@ -0,0 +1,232 @@
+package response_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+)
+
+// newPolledResponse returns the raw response of a poll of path, whose head changes
+// with n and whose body is body.
+func newPolledResponse(t *testing.T, path string, n int, body string) *response.Response {
+	t.Helper()
+	raw := fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nX-Poll: %d\r\nContent-Length: %d\r\n\r\n%s", n, len(body), body)
+	return parseTestResponse(t, raw, "https://example.com"+path)
+}
+
+func TestResponsePackDedupBodies(t *testing.T) {
+	pack := response.NewResponsePackFromConfig(response.ConfigResponsePack{DedupBodies: true})
+	status := `{"status":"pending","progress":50}`
+	var added []*response.Response
+	for i := 0; i < 5; i++ {
+		resp := newPolledResponse(t, "/job", i, status)
+		added = append(added, resp)
+		_ = pack.AddResponse(resp)
+	}
+	_ = pack.AddResponse(newPolledResponse(t, "/job", 5, `{"status":"done"}`))
+
+	want := response.DedupStats{Blobs: 2, References: 6, UniqueBytes: int64(len(status) + 17), ReferencedBytes: int64(5*len(status) + 17), StoredBytes: int64(len(status) + 17)}
+	if got := pack.Dedup(); got != want {
+		t.Errorf("Dedup() = %+v, want %+v", got, want)
+	}
+	if got := pack.GetStats().Dedup; got != want || !strings.Contains(pack.ToString(), "Dedup: 2 blobs, 6 references") {
+		t.Errorf("Stats.Dedup = %+v, ToString() = %q", got, pack.ToString())
+	}
+	if ratio := pack.Dedup().Ratio(); ratio <= 3 {
+		t.Errorf("Ratio() = %f, want above 3", ratio)
+	}
+
+	// The rounds share one body, the caller's responses keep their own
+	history, _ := pack.History("https://example.com/job")
+	if &history[0].Response.Body[0] != &history[4].Response.Body[0] || history[0].Response.ReadBody() != status {
+		t.Errorf("rounds do not share their body")
+	}
+	if &added[0].Body[0] == &history[0].Response.Body[0] || !added[0].BodyShared() {
+		t.Errorf("AddResponse() changed the caller's response")
+	}
+	// Appending to a shared body copies it
+	extended := append(history[0].Response.Body, '!')
+	if &extended[0] == &history[0].Response.Body[0] || history[4].Response.ReadBody() != status {
+		t.Errorf("append() wrote into the shared body")
+	}
+
+	_ = pack.DeleteRound("https://example.com/job", 1)
+	_ = pack.ReplaceRound("https://example.com/job", 2, newPolledResponse(t, "/job", 2, `{"status":"done"}`))
+	if got := pack.Dedup(); got.Blobs != 2 || got.References != 5 || got.ReferencedBytes != int64(3*len(status)+2*17) {
+		t.Errorf("Dedup() after changes = %+v", got)
+	}
+	pack.Calculate()
+	if got := pack.Dedup(); got.References != 5 {
+		t.Errorf("Dedup() after Calculate() = %+v", got)
+	}
+
+	_ = pack.DeleteResponse("https://example.com/job")
+	if got := pack.Dedup(); got != (response.DedupStats{}) {
+		t.Errorf("Dedup() after DeleteResponse() = %+v, want zero", got)
+	}
+	_ = pack.AddResponse(newPolledResponse(t, "/job", 6, status))
+	pack.Clear()
+	if got := pack.Dedup(); got != (response.DedupStats{}) {
+		t.Errorf("Dedup() after Clear() = %+v, want zero", got)
+	}
+}
+
+func TestResponsePackDedupBodiesKeepsRaw(t *testing.T) {
+	pack := response.NewResponsePackFromConfig(response.ConfigResponsePack{DedupBodies: true})
+	body := strings.Repeat(`{"status":"pending"}`, 20)
+	var raws []string
+	var logical int64
+	for i := 0; i < 4; i++ {
+		resp := newPolledResponse(t, "/job", i, body)
+		raws = append(raws, resp.ReadRawResponse())
+		logical += resp.Size()
+		_ = pack.AddResponse(resp)
+	}
+
+	history, _ := pack.History("https://example.com/job")
+	for i, round := range history {
+		if !round.Response.BodyShared() || round.Response.ReadRawResponse() != raws[i] {
+			t.Fatalf("round %d BodyShared() = %v, raw %q", round.Seq, round.Response.BodyShared(), round.Response.ReadRawResponse())
+		}
+	}
+	if &history[0].Response.Body[0] != &history[3].Response.Body[0] {
+		t.Errorf("rounds do not share their body")
+	}
+
+	// Each round stores its head and its body once, see Dedup for the blobs
+	usage := pack.Memory()
+	if want := logical - int64(4*len(body)); usage.Logical != logical || usage.Stored != want {
+		t.Errorf("Memory() = %+v, want logical %d and stored %d", usage, logical, want)
+	}
+
+	// Clones and redacted copies carry the whole raw message
+	clone := history[1].Response.Clone()
+	if !clone.BodyShared() || clone.ReadRawResponse() != raws[1] || &clone.Body[0] == &history[1].Response.Body[0] {
+		t.Errorf("Clone() raw %q", clone.ReadRawResponse())
+	}
+	if redacted := response.DefaultRedactionPolicy().Redact(history[0].Response); redacted.ReadRawResponse() != raws[0] {
+		t.Errorf("Redact() raw %q", redacted.ReadRawResponse())
+	}
+	data, _ := history[2].Response.ToJSON()
+	decoded, err := response.NewResponseFromJSON(data)
+	if err != nil || decoded.ReadRawResponse() != raws[2] {
+		t.Errorf("ToJSON() round trip error = %v, want raw %q", err, raws[2])
+	}
+}
+
+func TestCompressResponsePackDedupBodies(t *testing.T) {
+	deduped := response.NewCompressResponsePackFromConfig(response.ConfigCompressResponsePack{DedupBodies: true})
+	plain := response.NewCompressResponsePack()
+	body := strings.Repeat(`{"id":1,"name":"item","tags":["a","b"]},`, 50)
+	var raws []string
+	for i := 0; i < 10; i++ {
+		resp := newPolledResponse(t, "/items", i, body)
+		raws = append(raws, resp.ReadRawResponse())
+		_ = deduped.AddResponse(resp)
+		_ = plain.AddResponse(resp)
+	}
+	_ = deduped.AddResponse(newPolledResponse(t, "/other", 0, "{}"))
+
+	got := deduped.Dedup()
+	if got.Blobs != 2 || got.References != 11 || got.StoredBytes <= 0 || got.StoredBytes >= got.UniqueBytes {
+		t.Errorf("Dedup() = %+v", got)
+	}
+	if plain.Dedup() != (response.DedupStats{}) {
+		t.Errorf("Dedup() without deduplication = %+v", plain.Dedup())
+	}
+	if dedupedKey, _ := deduped.KeyMemory("https://example.com/items"); dedupedKey.Stored >= plain.Memory().Stored {
+		t.Errorf("deduplicated rounds store %d B, plain rounds %d B", dedupedKey.Stored, plain.Memory().Stored)
+	}
+	if !strings.Contains(deduped.ToString(), "Dedup: 2 blobs, 11 references") {
+		t.Errorf("ToString() = %q", deduped.ToString())
+	}
+
+	// Reads restore the body and the raw message
+	history, err := deduped.History("https://example.com/items")
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	for i, round := range history {
+		if round.Response.ReadBody() != body || round.Response.ReadRawResponse() != raws[i] || !round.Response.BodyShared() {
+			t.Fatalf("round %d = %q, raw %q", round.Seq, round.Response.Body, round.Response.RawResponse)
+		}
+	}
+	latest, _ := deduped.Latest("https://example.com/items")
+	byRound, _ := deduped.GetRound("https://example.com/items", 3)
+	all, _ := deduped.GetResponse("https://example.com/items")
+	if latest.ReadRawResponse() != raws[9] || byRound.ReadRawResponse() != raws[2] || len(all) != 10 || all[0].ReadBody() != body {
+		t.Errorf("Latest(), GetRound() or GetResponse() lost the shared body")
+	}
+
+	_ = deduped.DeleteResponse("https://example.com/items")
+	if got := deduped.Dedup(); got.Blobs != 1 || got.References != 1 || got.UniqueBytes != 2 {
+		t.Errorf("Dedup() after DeleteResponse() = %+v", got)
+	}
+	deduped.Clear()
+	if got := deduped.Dedup(); got != (response.DedupStats{}) {
+		t.Errorf("Dedup() after Clear() = %+v, want zero", got)
+	}
+}
+
+func TestDedupBodiesEvictions(t *testing.T) {
+	var evicted []*response.Response
+	limits := response.PackLimits{MaxRoundsPerKey: 2, OnEvict: func(eviction response.Eviction) {
+		evicted = append(evicted, eviction.Response)
+	}}
+	packs := []response.Pack{
+		response.NewResponsePackFromConfig(response.ConfigResponsePack{DedupBodies: true, Limits: limits}),
+		response.NewCompressResponsePackFromConfig(response.ConfigCompressResponsePack{DedupBodies: true, Limits: limits}),
+	}
+	for _, pack := range packs {
+		evicted = nil
+		for i := 0; i < 4; i++ {
+			_ = pack.AddResponse(newPolledResponse(t, "/job", i, `{"status":"pending"}`))
+		}
+		if len(evicted) != 2 || evicted[1].ReadBody() != `{"status":"pending"}` || !strings.Contains(evicted[1].ReadRawResponse(), "X-Poll: 1") {
+			t.Errorf("%T evicted %v", pack, evicted)
+		}
+		var stats response.DedupStats
+		switch typed := pack.(type) {
+		case *response.ResponsePack:
+			stats = typed.Dedup()
+		case *response.CompressResponsePack:
+			stats = typed.Dedup()
+		}
+		if stats.Blobs != 1 || stats.References != 2 {
+			t.Errorf("%T Dedup() = %+v after evictions", pack, stats)
+		}
+	}
+}
+
+func TestDedupBodiesConcurrency(t *testing.T) {
+	plain := response.NewResponsePackFromConfig(response.ConfigResponsePack{DedupBodies: true})
+	compressed := response.NewCompressResponsePackFromConfig(response.ConfigCompressResponsePack{DedupBodies: true})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				path := fmt.Sprintf("/k%d", w)
+				resp := newPolledResponse(t, path, i, fmt.Sprintf(`{"value":%d}`, i%3))
+				_ = plain.AddResponse(resp)
+				_ = compressed.AddResponse(resp)
+				_, _ = compressed.Latest("https://example.com" + path)
+				if i == 25 {
+					_ = plain.DeleteResponse("https://example.com" + path)
+					_ = compressed.DeleteResponse("https://example.com" + path)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, stats := range []response.DedupStats{plain.Dedup(), compressed.Dedup()} {
+		if stats.Blobs != 3 || stats.References != 4*24 {
+			t.Errorf("Dedup() = %+v, want 3 blobs and 96 references", stats)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package response_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func TestParseExpression(t *testing.T) {
+	pack := response.NewResponsePack()
+	for _, resp := range newQueryFixtures(t) {
+		_ = pack.AddResponse(resp)
+	}
+	pack.Responses["https://cdn.example.com/app.js"]["round_1"].Timing = nil
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`status>=500 AND host="api.internal" AND header[Content-Type]~"json"`, "[https://api.internal/orders#1 https://api.internal/users#1]"},
+		{`status=5xx && method=post`, "[https://api.internal/orders#1 https://api.internal/users#1]"},
+		{`status = 2XX OR tag = smoke`, "[https://api.internal/orders#1 https://api.internal/orders#2 https://api.internal/orders#3]"},
+		{`NOT (host = API.INTERNAL) `, "[https://cdn.example.com/app.js#1]"},
+		{`!host=api.internal || status==201`, "[https://api.internal/orders#2 https://cdn.example.com/app.js#1]"},
+		{`url ~ "/u" and body ~ 'time\\s?out'`, "[https://api.internal/users#1]"},
+		{`size < 3`, "[https://api.internal/orders#3]"},
+		{`latency >= 10ms`, "[https://api.internal/orders#1 https://api.internal/users#1]"},
+		{`time >= "2026-05-01T12:30:00Z"`, "[https://api.internal/orders#3 https://api.internal/users#1]"},
+		{`time < "2026-05-01T13:00:00Z" AND NOT tag=smoke`, "[https://api.internal/orders#2]"},
+		{`header[x-missing] != "a"`, "[]"},
+		{`json.id = 1`, "[https://api.internal/orders#2]"},
+		{`json.error ~ "^up" OR json.error = "db"`, "[https://api.internal/orders#1 https://api.internal/users#1]"},
+		{`json.id > 0.5 AND json.id != "2"`, "[https://api.internal/orders#2]"},
+		{`tag !~ smo`, "[https://api.internal/orders#2 https://api.internal/users#1 https://cdn.example.com/app.js#1]"},
+	}
+	for _, tt := range tests {
+		expression, err := response.ParseExpression(tt.source)
+		if err != nil {
+			t.Fatalf("ParseExpression(%q) error = %v", tt.source, err)
+		}
+		results, err := pack.Query(response.Query{Where: []response.Predicate{expression.Predicate()}})
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		if got := queryRounds(results); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		offset int
+	}{
+		{`status >= `, 10},
+		{`status >= 500 AND`, 17},
+		{`(status = 200`, 13},
+		{`colour = red`, 0},
+		{`status ~ 500`, 7},
+		{`status = 5..`, 9},
+		{`latency > 10`, 10},
+		{`time > yesterday`, 7},
+		{`body > "a"`, 5},
+		{`url ~ "("`, 6},
+		{`header Content-Type = x`, 7},
+		{`host = "api`, 7},
+		{`status = 200 200`, 13},
+		{`status # 200`, 7},
+		{`json..a = 1`, 8},
+	}
+	for _, tt := range tests {
+		_, err := response.ParseExpression(tt.source)
+		var expressionErr *response.ExpressionError
+		if !errors.As(err, &expressionErr) {
+			t.Errorf("ParseExpression(%q) error = %v, want an ExpressionError", tt.source, err)
+			continue
+		}
+		if expressionErr.Offset != tt.offset {
+			t.Errorf("ParseExpression(%q) offset = %d, want %d (%v)", tt.source, expressionErr.Offset, tt.offset, err)
+		}
+	}
+}
+
+func TestExpressionCompressResponsePack(t *testing.T) {
+	pack := response.NewCompressResponsePack()
+	for _, resp := range newQueryFixtures(t) {
+		_ = pack.AddResponse(resp)
+	}
+
+	expression, err := response.ParseExpression(`method = GET AND status = 5xx`)
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+	if expression.String() != `method = GET AND status = 5xx` {
+		t.Errorf("String() = %q", expression.String())
+	}
+	results, err := pack.Query(response.Query{Where: []response.Predicate{expression.Predicate()}})
+	if err != nil || queryRounds(results) != "[https://cdn.example.com/app.js#1]" {
+		t.Errorf("Query() = %s, %v", queryRounds(results), err)
+	}
+	if !expression.Match(results[0].Response) {
+		t.Errorf("Match() = false on a selected response")
+	}
+	if expression.Match(&response.Response{Method: codes.GET, StatusCode: codes.OK}) {
+		t.Errorf("Match() = true on a 200")
+	}
+}
@@ -0,0 +1,33 @@
+package response_test
+
+import (
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// Shared fixtures
+// ----------------------------------------------------------------------
+
+// newTestResponse builds a response for a test fixture, failing the test if
+// NewResponse rejects it. The body length is taken from body.
+func newTestResponse(t *testing.T, url string, host string, method codes.Method, status codes.StatusCode, headers map[string]string, body string) *response.Response {
+	t.Helper()
+	resp, err := response.NewResponse(url, host, method, status, headers, []byte(body), uint64(len(body)), nil)
+	if err != nil {
+		t.Fatalf("NewResponse() error = %v", err)
+	}
+	return resp
+}
+
+// parseTestResponse parses a raw HTTP response for a test fixture, failing
+// the test if it cannot be parsed.
+func parseTestResponse(t *testing.T, raw string, url string) *response.Response {
+	t.Helper()
+	resp, err := response.ParseStringHTTPResponse(raw, url)
+	if err != nil {
+		t.Fatalf("ParseStringHTTPResponse() error = %v", err)
+	}
+	return resp
+}
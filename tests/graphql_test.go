@@ -0,0 +1,99 @@
+package response_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+const graphqlURL = "https://api.example.com/graphql"
+
+const graphQLPartial = `{
+	"data": {"hero": {"name": "R2-D2", "friends": [{"name": "Luke"}, null]}},
+	"errors": [
+		{"message": "Name for character with ID 1002 could not be fetched.", "locations": [{"line": 6, "column": 7}], "path": ["hero", "friends", 1, "name"], "extensions": {"code": "NOT_FOUND"}}
+	],
+	"extensions": {"cost": 3}
+}`
+
+func TestResponseGraphQL(t *testing.T) {
+	graphQL, err := newTestResponse(t, graphqlURL, "api.example.com", codes.POST, codes.OK, map[string]string{"Content-Type": "application/json"}, graphQLPartial).GraphQL()
+	if err != nil {
+		t.Fatalf("GraphQL() error = %v", err)
+	}
+	if !graphQL.Failed() || !graphQL.Partial() || graphQL.Extensions["cost"] != float64(3) {
+		t.Errorf("GraphQL() = %+v, want a partial success", graphQL)
+	}
+
+	graphQLError := graphQL.Errors[0]
+	if graphQLError.PathString() != "hero.friends.1.name" || graphQLError.Locations[0].Line != 6 || graphQLError.Extensions["code"] != "NOT_FOUND" {
+		t.Errorf("error = %+v", graphQLError)
+	}
+	if !strings.HasSuffix(graphQLError.String(), "(path: hero.friends.1.name)") {
+		t.Errorf("String() = %q", graphQLError.String())
+	}
+
+	failed, err := newTestResponse(t, graphqlURL, "api.example.com", codes.POST, codes.OK, map[string]string{"Content-Type": "application/json"}, `{"data": null, "errors": [{"message": "boom"}]}`).GraphQL()
+	if err != nil || !failed.Failed() || failed.Partial() || failed.Errors[0].String() != "boom" {
+		t.Errorf("GraphQL() = %+v, %v, want a total failure", failed, err)
+	}
+
+	ok := newTestResponse(t, graphqlURL, "api.example.com", codes.POST, codes.OK, map[string]string{"Content-Type": "application/json"}, `{"data": {"ping": "pong"}}`)
+	if ok.HasGraphQLErrors() {
+		t.Errorf("HasGraphQLErrors() = true without errors")
+	}
+
+	for _, body := range []string{`[]`, `{"status": "ok"}`, `{`, ``} {
+		if _, err := newTestResponse(t, graphqlURL, "api.example.com", codes.POST, codes.OK, map[string]string{"Content-Type": "application/json"}, body).GraphQL(); err == nil {
+			t.Errorf("GraphQL(%q) error = nil", body)
+		}
+	}
+}
+
+func TestResponsePackGraphQLErrorsAsFailure(t *testing.T) {
+	lenient := response.NewResponsePack()
+	_ = lenient.AddResponse(newTestResponse(t, graphqlURL, "api.example.com", codes.POST, codes.OK, map[string]string{"Content-Type": "application/json"}, graphQLPartial))
+	if lenient.Success != 1 || lenient.GraphQLErrorsAsFailure() {
+		t.Errorf("default pack Success = %d, GraphQLErrorsAsFailure() = %v, want 1 and false", lenient.Success, lenient.GraphQLErrorsAsFailure())
+	}
+
+	// Enabling the option recounts the stored rounds
+	lenient.SetGraphQLErrorsAsFailure(true)
+	if !lenient.GraphQLErrorsAsFailure() || lenient.Success != 0 || lenient.Failure != 1 || lenient.FailureRatio != 1 {
+		t.Errorf("after SetGraphQLErrorsAsFailure(true) Success = %d, Failure = %d, FailureRatio = %v", lenient.Success, lenient.Failure, lenient.FailureRatio)
+	}
+
+	pack := response.NewResponsePack()
+	pack.SetGraphQLErrorsAsFailure(true)
+	_ = pack.AddResponse(newTestResponse(t, graphqlURL, "api.example.com", codes.POST, codes.OK, map[string]string{"Content-Type": "application/json"}, `{"data": {"ping": "pong"}}`))
+	_ = pack.AddResponse(newTestResponse(t, graphqlURL, "api.example.com", codes.POST, codes.OK, map[string]string{"Content-Type": "application/json"}, graphQLPartial))
+	_ = pack.AddResponse(newTestResponse(t, graphqlURL, "api.example.com", codes.POST, codes.BadRequest, map[string]string{"Content-Type": "application/json"}, `{"errors": [{"message": "Syntax Error"}]}`))
+
+	if pack.Success != 1 || pack.Failure != 2 {
+		t.Errorf("Success = %d, Failure = %d, want 1 and 2", pack.Success, pack.Failure)
+	}
+
+	report, err := pack.GetErrorReportEntries()
+	if err != nil {
+		t.Fatalf("GetErrorReportEntries() error = %v", err)
+	}
+	rounds := report["https://api.example.com/graphql"]
+	if len(rounds) != 2 || len(rounds["round_2"].GraphQLErrors) != 1 || rounds["round_3"].GraphQLErrors[0].Message != "Syntax Error" {
+		t.Errorf("GetErrorReportEntries() = %+v", rounds)
+	}
+
+	text, err := pack.GetErrorReportString()
+	if err != nil {
+		t.Fatalf("GetErrorReportString() error = %v", err)
+	}
+	for _, want := range []string{
+		"\tround_2: 200\n\t\t- Name for character with ID 1002 could not be fetched. (path: hero.friends.1.name)\n",
+		"\tround_3: 400\n\t\t- Syntax Error\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("GetErrorReportString() = %q, missing %q", text, want)
+		}
+	}
+}
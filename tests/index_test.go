@@ -0,0 +1,182 @@
+package response_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+var allIndexes = []response.IndexField{response.IndexHost, response.IndexStatus, response.IndexMethod, response.IndexTag}
+
+// newIndexPacks returns the query fixtures in a pack without index and in a pack
+// with every index.
+func newIndexPacks(t *testing.T) (*response.ResponsePack, *response.ResponsePack) {
+	t.Helper()
+	plain := response.NewResponsePack()
+	indexed := response.NewResponsePackFromConfig(response.ConfigResponsePack{Indexes: allIndexes})
+	for _, resp := range newQueryFixtures(t) {
+		_ = plain.AddResponse(resp)
+		_ = indexed.AddResponse(resp)
+	}
+	return plain, indexed
+}
+
+// checkSameQueries runs the same queries on both packs and compares the results.
+func checkSameQueries(t *testing.T, plain, indexed *response.ResponsePack) {
+	t.Helper()
+	wheres := [][]response.Predicate{
+		nil,
+		{response.StatusClass("5xx"), response.MethodIs("post"), response.HostIs("API.internal")},
+		{response.StatusRange(200, 201)},
+		{response.TaggedWith("smoke"), response.MethodIs(codes.GET)},
+		{response.AnyOf(response.HostIs("cdn.example.com"), response.StatusClass("2xx"))},
+		{response.AnyOf(response.HostIs("cdn.example.com"), response.BodyContains("db"))},
+		{response.Not(response.TaggedWith("smoke"))},
+		{response.PredicateFunc(func(resp *response.Response) bool { return len(resp.Body) > 5 })},
+	}
+	for _, source := range []string{
+		`status >= 500 AND host = "API.INTERNAL"`,
+		`status != 5xx OR tag = smoke`,
+		`method = get AND NOT tag = smoke`,
+		`tag ~ "^sm" AND json.error = "db"`,
+		`host ~ "^cdn" OR status = 201`,
+	} {
+		expression, err := response.ParseExpression(source)
+		if err != nil {
+			t.Fatalf("ParseExpression(%q) error = %v", source, err)
+		}
+		wheres = append(wheres, []response.Predicate{expression.Predicate()})
+	}
+
+	for i, where := range wheres {
+		for _, query := range []response.Query{{Where: where}, {Where: where, SortBy: response.SortByTime, Descending: true, Limit: 2}} {
+			want, err := plain.Query(query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			got, err := indexed.Query(query)
+			if err != nil {
+				t.Fatalf("indexed Query() error = %v", err)
+			}
+			if queryRounds(got) != queryRounds(want) {
+				t.Errorf("query %d: indexed = %s, want %s", i, queryRounds(got), queryRounds(want))
+			}
+		}
+	}
+
+	wantReport, wantErr := plain.GetErrorReportString()
+	gotReport, gotErr := indexed.GetErrorReportString()
+	if gotReport != wantReport || (gotErr == nil) != (wantErr == nil) {
+		t.Errorf("indexed error report = %q, want %q", gotReport, wantReport)
+	}
+	for _, host := range []string{"api.internal", "CDN.example.com", "nowhere"} {
+		want, _ := plain.HostStats(host)
+		got, _ := indexed.HostStats(host)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("indexed HostStats(%s) = %+v, want %+v", host, got, want)
+		}
+	}
+}
+
+func TestIndexedQueries(t *testing.T) {
+	plain, indexed := newIndexPacks(t)
+	if got := indexed.Indexes(); !reflect.DeepEqual(got, []response.IndexField{"host", "method", "status", "tag"}) {
+		t.Errorf("Indexes() = %v", got)
+	}
+	checkSameQueries(t, plain, indexed)
+
+	stats, err := indexed.HostStats("api.internal")
+	if err != nil || stats.StatusClasses["5xx"] != 2 || stats.Methods["POST"] != 3 {
+		t.Errorf("HostStats() = %+v, %v", stats, err)
+	}
+}
+
+func TestIndexesFollowChanges(t *testing.T) {
+	plain, indexed := newIndexPacks(t)
+	for _, pack := range []*response.ResponsePack{plain, indexed} {
+		if err := pack.DeleteRound("https://api.internal/orders", 1); err != nil {
+			t.Fatalf("DeleteRound() error = %v", err)
+		}
+		replacement := newQueryResponse(t, "https://api.internal/orders", "api.internal", codes.DELETE, codes.Conflict, `{"error":"busy"}`, 50, "smoke")
+		if err := pack.ReplaceRound("https://api.internal/orders", 2, replacement); err != nil {
+			t.Fatalf("ReplaceRound() error = %v", err)
+		}
+		if err := pack.DeleteResponse("https://cdn.example.com/app.js"); err != nil {
+			t.Fatalf("DeleteResponse() error = %v", err)
+		}
+		_ = pack.AddResponse(newQueryResponse(t, "https://cdn.example.com/app.js", "cdn.example.com", codes.GET, codes.OK, `ok`, 60, "smoke"))
+	}
+	checkSameQueries(t, plain, indexed)
+
+	// A new classifier changes the failed rounds
+	for _, pack := range []*response.ResponsePack{plain, indexed} {
+		pack.SetClassifier(response.StatusAllowListClassifier(codes.Conflict))
+	}
+	checkSameQueries(t, plain, indexed)
+
+	for _, pack := range []*response.ResponsePack{plain, indexed} {
+		pack.Clear()
+	}
+	checkSameQueries(t, plain, indexed)
+}
+
+func TestSetIndexes(t *testing.T) {
+	plain, _ := newIndexPacks(t)
+	indexed, _ := newIndexPacks(t)
+	indexed.SetIndexes(response.IndexStatus, response.IndexTag)
+	checkSameQueries(t, plain, indexed)
+
+	filtered, err := indexed.Filter(response.Query{Where: []response.Predicate{response.StatusClass("5xx")}})
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	if got := fmt.Sprint(filtered.Indexes()); got != "[status tag]" {
+		t.Errorf("filtered Indexes() = %s, want [status tag]", got)
+	}
+
+	indexed.SetIndexes()
+	if len(indexed.Indexes()) != 0 {
+		t.Errorf("Indexes() = %v after SetIndexes(), want none", indexed.Indexes())
+	}
+	checkSameQueries(t, plain, indexed)
+}
+
+func TestIndexedPackConcurrency(t *testing.T) {
+	pack := response.NewResponsePackFromConfig(response.ConfigResponsePack{Indexes: allIndexes})
+	var responses []*response.Response
+	for i := 0; i < 200; i++ {
+		status := codes.OK
+		if i%4 == 0 {
+			status = codes.InternalServerError
+		}
+		responses = append(responses, newQueryResponse(t, fmt.Sprintf("https://h%d.example.com/", i%5), "", codes.GET, status, "x", i))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_, _ = pack.Query(response.Query{Where: []response.Predicate{response.StatusClass("5xx")}})
+			_, _ = pack.HostStats("h1.example.com")
+		}
+	}()
+	if errs := pack.BatchAddResponse(responses); errs != nil {
+		t.Fatalf("BatchAddResponse() errors = %v", errs)
+	}
+	<-done
+
+	results, _ := pack.Query(response.Query{Where: []response.Predicate{response.StatusClass("5xx"), response.HostIs("h0.example.com")}})
+	if len(results) != 10 {
+		t.Errorf("indexed Query() = %d rounds, want 10", len(results))
+	}
+	report, _ := pack.GetErrorReport()
+	failed := 0
+	for _, rounds := range report {
+		failed += len(rounds)
+	}
+	if failed != 50 {
+		t.Errorf("GetErrorReport() = %d rounds, want 50", failed)
+	}
+}
@@ -0,0 +1,277 @@
+package response_test
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// limitPack is the behaviour shared by both packs under limits.
+type limitPack interface {
+	response.Pack
+	Prune()
+	SetLimits(limits response.PackLimits)
+}
+
+// newLimitResponse returns a response of path with a body of size bytes.
+func newLimitResponse(t *testing.T, path string, status codes.StatusCode, size int) *response.Response {
+	t.Helper()
+	body := make([]byte, size)
+	for i := range body {
+		body[i] = 'a' + byte(i%26)
+	}
+	return newTestResponse(t, "https://example.com"+path, "example.com", codes.GET, status, nil, string(body))
+}
+
+// evictionLog collects the evictions of a pack.
+type evictionLog struct {
+	mu        sync.Mutex
+	evictions []string
+}
+
+func (l *evictionLog) record(eviction response.Eviction) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.evictions = append(l.evictions, fmt.Sprintf("%s#%d:%s", eviction.Key[len("https://example.com"):], eviction.Round, eviction.Reason))
+}
+
+func (l *evictionLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return fmt.Sprint(l.evictions)
+}
+
+// packContent formats the stored rounds of pack as "path#round".
+func packContent(t *testing.T, pack response.Pack) string {
+	t.Helper()
+	keys := pack.GetKeysOfResponses()
+	sort.Strings(keys)
+	var out []string
+	for _, key := range keys {
+		history, err := pack.History(key)
+		if err != nil {
+			t.Fatalf("History() error = %v", err)
+		}
+		for _, round := range history {
+			out = append(out, fmt.Sprintf("%s#%d", key[len("https://example.com"):], round.Seq))
+		}
+	}
+	return fmt.Sprint(out)
+}
+
+// newLimitPacks returns a ResponsePack and a CompressResponsePack with limits, and
+// the logs of their evictions.
+func newLimitPacks(limits response.PackLimits) ([]limitPack, []*evictionLog) {
+	plainLog, compressedLog := &evictionLog{}, &evictionLog{}
+	plainLimits, compressedLimits := limits, limits
+	plainLimits.OnEvict = plainLog.record
+	compressedLimits.OnEvict = compressedLog.record
+	packs := []limitPack{
+		response.NewResponsePackFromConfig(response.ConfigResponsePack{Limits: plainLimits}),
+		response.NewCompressResponsePackFromConfig(response.ConfigCompressResponsePack{Limits: compressedLimits}),
+	}
+	return packs, []*evictionLog{plainLog, compressedLog}
+}
+
+func TestPackLimitsKeysAndRounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		limits    response.PackLimits
+		wantPack  string
+		wantEvict string
+	}{
+		{
+			"max rounds per key",
+			response.PackLimits{MaxRoundsPerKey: 2},
+			"[/a#4 /b#1 /c#2 /c#3]",
+			"[/a#1:maxRounds /a#2:maxRounds /c#1:maxRounds]",
+		},
+		{
+			"max keys, LRU",
+			response.PackLimits{MaxKeys: 2, Policy: response.EvictLRU},
+			"[/a#1 /a#2 /a#4 /c#1 /c#2 /c#3]",
+			"[/b#1:maxKeys]",
+		},
+		{
+			"max keys, FIFO",
+			response.PackLimits{MaxKeys: 2, Policy: response.EvictFIFO},
+			"[/b#1 /c#1 /c#2 /c#3]",
+			"[/a#1:maxKeys /a#2:maxKeys /a#4:maxKeys]",
+		},
+		{
+			"max keys, oldest round",
+			response.PackLimits{MaxKeys: 2, Policy: response.EvictOldestRound},
+			"[/b#1 /c#1 /c#2 /c#3]",
+			"[/a#1:maxKeys /a#2:maxKeys /a#4:maxKeys]",
+		},
+	}
+	for _, tt := range tests {
+		packs, logs := newLimitPacks(tt.limits)
+		pack := packs[0].(*response.ResponsePack)
+		_ = pack.AddResponse(newLimitResponse(t, "/a", codes.OK, 10))
+		_ = pack.AddResponse(newLimitResponse(t, "/a", codes.OK, 10))
+		_ = pack.AddResponse(newLimitResponse(t, "/b", codes.OK, 10))
+		// Reading a makes b the least recently used key
+		_, _ = pack.Latest("https://example.com/a")
+		_ = pack.AddResponse(newLimitResponse(t, "/a", codes.OK, 10))
+		_ = pack.AddResponse(newLimitResponse(t, "/a", codes.OK, 10))
+		_ = pack.DeleteRound("https://example.com/a", 3)
+		for i := 0; i < 3; i++ {
+			_ = pack.AddResponse(newLimitResponse(t, "/c", codes.OK, 10))
+		}
+		if got := packContent(t, pack); got != tt.wantPack {
+			t.Errorf("%s: rounds = %s, want %s", tt.name, got, tt.wantPack)
+		}
+		if got := logs[0].String(); got != tt.wantEvict {
+			t.Errorf("%s: evictions = %s, want %s", tt.name, got, tt.wantEvict)
+		}
+	}
+
+	packs, logs := newLimitPacks(response.PackLimits{MaxKeys: 1, MaxRoundsPerKey: 1, Policy: response.EvictFIFO})
+	compressed := packs[1]
+	_ = compressed.AddResponse(newLimitResponse(t, "/a", codes.OK, 10))
+	_ = compressed.AddResponse(newLimitResponse(t, "/a", codes.Created, 10))
+	_ = compressed.AddResponse(newLimitResponse(t, "/b", codes.OK, 10))
+	if got := packContent(t, compressed); got != "[/b#1]" {
+		t.Errorf("compressed rounds = %s, want [/b#1]", got)
+	}
+	if got := logs[1].String(); got != "[/a#1:maxRounds /a#2:maxKeys]" {
+		t.Errorf("compressed evictions = %s", got)
+	}
+}
+
+func TestPackLimitsMaxBytes(t *testing.T) {
+	packs, logs := newLimitPacks(response.PackLimits{MaxBytes: 35, Policy: response.EvictOldestRound})
+	plain := packs[0].(*response.ResponsePack)
+	for _, path := range []string{"/a", "/b", "/a", "/c"} {
+		_ = plain.AddResponse(newLimitResponse(t, path, codes.InternalServerError, 10))
+	}
+	if got := packContent(t, plain); got != "[/a#2 /b#1 /c#1]" {
+		t.Errorf("rounds = %s", got)
+	}
+	if got := logs[0].String(); got != "[/a#1:maxBytes]" {
+		t.Errorf("evictions = %s", got)
+	}
+	// Counters and stats cover the retained rounds
+	if plain.Total != 3 || plain.Failure != 3 || plain.Evicted != 1 || plain.Stats.BodySize.Count != 3 {
+		t.Errorf("Total %d, Failure %d, Evicted %d, BodySize %d", plain.Total, plain.Failure, plain.Evicted, plain.Stats.BodySize.Count)
+	}
+	plain.Calculate()
+	if plain.Total != 3 || plain.Evicted != 1 {
+		t.Errorf("after Calculate() Total %d, Evicted %d", plain.Total, plain.Evicted)
+	}
+
+	// A round larger than the limit is rejected, the stored rounds are kept
+	if err := plain.AddResponse(newLimitResponse(t, "/d", codes.OK, 50)); err == nil {
+		t.Errorf("AddResponse() of an oversized round error = nil")
+	}
+	if err := plain.ReplaceRound("https://example.com/b", 1, newLimitResponse(t, "/b", codes.OK, 50)); err == nil {
+		t.Errorf("ReplaceRound() with an oversized round error = nil")
+	}
+	if got := packContent(t, plain); got != "[/a#2 /b#1 /c#1]" || plain.Total != 3 || plain.Evicted != 1 {
+		t.Errorf("rounds = %s, Total %d, Evicted %d after oversized rounds", got, plain.Total, plain.Evicted)
+	}
+
+	compressed := packs[1].(*response.CompressResponsePack)
+	compressed.SetLimits(response.PackLimits{MaxBytes: 1, Policy: response.EvictLRU, OnEvict: logs[1].record})
+	if err := compressed.AddResponse(newLimitResponse(t, "/a", codes.OK, 10)); err == nil {
+		t.Errorf("compressed AddResponse() of an oversized round error = nil")
+	}
+	if compressed.GetResponseCount() != 0 || compressed.Evicted != 0 || compressed.Memory().Rounds != 0 {
+		t.Errorf("compressed count %d, Evicted %d", compressed.GetResponseCount(), compressed.Evicted)
+	}
+}
+
+func TestPackLimitsTTL(t *testing.T) {
+	packs, logs := newLimitPacks(response.PackLimits{TTL: 100 * time.Millisecond})
+	for _, pack := range packs {
+		_ = pack.AddResponse(newLimitResponse(t, "/old", codes.OK, 10))
+	}
+	time.Sleep(150 * time.Millisecond)
+	for _, pack := range packs {
+		_ = pack.AddResponse(newLimitResponse(t, "/new", codes.OK, 10))
+	}
+	for i, pack := range packs {
+		if got := packContent(t, pack); got != "[/new#1]" {
+			t.Errorf("pack %d rounds = %s, want [/new#1]", i, got)
+		}
+		if got := logs[i].String(); got != "[/old#1:ttl]" {
+			t.Errorf("pack %d evictions = %s", i, got)
+		}
+	}
+
+	// Reads do not check the TTL, expired rounds stay until Prune
+	time.Sleep(150 * time.Millisecond)
+	for i, pack := range packs {
+		if got := packContent(t, pack); got != "[/new#1]" {
+			t.Errorf("pack %d rounds before Prune() = %s, want [/new#1]", i, got)
+		}
+		pack.Prune()
+		if got := packContent(t, pack); got != "[]" {
+			t.Errorf("pack %d rounds after Prune() = %s, want none", i, got)
+		}
+	}
+}
+
+func TestPackLimitsCallbackUsesPack(t *testing.T) {
+	var pack *response.ResponsePack
+	var evicted []*response.Response
+	pack = response.NewResponsePackFromConfig(response.ConfigResponsePack{Limits: response.PackLimits{
+		MaxKeys: 1,
+		OnEvict: func(eviction response.Eviction) {
+			// The pack is unlocked while the callback runs
+			evicted = append(evicted, eviction.Response)
+			_ = pack.GetKeysOfResponses()
+		},
+	}})
+	_ = pack.AddResponse(newLimitResponse(t, "/a", codes.OK, 3))
+	_ = pack.AddResponse(newLimitResponse(t, "/b", codes.OK, 3))
+	if len(evicted) != 1 || evicted[0].Url != "https://example.com/a" {
+		t.Errorf("evicted = %v", evicted)
+	}
+
+	pack.SetLimits(response.PackLimits{})
+	_ = pack.AddResponse(newLimitResponse(t, "/c", codes.OK, 3))
+	if pack.Len() != 2 {
+		t.Errorf("Len() = %d without limits, want 2", pack.Len())
+	}
+}
+
+func TestPackLimitsConcurrency(t *testing.T) {
+	packs, _ := newLimitPacks(response.PackLimits{MaxKeys: 5, MaxRoundsPerKey: 3, MaxBytes: 120})
+	for _, pack := range packs {
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					path := fmt.Sprintf("/k%d", (w+i)%8)
+					_ = pack.AddResponse(newLimitResponse(t, path, codes.OK, 10))
+					_, _ = pack.GetResponse("https://example.com" + path)
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		keys := pack.GetKeysOfResponses()
+		if len(keys) > 5 {
+			t.Errorf("%T kept %d keys, want at most 5", pack, len(keys))
+		}
+		for _, key := range keys {
+			history, _ := pack.History(key)
+			if len(history) > 3 {
+				t.Errorf("%T kept %d rounds of %s, want at most 3", pack, len(history), key)
+			}
+		}
+	}
+	plain := packs[0].(*response.ResponsePack)
+	if plain.Total+plain.Evicted != 200 || plain.Stats.BodySize.Count != plain.Total || plain.Total*10 > 120 {
+		t.Errorf("Total %d, Evicted %d, BodySize %d", plain.Total, plain.Evicted, plain.Stats.BodySize.Count)
+	}
+}
@@ -0,0 +1,129 @@
+package response_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// newPagedServer serves pages 1..last of /items, linking each page to the next one.
+func newPagedServer(t *testing.T, last int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < last {
+			w.Header().Add("Link", fmt.Sprintf(`</items?page=%d>; rel="next"`, page+1))
+		}
+		w.Header().Add("Link", `</items?page=1>; rel="first"`)
+		fmt.Fprintf(w, `{"page":%d}`, page)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResponseLinks(t *testing.T) {
+	resp := newTestResponse(t, "https://api.example.com/items?page=2", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"Link": `<https://api.example.com/items?page=3&fields=a,b>; rel="next", </items?page=1>; rel="first prev"; title="First, page", ` +
+			`<https://example.com/doc>; rel=describedby; title*=UTF-8'en'%E2%82%AC%20rates; hreflang=en; hreflang=de; type="text/html"`,
+	}, "")
+
+	links := resp.Links()
+	if len(links) != 3 {
+		t.Fatalf("Links() = %+v, want 3 links", links)
+	}
+	if links[0].Target != "https://api.example.com/items?page=3&fields=a,b" || !links[0].HasRel("next") {
+		t.Errorf("next link = %+v", links[0])
+	}
+	if links[1].Target != "https://api.example.com/items?page=1" || !links[1].HasRel("PREV") || links[1].Title != "First, page" {
+		t.Errorf("first link = %+v", links[1])
+	}
+	if links[2].Title != "€ rates" || len(links[2].Hreflang) != 2 || links[2].Type != "text/html" {
+		t.Errorf("describedby link = %+v", links[2])
+	}
+
+	if _, ok := resp.Link("last"); ok {
+		t.Errorf("Link(last) found a link")
+	}
+	if next, ok := resp.Link("next"); !ok || next.Params["rel"] != "next" {
+		t.Errorf("Link(next) = %+v, %v", next, ok)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	server := newPagedServer(t, 4)
+	start := server.URL + "/items"
+
+	pack, err := response.Paginate(context.Background(), start, response.HTTPPageFetcher(server.Client(), nil), response.PaginateOptions{})
+	if err != nil {
+		t.Fatalf("Paginate() error = %v", err)
+	}
+
+	pages, err := pack.GetResponse(start)
+	if err != nil {
+		t.Fatalf("GetResponse() error = %v", err)
+	}
+	if len(pages) != 4 {
+		t.Fatalf("Paginate() stored %d pages, want 4", len(pages))
+	}
+	for index, page := range pages {
+		if want := fmt.Sprintf(`{"page":%d}`, index+1); string(page.Body) != want {
+			t.Errorf("round %d body = %s, want %s", index+1, page.Body, want)
+		}
+	}
+	if pages[2].Request.Url != server.URL+"/items?page=3" || pages[2].Timing == nil {
+		t.Errorf("page 3 request = %+v", pages[2].Request)
+	}
+	if pack.Total != 4 || pack.Success != 4 {
+		t.Errorf("Total = %d, Success = %d", pack.Total, pack.Success)
+	}
+}
+
+func TestPaginateStops(t *testing.T) {
+	server := newPagedServer(t, 10)
+	fetch := response.HTTPPageFetcher(server.Client(), nil)
+
+	limited, err := response.Paginate(context.Background(), server.URL+"/items", fetch, response.PaginateOptions{MaxPages: 3})
+	if err != nil {
+		t.Fatalf("Paginate() error = %v", err)
+	}
+	if limited.Total != 3 {
+		t.Errorf("MaxPages: Total = %d, want 3", limited.Total)
+	}
+
+	// The first relation of every page points to page 1, which was already fetched
+	loop, err := response.Paginate(context.Background(), server.URL+"/items?page=1", fetch, response.PaginateOptions{Rel: "first"})
+	if err != nil {
+		t.Fatalf("Paginate() error = %v", err)
+	}
+	if loop.Total != 1 {
+		t.Errorf("loop: Total = %d, want 1", loop.Total)
+	}
+
+	calls := 0
+	failing := func(ctx context.Context, url string) (*response.Response, error) {
+		calls++
+		if calls == 2 {
+			return nil, fmt.Errorf("connection reset")
+		}
+		return fetch(ctx, url)
+	}
+	partial, err := response.Paginate(context.Background(), server.URL+"/items", failing, response.PaginateOptions{})
+	if err == nil || partial.Total != 1 {
+		t.Errorf("Paginate() = %d pages, %v, want 1 page and an error", partial.Total, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := response.Paginate(ctx, server.URL+"/items", fetch, response.PaginateOptions{}); err == nil {
+		t.Errorf("Paginate() with a cancelled context error = nil")
+	}
+}
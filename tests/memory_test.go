@@ -0,0 +1,111 @@
+package response_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func TestResponseSize(t *testing.T) {
+	resp := newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.OK, map[string]string{"Content-Type": "text/plain"}, "hello")
+	resp.RawResponse = []byte("raw")
+	resp.AddTags("smoke")
+	// method + url + host + body + raw + header + tag
+	want := int64(3 + 21 + 11 + 5 + 3 + 12 + 10 + 5)
+	if got := resp.Size(); got != want {
+		t.Errorf("Size() = %d, want %d", got, want)
+	}
+	if usage := resp.MemoryUsage(); usage.Rounds != 1 || usage.Logical != want || usage.Stored != want {
+		t.Errorf("MemoryUsage() = %+v", usage)
+	}
+}
+
+func TestResponsePackMemory(t *testing.T) {
+	pack := response.NewResponsePack()
+	a := newLimitResponse(t, "/a", codes.OK, 100)
+	b := newLimitResponse(t, "/b", codes.OK, 40)
+	_ = pack.AddResponse(a)
+	_ = pack.AddResponse(a)
+	_ = pack.AddResponse(b)
+
+	usage := pack.Memory()
+	if usage.Rounds != 3 || usage.Logical != 2*a.Size()+b.Size() || usage.Stored != usage.Logical {
+		t.Errorf("Memory() = %+v", usage)
+	}
+	if usage != pack.GetStats().Memory {
+		t.Errorf("Stats.Memory = %+v, want %+v", pack.GetStats().Memory, usage)
+	}
+	keyUsage, err := pack.KeyMemory("https://example.com/a")
+	if err != nil || keyUsage.Rounds != 2 || keyUsage.Logical != 2*a.Size() {
+		t.Errorf("KeyMemory() = %+v, %v", keyUsage, err)
+	}
+	if !strings.Contains(pack.ToString(), "Memory: 3 rounds") {
+		t.Errorf("ToString() = %q, want the memory usage", pack.ToString())
+	}
+
+	// Usage follows removed and replaced rounds
+	_ = pack.DeleteRound("https://example.com/a", 1)
+	smaller := newLimitResponse(t, "/a", codes.OK, 10)
+	if err := pack.ReplaceRound("https://example.com/a", 2, smaller); err != nil {
+		t.Fatalf("ReplaceRound() error = %v", err)
+	}
+	if keyUsage, _ := pack.KeyMemory("https://example.com/a"); keyUsage.Rounds != 1 || keyUsage.Logical != smaller.Size() {
+		t.Errorf("KeyMemory() after changes = %+v", keyUsage)
+	}
+	_ = pack.DeleteResponse("https://example.com/a")
+	if _, err := pack.KeyMemory("https://example.com/a"); err == nil {
+		t.Errorf("KeyMemory() of a deleted key did not fail")
+	}
+	if usage := pack.Memory(); usage.Rounds != 1 || usage.Logical != b.Size() {
+		t.Errorf("Memory() after DeleteResponse() = %+v", usage)
+	}
+	pack.Clear()
+	if usage := pack.Memory(); usage != (response.MemoryUsage{}) {
+		t.Errorf("Memory() after Clear() = %+v, want zero", usage)
+	}
+}
+
+func TestCompressResponsePackMemory(t *testing.T) {
+	pack := response.NewCompressResponsePack()
+	if usage := pack.Memory(); usage.CompressionRatio() != 0 {
+		t.Errorf("CompressionRatio() of an empty pack = %f, want 0", usage.CompressionRatio())
+	}
+	large := newLimitResponse(t, "/a", codes.OK, 4096)
+	_ = pack.AddResponse(large)
+	_ = pack.AddResponse(large)
+	_ = pack.AddResponse(newLimitResponse(t, "/b", codes.OK, 10))
+
+	usage := pack.Memory()
+	if usage.Rounds != 3 || usage.Stored <= 0 || usage.Stored >= usage.Logical {
+		t.Errorf("Memory() = %+v", usage)
+	}
+	if ratio := usage.CompressionRatio(); ratio <= 1 {
+		t.Errorf("CompressionRatio() = %f, want above 1", ratio)
+	}
+	keyUsage, err := pack.KeyMemory("https://example.com/a")
+	if err != nil || keyUsage.Rounds != 2 || keyUsage.Logical != 2*large.Size() {
+		t.Errorf("KeyMemory() = %+v, %v", keyUsage, err)
+	}
+	if !strings.Contains(pack.ToString(), "Rounds: 3") || !strings.Contains(pack.ToString(), "Memory: 3 rounds") {
+		t.Errorf("ToString() = %q", pack.ToString())
+	}
+
+	_ = pack.DeleteResponse("https://example.com/a")
+	if usage := pack.Memory(); usage.Rounds != 1 || usage.Logical != newLimitResponse(t, "/b", codes.OK, 10).Size() {
+		t.Errorf("Memory() after DeleteResponse() = %+v", usage)
+	}
+
+	// Evicted rounds are no longer counted
+	limited := response.NewCompressResponsePackFromConfig(response.ConfigCompressResponsePack{Limits: response.PackLimits{MaxRoundsPerKey: 1}})
+	_ = limited.AddResponse(large)
+	_ = limited.AddResponse(large)
+	if usage := limited.Memory(); usage.Rounds != 1 || usage.Logical != large.Size() {
+		t.Errorf("Memory() under limits = %+v", usage)
+	}
+	limited.Clear()
+	if usage := limited.Memory(); usage != (response.MemoryUsage{}) {
+		t.Errorf("Memory() after Clear() = %+v, want zero", usage)
+	}
+}
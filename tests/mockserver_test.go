@@ -0,0 +1,225 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func newMockPack(t *testing.T) *response.ResponsePack {
+	t.Helper()
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newReplayFixture(t, "https://api.example.com/items", codes.GET, "one"))
+	_ = pack.AddResponse(newReplayFixture(t, "https://api.example.com/items", codes.GET, "two"))
+	_ = pack.AddResponse(newReplayFixture(t, "https://api.example.com/users?id=7", codes.GET, "user7"))
+	return pack
+}
+
+func getBody(t *testing.T, client *http.Client, req *http.Request) (int, string) {
+	t.Helper()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	return resp.StatusCode, readAll(t, resp)
+}
+
+func TestMockServerSequential(t *testing.T) {
+	server := httptest.NewServer(response.NewMockServer(newMockPack(t), response.ServeSequential))
+	defer server.Close()
+
+	want := []string{"one", "two", "one"}
+	for i, expected := range want {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/items", nil)
+		status, body := getBody(t, server.Client(), req)
+		if status != http.StatusOK || body != expected {
+			t.Errorf("call %d: got %d %q, want 200 %q", i, status, body, expected)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/users?id=7", nil)
+	if _, body := getBody(t, server.Client(), req); body != "user7" {
+		t.Errorf("body = %q, want user7", body)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/missing", nil)
+	if status, _ := getBody(t, server.Client(), req); status != http.StatusNotFound {
+		t.Errorf("status for unknown path = %d, want 404", status)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/items", nil)
+	if status, _ := getBody(t, server.Client(), req); status != http.StatusMethodNotAllowed {
+		t.Errorf("status for unknown method = %d, want 405", status)
+	}
+}
+
+func TestMockServerSticky(t *testing.T) {
+	server := httptest.NewServer(response.NewMockServer(newMockPack(t), response.ServeSticky))
+	defer server.Close()
+
+	bodies := map[string]string{}
+	for i := 0; i < 3; i++ {
+		for _, client := range []string{"a", "b"} {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+"/items", nil)
+			req.Header.Set("X-Mock-Client", client)
+			_, body := getBody(t, server.Client(), req)
+			if previous, ok := bodies[client]; ok && previous != body {
+				t.Errorf("client %s got %q, previously %q", client, body, previous)
+			}
+			bodies[client] = body
+		}
+	}
+
+	if bodies["a"] == bodies["b"] {
+		t.Errorf("clients a and b got the same round %q, want different rounds", bodies["a"])
+	}
+}
+
+func TestMockServerRandom(t *testing.T) {
+	mock := response.NewMockServer(newMockPack(t), response.ServeRandom)
+	mock.Seed(1)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/items", nil)
+		_, body := getBody(t, server.Client(), req)
+		seen[body] = true
+	}
+	if !seen["one"] || !seen["two"] {
+		t.Errorf("random mode served %v, want both rounds", seen)
+	}
+}
+
+func TestMockServerFaults(t *testing.T) {
+	mock := response.NewMockServer(newMockPack(t), response.ServeSequential)
+	mock.Faults = response.FaultConfig{BurstEvery: 3, BurstLength: 2}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	want := []int{503, 503, 200, 503, 503, 200}
+	for i, expected := range want {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/items", nil)
+		if status, _ := getBody(t, server.Client(), req); status != expected {
+			t.Errorf("request %d: status = %d, want %d", i+1, status, expected)
+		}
+	}
+
+	mock.Faults = response.FaultConfig{ResetRate: 1}
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/items", nil)
+	if _, err := server.Client().Do(req); err == nil {
+		t.Error("Do() with ResetRate 1 should fail")
+	}
+}
+
+func TestMockServerLatency(t *testing.T) {
+	mock := response.NewMockServer(newMockPack(t), response.ServeSequential)
+	mock.Latency = 50 * time.Millisecond
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	start := time.Now()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/items", nil)
+	_, _ = getBody(t, server.Client(), req)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("elapsed = %v, want at least 50ms", elapsed)
+	}
+}
+
+func TestMockServerReload(t *testing.T) {
+	reloaded := response.NewResponsePack()
+	_ = reloaded.AddResponse(newReplayFixture(t, "https://api.example.com/fresh", codes.GET, "fresh"))
+	data, err := reloaded.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "pack.json")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	mock := response.NewMockServer(newMockPack(t), response.ServeSequential)
+	mock.PackFile = file
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/__admin/reload", nil)
+	status, body := getBody(t, server.Client(), req)
+	if status != http.StatusOK || !strings.Contains(body, "reloaded") {
+		t.Fatalf("reload = %d %q, want 200", status, body)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/fresh", nil)
+	if _, body := getBody(t, server.Client(), req); body != "fresh" {
+		t.Errorf("body after reload = %q, want fresh", body)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/items", nil)
+	if status, _ := getBody(t, server.Client(), req); status != http.StatusNotFound {
+		t.Errorf("status for old path after reload = %d, want 404", status)
+	}
+}
+
+func TestMockServerHosts(t *testing.T) {
+	pack := response.NewCompressResponsePack()
+	_ = pack.AddResponse(newReplayFixture(t, "https://api.example.com/status", codes.GET, "api"))
+	_ = pack.AddResponse(newReplayFixture(t, "https://auth.example.com/status", codes.GET, "auth"))
+
+	mock := response.NewMockServer(pack, response.ServeSequential)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	for host, want := range map[string]string{"api.example.com": "api", "auth.example.com:443": "auth"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/status", nil)
+		req.Host = host
+		if status, body := getBody(t, server.Client(), req); status != http.StatusOK || body != want {
+			t.Errorf("GET /status on %s = %d %q, want 200 %q", host, status, body, want)
+		}
+	}
+
+	// Rounds are read when the pack is set, later rounds wait for SetPack
+	_ = pack.AddResponse(newReplayFixture(t, "https://api.example.com/new", codes.GET, "new"))
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/new", nil)
+	if status, _ := getBody(t, server.Client(), req); status != http.StatusNotFound {
+		t.Errorf("GET /new before SetPack() = %d, want 404", status)
+	}
+	mock.SetPack(pack)
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/new", nil)
+	if status, body := getBody(t, server.Client(), req); status != http.StatusOK || body != "new" {
+		t.Errorf("GET /new after SetPack() = %d %q", status, body)
+	}
+}
+
+func TestMockServerQueryVariants(t *testing.T) {
+	pack := newMockPack(t)
+	_ = pack.AddResponse(newReplayFixture(t, "https://api.example.com/users?id=8", codes.GET, "user8"))
+	server := httptest.NewServer(response.NewMockServer(pack, response.ServeSequential))
+	defer server.Close()
+
+	for query, want := range map[string]string{"?id=7": "user7", "?id=8": "user8"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/users"+query, nil)
+		if status, body := getBody(t, server.Client(), req); status != http.StatusOK || body != want {
+			t.Errorf("GET /users%s = %d %q, want 200 %q", query, status, body, want)
+		}
+	}
+
+	// Several query variants and none matches
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/users?id=9", nil)
+	if status, body := getBody(t, server.Client(), req); status != http.StatusNotFound {
+		t.Errorf("GET /users?id=9 = %d %q, want 404", status, body)
+	}
+
+	// A single recording answers any query
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/items?page=2", nil)
+	if status, body := getBody(t, server.Client(), req); status != http.StatusOK || body != "one" {
+		t.Errorf("GET /items?page=2 = %d %q, want 200 one", status, body)
+	}
+}
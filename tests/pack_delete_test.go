@@ -0,0 +1,164 @@
+package response_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+// checkPackCounters compares the counters of pack with the ones Calculate rebuilds
+// from the stored rounds, and with the wanted values.
+func checkPackCounters(t *testing.T, pack *response.ResponsePack, total, success, failure uint64) {
+	t.Helper()
+	if pack.Total != total || pack.Success != success || pack.Failure != failure {
+		t.Errorf("Total/Success/Failure = %d/%d/%d, want %d/%d/%d", pack.Total, pack.Success, pack.Failure, total, success, failure)
+	}
+
+	stats := pack.GetStats()
+	ratios := [2]float64{pack.SuccessRatio, pack.FailureRatio}
+	pack.Calculate()
+	if pack.Total != total || pack.Success != success || pack.Failure != failure {
+		t.Errorf("after Calculate() Total/Success/Failure = %d/%d/%d", pack.Total, pack.Success, pack.Failure)
+	}
+	if ratios != [2]float64{pack.SuccessRatio, pack.FailureRatio} {
+		t.Errorf("ratios = %v, Calculate() gives %v", ratios, [2]float64{pack.SuccessRatio, pack.FailureRatio})
+	}
+	if got, want := fmt.Sprintf("%+v", stats), fmt.Sprintf("%+v", pack.GetStats()); got != want {
+		t.Errorf("Stats = %s, Calculate() gives %s", got, want)
+	}
+}
+
+func newDeletePack(t *testing.T) *response.ResponsePack {
+	t.Helper()
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.OK, nil, "a1"))
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.InternalServerError, nil, "a2"))
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.OK, nil, "a3"))
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/b", "example.com", codes.GET, codes.NotFound, nil, "b1"))
+	checkPackCounters(t, pack, 4, 2, 2)
+	return pack
+}
+
+func TestResponsePackDeleteResponse(t *testing.T) {
+	pack := newDeletePack(t)
+
+	if err := pack.DeleteResponse("https://example.com/b"); err != nil {
+		t.Fatalf("DeleteResponse() error = %v", err)
+	}
+	checkPackCounters(t, pack, 3, 2, 1)
+	if pack.GetStats().StatusCodes[404] != 0 {
+		t.Errorf("StatusCodes = %v, want no 404", pack.GetStats().StatusCodes)
+	}
+	if _, err := pack.GetResponse("https://example.com/b"); err == nil {
+		t.Errorf("GetResponse() of a deleted URL error = nil, want an error")
+	}
+	if err := pack.DeleteResponse("https://example.com/b"); err == nil {
+		t.Errorf("DeleteResponse() of a deleted URL error = nil, want an error")
+	}
+
+	// The sequence numbers of a deleted URL are not reused
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/b", "example.com", codes.GET, codes.OK, nil, "b2"))
+	if _, err := pack.GetRound("https://example.com/b", 2); err != nil {
+		t.Errorf("GetRound(2) error = %v", err)
+	}
+
+	errs := pack.BatchDeleteResponse([]string{"https://example.com/a", "https://example.com/missing"})
+	if len(errs) != 1 {
+		t.Errorf("BatchDeleteResponse() errors = %v, want 1", errs)
+	}
+	checkPackCounters(t, pack, 1, 1, 0)
+}
+
+func TestResponsePackDeleteRound(t *testing.T) {
+	pack := newDeletePack(t)
+	url := "https://example.com/a"
+
+	if err := pack.DeleteRound(url, 2); err != nil {
+		t.Fatalf("DeleteRound() error = %v", err)
+	}
+	checkPackCounters(t, pack, 3, 2, 1)
+	if pack.SuccessRatio != 2.0/3 {
+		t.Errorf("SuccessRatio = %f, want 2/3", pack.SuccessRatio)
+	}
+	if err := pack.DeleteRound(url, 2); err == nil {
+		t.Errorf("DeleteRound() of a deleted round error = nil, want an error")
+	}
+
+	// Remaining rounds keep their keys, new rounds do not collide with them
+	_ = pack.AddResponse(newTestResponse(t, url, "example.com", codes.GET, codes.OK, nil, "a4"))
+	history, err := pack.History(url)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	var got []string
+	for _, round := range history {
+		got = append(got, fmt.Sprintf("%d:%s", round.Seq, round.Response.Body))
+	}
+	if fmt.Sprint(got) != "[1:a1 3:a3 4:a4]" {
+		t.Errorf("History() = %v, want [1:a1 3:a3 4:a4]", got)
+	}
+
+	// Deleting the latest round does not free its number either
+	_ = pack.DeleteRound(url, 4)
+	_ = pack.AddResponse(newTestResponse(t, url, "example.com", codes.GET, codes.OK, nil, "a5"))
+	latest, err := pack.Latest(url)
+	if err != nil || string(latest.Body) != "a5" {
+		t.Errorf("Latest() = %v, %v", latest, err)
+	}
+	if _, err := pack.GetRound(url, 5); err != nil {
+		t.Errorf("GetRound(5) error = %v", err)
+	}
+
+	// The URL goes with its last round
+	_ = pack.DeleteRound("https://example.com/b", 1)
+	if _, err := pack.GetResponse("https://example.com/b"); err == nil {
+		t.Errorf("GetResponse() after deleting the last round error = nil, want an error")
+	}
+	checkPackCounters(t, pack, 3, 3, 0)
+}
+
+func TestResponsePackReplaceRound(t *testing.T) {
+	pack := newDeletePack(t)
+	url := "https://example.com/a"
+
+	if err := pack.ReplaceRound(url, 2, newTestResponse(t, url, "example.com", codes.GET, codes.OK, nil, "fixed")); err != nil {
+		t.Fatalf("ReplaceRound() error = %v", err)
+	}
+	checkPackCounters(t, pack, 4, 3, 1)
+	replaced, err := pack.GetRound(url, 2)
+	if err != nil || string(replaced.Body) != "fixed" {
+		t.Errorf("GetRound(2) = %v, %v", replaced, err)
+	}
+
+	if err := pack.ReplaceRound(url, 9, newTestResponse(t, url, "example.com", codes.GET, codes.OK, nil, "x")); err == nil {
+		t.Errorf("ReplaceRound() of a missing round error = nil, want an error")
+	}
+	if err := pack.ReplaceRound(url, 1, newTestResponse(t, "https://example.com/b", "example.com", codes.GET, codes.OK, nil, "x")); err == nil {
+		t.Errorf("ReplaceRound() with another URL error = nil, want an error")
+	}
+	if err := pack.ReplaceRound(url, 1, nil); err == nil {
+		t.Errorf("ReplaceRound() with a nil response error = nil, want an error")
+	}
+	checkPackCounters(t, pack, 4, 3, 1)
+}
+
+func TestResponsePackClear(t *testing.T) {
+	pack := newDeletePack(t)
+	pack.AddInfo("env", "test")
+
+	pack.Clear()
+	checkPackCounters(t, pack, 0, 0, 0)
+	if pack.Len() != 0 || pack.SuccessRatio != 0 || pack.GetStats().BodySize.Count != 0 {
+		t.Errorf("Clear() left Len %d, SuccessRatio %f, BodySize %+v", pack.Len(), pack.SuccessRatio, pack.GetStats().BodySize)
+	}
+	if pack.Info["env"] != "test" {
+		t.Errorf("Clear() dropped Info")
+	}
+
+	_ = pack.AddResponse(newTestResponse(t, "https://example.com/a", "example.com", codes.GET, codes.OK, nil, "new"))
+	if _, err := pack.GetRound("https://example.com/a", 1); err != nil {
+		t.Errorf("GetRound(1) after Clear() error = %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package response_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func TestProblemJSON(t *testing.T) {
+	resp := newTestResponse(t, "https://api.example.com/pay", "api.example.com", codes.GET, codes.Forbidden, map[string]string{"Content-Type": "application/problem+json; charset=utf-8"}, `{
+		"type": "https://example.com/probs/out-of-credit",
+		"title": "You do not have enough credit.",
+		"status": 403,
+		"detail": "Your current balance is 30, but that costs 50.",
+		"instance": "/account/12345/msgs/abc",
+		"balance": 30,
+		"accounts": ["/account/12345", "/account/67890"]
+	}`)
+
+	problem, err := resp.Problem()
+	if err != nil {
+		t.Fatalf("Problem() error = %v", err)
+	}
+	if problem.Type != "https://example.com/probs/out-of-credit" || problem.Status != 403 || problem.Instance != "/account/12345/msgs/abc" {
+		t.Errorf("Problem() = %+v", problem)
+	}
+	if problem.Summary() != "You do not have enough credit.: Your current balance is 30, but that costs 50." {
+		t.Errorf("Summary() = %q", problem.Summary())
+	}
+	if problem.Extensions["balance"].(interface{ String() string }).String() != "30" || len(problem.Extensions["accounts"].([]interface{})) != 2 {
+		t.Errorf("Extensions = %v", problem.Extensions)
+	}
+}
+
+func TestProblemJSONDefaultsAndWrongTypes(t *testing.T) {
+	resp := newTestResponse(t, "https://api.example.com/x", "api.example.com", codes.GET, codes.BadRequest, map[string]string{"Content-Type": "application/problem+json"}, `{"title": 5, "status": "400", "detail": "bad"}`)
+
+	problem, err := resp.Problem()
+	if err != nil {
+		t.Fatalf("Problem() error = %v", err)
+	}
+	if problem.Type != "about:blank" || problem.Title != "" || problem.Status != 0 || problem.Detail != "bad" {
+		t.Errorf("Problem() = %+v, want wrong-typed members ignored", problem)
+	}
+
+	if _, err := newTestResponse(t, "https://api.example.com/x", "api.example.com", codes.GET, codes.BadRequest, map[string]string{"Content-Type": "application/json"}, `{}`).Problem(); err == nil {
+		t.Errorf("Problem() on application/json error = nil")
+	}
+	if _, err := newTestResponse(t, "https://api.example.com/x", "api.example.com", codes.GET, codes.BadRequest, map[string]string{"Content-Type": "application/problem+json"}, `{`).Problem(); err == nil {
+		t.Errorf("Problem() on malformed body error = nil")
+	}
+}
+
+func TestProblemXML(t *testing.T) {
+	resp := newTestResponse(t, "https://api.example.com/pay", "api.example.com", codes.GET, codes.Forbidden, map[string]string{"Content-Type": "application/problem+xml"}, `<?xml version="1.0" encoding="UTF-8"?>
+<problem xmlns="urn:ietf:rfc:7807">
+  <type>https://example.com/probs/out-of-credit</type>
+  <title>You do not have enough credit.</title>
+  <detail>Your current balance is 30, but that costs 50.</detail>
+  <instance>https://example.net/account/12345/msgs/abc</instance>
+  <status>403</status>
+  <balance>30</balance>
+  <accounts>
+    <i>https://example.net/account/12345</i>
+    <i>https://example.net/account/67890</i>
+  </accounts>
+</problem>`)
+
+	problem, err := resp.Problem()
+	if err != nil {
+		t.Fatalf("Problem() error = %v", err)
+	}
+	if problem.Status != 403 || problem.Title != "You do not have enough credit." {
+		t.Errorf("Problem() = %+v", problem)
+	}
+	if problem.Extensions["balance"] != "30" || len(problem.Extensions["accounts"].([]interface{})) != 2 {
+		t.Errorf("Extensions = %v", problem.Extensions)
+	}
+
+	if _, err := newTestResponse(t, "https://api.example.com/x", "api.example.com", codes.GET, codes.BadRequest, map[string]string{"Content-Type": "application/problem+xml"}, `<error/>`).Problem(); err == nil {
+		t.Errorf("Problem() with a non-problem root error = nil")
+	}
+}
+
+func TestErrorReportWithProblem(t *testing.T) {
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newTestResponse(t, "https://api.example.com/pay", "api.example.com", codes.GET, codes.OK, map[string]string{"Content-Type": "application/json"}, `{}`))
+	_ = pack.AddResponse(newTestResponse(t, "https://api.example.com/pay", "api.example.com", codes.GET, codes.Forbidden, map[string]string{"Content-Type": "application/problem+json"}, `{"title": "Out of credit", "detail": "Balance is 30"}`))
+	_ = pack.AddResponse(newTestResponse(t, "https://api.example.com/pay", "api.example.com", codes.GET, codes.InternalServerError, map[string]string{"Content-Type": "text/plain"}, `oops`))
+
+	report, err := pack.GetErrorReportEntries()
+	if err != nil {
+		t.Fatalf("GetErrorReportEntries() error = %v", err)
+	}
+	rounds := report["https://api.example.com/pay"]
+	if len(rounds) != 2 {
+		t.Fatalf("GetErrorReportEntries() rounds = %v, want both failed rounds", rounds)
+	}
+	if rounds["round_2"].Problem == nil || rounds["round_2"].Problem.Title != "Out of credit" || rounds["round_3"].Problem != nil {
+		t.Errorf("problems = %+v, %+v", rounds["round_2"].Problem, rounds["round_3"].Problem)
+	}
+	if rounds["round_2"].StatusCode != codes.Forbidden {
+		t.Errorf("StatusCode = %d, want 403", rounds["round_2"].StatusCode)
+	}
+
+	text, err := pack.GetErrorReportString()
+	if err != nil {
+		t.Fatalf("GetErrorReportString() error = %v", err)
+	}
+	if !strings.Contains(text, "\tround_2: 403 - Out of credit: Balance is 30\n\tround_3: 500\n") {
+		t.Errorf("GetErrorReportString() = %q", text)
+	}
+}
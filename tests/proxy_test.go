@@ -0,0 +1,198 @@
+package response_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `","body":"` + string(body) + `"}`))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func TestRecordingProxyCapturesExchanges(t *testing.T) {
+	upstream := newUpstream(t)
+	pack := response.NewResponsePack()
+
+	recorder, err := response.NewRecordingProxy(upstream.URL, pack)
+	if err != nil {
+		t.Fatalf("NewRecordingProxy() error = %v", err)
+	}
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	resp, err := http.Post(proxy.URL+"/items?page=1", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if body := readAll(t, resp); !strings.Contains(body, `"body":"hello"`) {
+		t.Errorf("proxied body = %q, want upstream echo", body)
+	}
+
+	resp, err = http.Get(proxy.URL + "/missing")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = readAll(t, resp)
+
+	if pack.Total != 2 || pack.Failure != 1 {
+		t.Errorf("pack Total = %d, Failure = %d, want 2 and 1", pack.Total, pack.Failure)
+	}
+
+	rounds, err := pack.GetResponse(upstream.URL + "/items?page=1")
+	if err != nil {
+		t.Fatalf("GetResponse() error = %v", err)
+	}
+	recorded := rounds[0]
+	if recorded.Request == nil || string(recorded.Request.Body) != "hello" {
+		t.Errorf("recorded request = %+v, want body hello", recorded.Request)
+	}
+	if recorded.Timing == nil || recorded.Timing.Start.IsZero() {
+		t.Error("recorded round has no timing")
+	}
+	if !strings.Contains(recorded.ReadBody(), `"path":"/items"`) {
+		t.Errorf("recorded body = %q", recorded.ReadBody())
+	}
+}
+
+func TestRecordingProxyRedactionAndSampling(t *testing.T) {
+	upstream := newUpstream(t)
+	pack := response.NewCompressResponsePack()
+
+	recorder, err := response.NewRecordingProxy(upstream.URL, pack)
+	if err != nil {
+		t.Fatalf("NewRecordingProxy() error = %v", err)
+	}
+	policy := response.NewRedactionPolicy(response.RedactHash)
+	policy.Headers = []string{"Set-Cookie"}
+	if err := recorder.SetRedactionPolicy(policy); err != nil {
+		t.Fatalf("SetRedactionPolicy() error = %v", err)
+	}
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	cookie := resp.Header.Get("Set-Cookie")
+	if cookie == "" {
+		t.Error("redaction must not change the proxied response")
+	}
+	_ = readAll(t, resp)
+
+	rounds, err := pack.GetResponse(upstream.URL + "/a")
+	if err != nil {
+		t.Fatalf("GetResponse() error = %v", err)
+	}
+	// Rounds are redacted once, so the stored hash is the hash of the cookie
+	want := policy.Redact(newTestResponse(t, upstream.URL+"/a", "", codes.GET, codes.OK, map[string]string{"Set-Cookie": cookie}, ""))
+	if got := rounds[0].Headers["Set-Cookie"]; got == cookie || got != want.Headers["Set-Cookie"] {
+		t.Errorf("stored Set-Cookie = %q, want %q", got, want.Headers["Set-Cookie"])
+	}
+
+	recorder.SampleRate = 0
+	resp, err = http.Get(proxy.URL + "/b")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = readAll(t, resp)
+	if pack.GetResponseCount() != 1 {
+		t.Errorf("GetResponseCount() = %d, want 1 with sampling disabled", pack.GetResponseCount())
+	}
+}
+
+func TestRecordingProxyDownload(t *testing.T) {
+	upstream := newUpstream(t)
+	pack := response.NewCompressResponsePackFromConfig(response.ConfigCompressResponsePack{KeyFunc: response.MethodURLKey})
+
+	recorder, err := response.NewRecordingProxy(upstream.URL, pack)
+	if err != nil {
+		t.Fatalf("NewRecordingProxy() error = %v", err)
+	}
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	resp, err := http.Post(proxy.URL+"/items", "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	_ = readAll(t, resp)
+
+	resp, err = http.Get(proxy.URL + "/__proxy/pack")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	downloaded, err := response.NewResponsePackFromJSON([]byte(readAll(t, resp)))
+	if err != nil {
+		t.Fatalf("NewResponsePackFromJSON() error = %v", err)
+	}
+	// The download keeps the keys of the pack
+	if _, err := downloaded.GetResponse("POST " + upstream.URL + "/items"); err != nil {
+		t.Errorf("downloaded pack GetResponse() error = %v, keys %v", err, downloaded.GetKeysOfResponses())
+	}
+
+	resp, err = http.Get(proxy.URL + "/__proxy/pack?format=har")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	var har struct {
+		Log struct {
+			Version string `json:"version"`
+			Entries []struct {
+				Request struct {
+					Method string `json:"method"`
+					Url    string `json:"url"`
+				} `json:"request"`
+				Response struct {
+					Status int `json:"status"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(readAll(t, resp)), &har); err != nil {
+		t.Fatalf("HAR Unmarshal() error = %v", err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 1 {
+		t.Fatalf("HAR = %+v, want version 1.2 with 1 entry", har.Log)
+	}
+	entry := har.Log.Entries[0]
+	if entry.Request.Method != "POST" || entry.Request.Url != upstream.URL+"/items" || entry.Response.Status != 200 {
+		t.Errorf("HAR entry = %+v", entry)
+	}
+
+	resp, err = http.Get(proxy.URL + "/__proxy/pack?format=xml")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = readAll(t, resp)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown format status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestNewRecordingProxyInvalidTarget(t *testing.T) {
+	if _, err := response.NewRecordingProxy("not a url", response.NewResponsePack()); err == nil {
+		t.Error("NewRecordingProxy() with invalid target should return error")
+	}
+	if _, err := response.NewRecordingProxy("http://localhost", nil); err == nil {
+		t.Error("NewRecordingProxy() with nil pack should return error")
+	}
+}
@@ -0,0 +1,149 @@
+package response_test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+var queryEpoch = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// newQueryResponse returns a response started minutes after queryEpoch.
+func newQueryResponse(t *testing.T, url string, host string, method codes.Method, status codes.StatusCode, body string, minutes int, tags ...string) *response.Response {
+	t.Helper()
+	resp := newTestResponse(t, url, host, method, status, map[string]string{"Content-Type": "application/json"}, body)
+	resp.Timing = &response.Timing{Start: queryEpoch.Add(time.Duration(minutes) * time.Minute), Duration: time.Duration(len(body)) * time.Millisecond}
+	resp.AddTags(tags...)
+	return resp
+}
+
+func newQueryFixtures(t *testing.T) []*response.Response {
+	t.Helper()
+	return []*response.Response{
+		newQueryResponse(t, "https://api.internal/orders", "api.internal", codes.POST, codes.InternalServerError, `{"error":"db"}`, 10, "smoke"),
+		newQueryResponse(t, "https://api.internal/orders", "api.internal", codes.POST, codes.Created, `{"id":1}`, 20),
+		newQueryResponse(t, "https://api.internal/orders", "api.internal", codes.GET, codes.OK, `[]`, 30, "smoke"),
+		newQueryResponse(t, "https://api.internal/users", "api.internal", codes.POST, codes.BadGateway, `{"error":"upstream timeout"}`, 90),
+		newQueryResponse(t, "https://cdn.example.com/app.js", "cdn.example.com", codes.GET, codes.ServiceUnavailable, `down`, 40),
+	}
+}
+
+// queryRounds formats results as "key#round".
+func queryRounds(results []response.QueryResult) string {
+	var out []string
+	for _, result := range results {
+		out = append(out, fmt.Sprintf("%s#%d", result.Key, result.Round))
+	}
+	return fmt.Sprint(out)
+}
+
+func TestQueryPredicates(t *testing.T) {
+	pack := response.NewResponsePack()
+	for _, resp := range newQueryFixtures(t) {
+		_ = pack.AddResponse(resp)
+	}
+
+	tests := []struct {
+		name  string
+		where []response.Predicate
+		want  string
+	}{
+		{"all", nil, "[https://api.internal/orders#1 https://api.internal/orders#2 https://api.internal/orders#3 https://api.internal/users#1 https://cdn.example.com/app.js#1]"},
+		{"5xx POSTs to host in the hour", []response.Predicate{
+			response.StatusClass("5xx"),
+			response.MethodIs(codes.POST),
+			response.HostIs("API.internal"),
+			response.TimeRange(queryEpoch, queryEpoch.Add(time.Hour)),
+		}, "[https://api.internal/orders#1]"},
+		{"status range", []response.Predicate{response.StatusRange(200, 299)}, "[https://api.internal/orders#2 https://api.internal/orders#3]"},
+		{"URL prefix", []response.Predicate{response.URLPrefix("https://api.internal/u")}, "[https://api.internal/users#1]"},
+		{"URL regexp", []response.Predicate{response.URLMatches(regexp.MustCompile(`\.js$`))}, "[https://cdn.example.com/app.js#1]"},
+		{"header", []response.Predicate{response.HeaderMatches("content-type", regexp.MustCompile("json")), response.HeaderIs("Content-Type", "text/plain")}, "[]"},
+		{"body", []response.Predicate{response.BodyContains("timeout")}, "[https://api.internal/users#1]"},
+		{"tag", []response.Predicate{response.TaggedWith("smoke")}, "[https://api.internal/orders#1 https://api.internal/orders#3]"},
+		{"any of, not", []response.Predicate{response.AnyOf(response.MethodIs(codes.GET), response.StatusRange(502, 502)), response.Not(response.HostIs("cdn.example.com"))}, "[https://api.internal/orders#3 https://api.internal/users#1]"},
+		{"open time range", []response.Predicate{response.TimeRange(queryEpoch.Add(40*time.Minute), time.Time{})}, "[https://api.internal/users#1 https://cdn.example.com/app.js#1]"},
+	}
+	for _, tt := range tests {
+		results, err := pack.Query(response.Query{Where: tt.where})
+		if err != nil {
+			t.Fatalf("%s: Query() error = %v", tt.name, err)
+		}
+		if got := queryRounds(results); got != tt.want {
+			t.Errorf("%s: Query() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQuerySortAndPaginate(t *testing.T) {
+	pack := response.NewCompressResponsePack()
+	for _, resp := range newQueryFixtures(t) {
+		_ = pack.AddResponse(resp)
+	}
+
+	results, err := pack.Query(response.Query{SortBy: response.SortByTime, Descending: true, Offset: 1, Limit: 2})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got := queryRounds(results); got != "[https://cdn.example.com/app.js#1 https://api.internal/orders#3]" {
+		t.Errorf("Query() = %s", got)
+	}
+
+	results, _ = pack.Query(response.Query{SortBy: response.SortByStatus, Limit: 1})
+	if len(results) != 1 || results[0].Response.StatusCode != codes.OK {
+		t.Errorf("lowest status = %+v", results)
+	}
+	results, _ = pack.Query(response.Query{SortBy: response.SortByBodySize, Descending: true, Limit: 1})
+	if len(results) != 1 || results[0].Key != "https://api.internal/users" {
+		t.Errorf("largest body = %s", queryRounds(results))
+	}
+
+	results, _ = pack.Query(response.Query{Offset: 10})
+	if len(results) != 0 {
+		t.Errorf("Query() past the end = %s, want none", queryRounds(results))
+	}
+	if _, err := pack.Query(response.Query{Limit: -1}); err == nil {
+		t.Errorf("Query() with a negative limit error = nil, want an error")
+	}
+}
+
+func TestQueryFilter(t *testing.T) {
+	pack := response.NewResponsePackFromConfig(response.ConfigResponsePack{KeyFunc: response.MethodURLKey, Info: map[string]string{"env": "staging"}})
+	for _, resp := range newQueryFixtures(t) {
+		_ = pack.AddResponse(resp)
+	}
+
+	failures, err := pack.Filter(response.Query{Where: []response.Predicate{response.StatusClass("5xx")}})
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	if failures.Total != 3 || failures.Failure != 3 || failures.Info["env"] != "staging" {
+		t.Errorf("Filter() Total %d, Failure %d, Info %v", failures.Total, failures.Failure, failures.Info)
+	}
+	if _, err := failures.GetRound("POST https://api.internal/orders", 1); err != nil {
+		t.Errorf("GetRound() on the filtered pack error = %v", err)
+	}
+	if pack.Total != 5 {
+		t.Errorf("Filter() changed the source pack, Total = %d", pack.Total)
+	}
+
+	compressed := response.NewCompressResponsePack()
+	for _, resp := range newQueryFixtures(t) {
+		_ = compressed.AddResponse(resp)
+	}
+	smoke, err := compressed.Filter(response.Query{Where: []response.Predicate{response.TaggedWith("smoke")}})
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	if smoke.GetResponseCount() != 2 {
+		t.Errorf("Filter() kept %d rounds, want 2", smoke.GetResponseCount())
+	}
+	latest, err := smoke.Latest("https://api.internal/orders")
+	if err != nil || !latest.HasTag("smoke") || latest.Method != codes.GET {
+		t.Errorf("Latest() = %+v, %v", latest, err)
+	}
+}
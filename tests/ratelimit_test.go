@@ -0,0 +1,144 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	date := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	seconds := newTestResponse(t, "https://api.example.com/", "api.example.com", codes.GET, codes.TooManyRequests, map[string]string{
+		"Retry-After": "120",
+		"Date":        date.Format(http.TimeFormat),
+	}, "").RateLimit()
+	if seconds.RetryAfter != 2*time.Minute || !seconds.RetryAt.Equal(date.Add(2*time.Minute)) || !seconds.Throttled() {
+		t.Errorf("seconds form = %+v", seconds)
+	}
+
+	httpDate := newTestResponse(t, "https://api.example.com/", "api.example.com", codes.GET, codes.ServiceUnavailable, map[string]string{
+		"Retry-After": date.Add(30 * time.Second).Format(http.TimeFormat),
+		"Date":        date.Format(http.TimeFormat),
+	}, "").RateLimit()
+	if httpDate.RetryAfter != 30*time.Second {
+		t.Errorf("date form RetryAfter = %v, want 30s", httpDate.RetryAfter)
+	}
+
+	none := newTestResponse(t, "https://api.example.com/", "api.example.com", codes.GET, codes.OK, nil, "").RateLimit()
+	if none.RetryAfter != -1 || none.Limit != -1 || none.Remaining != -1 || none.Source != "" || none.Throttled() {
+		t.Errorf("no headers = %+v", none)
+	}
+}
+
+func TestRateLimitIETFHeaders(t *testing.T) {
+	structured := newTestResponse(t, "https://api.example.com/", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"RateLimit":        `"default";r=50;t=30`,
+		"RateLimit-Policy": `"default";q=100;w=60, "daily";q=1000;w=86400`,
+	}, "").RateLimit()
+	if structured.Source != response.RateLimitSourceIETF || structured.Remaining != 50 || structured.Reset != 30*time.Second || structured.Limit != 100 {
+		t.Errorf("structured = %+v", structured)
+	}
+	if len(structured.Policies) != 2 || structured.Policies[1].Name != "daily" || structured.Policies[1].Window != 24*time.Hour {
+		t.Errorf("Policies = %+v", structured.Policies)
+	}
+
+	draft := newTestResponse(t, "https://api.example.com/", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"RateLimit-Limit":     "10",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "7",
+		"RateLimit-Policy":    "10;w=1",
+	}, "").RateLimit()
+	if draft.Limit != 10 || draft.Remaining != 0 || draft.Reset != 7*time.Second || !draft.Throttled() {
+		t.Errorf("draft = %+v", draft)
+	}
+	if draft.Policies[0].Quota != 10 || draft.Policies[0].Window != time.Second {
+		t.Errorf("draft policy = %+v", draft.Policies[0])
+	}
+}
+
+func TestRateLimitXHeaders(t *testing.T) {
+	date := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	epoch := newTestResponse(t, "https://api.example.com/", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"X-RateLimit-Limit":     "5000",
+		"X-RateLimit-Remaining": "4999",
+		"X-RateLimit-Reset":     "1777633200", // date + 1h
+		"Date":                  date.Format(http.TimeFormat),
+	}, "").RateLimit()
+	if epoch.Source != response.RateLimitSourceX || epoch.Limit != 5000 || epoch.Remaining != 4999 || epoch.Reset != time.Hour {
+		t.Errorf("epoch reset = %+v", epoch)
+	}
+
+	resetAfter := newTestResponse(t, "https://api.example.com/", "api.example.com", codes.GET, codes.OK, map[string]string{
+		"X-Rate-Limit-Limit":       "5",
+		"X-Rate-Limit-Reset-After": "1.5",
+	}, "").RateLimit()
+	if resetAfter.Limit != 5 || resetAfter.Reset != 1500*time.Millisecond {
+		t.Errorf("reset-after = %+v", resetAfter)
+	}
+}
+
+func TestAnalyzeThrottling(t *testing.T) {
+	pack := response.NewResponsePack()
+	add := func(url string, host string, status codes.StatusCode, headers map[string]string) {
+		if err := pack.AddResponse(newTestResponse(t, url, host, codes.GET, status, headers, "")); err != nil {
+			t.Fatalf("AddResponse() error = %v", err)
+		}
+	}
+
+	policy := map[string]string{"RateLimit-Policy": "100;w=60", "RateLimit-Remaining": "3"}
+	add("https://a.example.com/x", "a.example.com", codes.OK, policy)
+	add("https://a.example.com/x", "a.example.com", codes.TooManyRequests, map[string]string{"Retry-After": "20", "RateLimit-Remaining": "0"})
+	add("https://a.example.com/x", "a.example.com", codes.TooManyRequests, map[string]string{"Retry-After": "45"})
+	add("https://a.example.com/y", "a.example.com", codes.OK, nil)
+
+	add("https://b.example.com/z", "b.example.com", codes.ServiceUnavailable, nil)
+	add("https://b.example.com/z", "b.example.com", codes.ServiceUnavailable, nil)
+	add("https://b.example.com/z", "b.example.com", codes.ServiceUnavailable, nil)
+
+	report, err := response.AnalyzeThrottling(pack)
+	if err != nil {
+		t.Fatalf("AnalyzeThrottling() error = %v", err)
+	}
+	if len(report.Hosts) != 2 {
+		t.Fatalf("Hosts = %+v", report.Hosts)
+	}
+
+	a := report.Hosts[0]
+	if a.Rounds != 4 || a.Throttled != 2 || a.TooManyRequests != 2 || a.Streak != 2 || a.Urls["https://a.example.com/x"] != 2 {
+		t.Errorf("a = %+v", a)
+	}
+	if a.ThrottleRate() != 0.5 || a.MinRemaining != 0 || a.Limit != 100 || a.MaxRetryAfter != 45*time.Second {
+		t.Errorf("a quotas = %+v", a)
+	}
+	if a.RecommendedBackoff != 45*time.Second || a.RecommendedInterval != 600*time.Millisecond {
+		t.Errorf("a recommendations = %v, %v", a.RecommendedBackoff, a.RecommendedInterval)
+	}
+
+	b := report.Hosts[1]
+	if b.Unavailable != 3 || b.Streak != 3 || b.RecommendedBackoff != 4*time.Second {
+		t.Errorf("b = %+v, want exponential backoff of 4s", b)
+	}
+
+	data, err := report.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report JSON is invalid: %v", err)
+	}
+	if text := report.ToString(); !strings.Contains(text, "Throttled: 2 (50.00%)") || !strings.Contains(text, "RecommendedBackoff: 45s") {
+		t.Errorf("ToString() = %s", text)
+	}
+
+	if _, err := response.AnalyzeThrottling(nil); err == nil {
+		t.Errorf("AnalyzeThrottling(nil) error = nil")
+	}
+}
@@ -0,0 +1,168 @@
+package response_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+const rawFixedBody = "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 13\r\n\r\n{\"ok\": true}\n"
+
+const rawURL = "https://example.com/raw"
+
+const rawChunkedBody = "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n"
+
+func TestBodySharedWithRawResponse(t *testing.T) {
+	resp := parseTestResponse(t, rawFixedBody, rawURL)
+	if !resp.BodyShared() || resp.ReadBody() != "{\"ok\": true}\n" || resp.ReadRawResponse() != rawFixedBody {
+		t.Fatalf("BodyShared() = %v, body %q, raw %q", resp.BodyShared(), resp.Body, resp.RawResponse)
+	}
+
+	// Appending to the body never writes into the raw message
+	body := append(resp.Body, "tail"...)
+	if resp.ReadRawResponse() != rawFixedBody || string(body) != "{\"ok\": true}\ntail" {
+		t.Errorf("append changed the raw message: %q", resp.RawResponse)
+	}
+
+	// A shared body is counted once
+	usage := resp.MemoryUsage()
+	if usage.Logical-usage.Stored != int64(len(resp.Body)) {
+		t.Errorf("MemoryUsage() = %+v, want the body counted once", usage)
+	}
+
+	clone := resp.Clone()
+	if !clone.BodyShared() || &clone.Body[0] == &resp.Body[0] {
+		t.Errorf("Clone() BodyShared() = %v, want a shared body of its own", clone.BodyShared())
+	}
+
+	chunked := parseTestResponse(t, rawChunkedBody, rawURL)
+	if chunked.BodyShared() || chunked.ReadBody() != "hello" {
+		t.Errorf("chunked BodyShared() = %v, body %q", chunked.BodyShared(), chunked.Body)
+	}
+}
+
+func TestBodySharedIsTransparent(t *testing.T) {
+	shared := parseTestResponse(t, rawFixedBody, rawURL)
+	copied := shared.Clone()
+	copied.Body = append([]byte(nil), shared.Body...)
+	if copied.BodyShared() {
+		t.Fatalf("copied body is shared")
+	}
+
+	sharedJSON, _ := shared.ToJSON()
+	copiedJSON, _ := copied.ToJSON()
+	if !bytes.Equal(sharedJSON, copiedJSON) {
+		t.Errorf("ToJSON() = %s, want %s", sharedJSON, copiedJSON)
+	}
+
+	compressed, err := copied.Compress()
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	decompressed, err := response.NewResponseFromCompressed(compressed)
+	if err != nil {
+		t.Fatalf("NewResponseFromCompressed() error = %v", err)
+	}
+	if !decompressed.BodyShared() || decompressed.ReadBody() != shared.ReadBody() {
+		t.Errorf("decompressed BodyShared() = %v, body %q", decompressed.BodyShared(), decompressed.Body)
+	}
+	decoded, err := response.NewResponseFromJSON(sharedJSON)
+	if err != nil || !decoded.BodyShared() {
+		t.Errorf("NewResponseFromJSON() BodyShared() = %v, %v", decoded.BodyShared(), err)
+	}
+}
+
+func TestDropRawResponse(t *testing.T) {
+	resp := parseTestResponse(t, rawFixedBody, rawURL)
+	resp.DropRawResponse()
+	if resp.RawResponse != nil || resp.BodyShared() || resp.ReadBody() != "{\"ok\": true}\n" {
+		t.Fatalf("after DropRawResponse() raw %q, body %q", resp.RawResponse, resp.Body)
+	}
+
+	// The regenerated message parses back to the same response
+	regenerated := resp.ReadRawResponse()
+	if !strings.HasPrefix(regenerated, "HTTP/1.1 200 OK\r\n") || !strings.HasSuffix(regenerated, "\r\n\r\n{\"ok\": true}\n") {
+		t.Errorf("ReadRawResponse() = %q", regenerated)
+	}
+	parsed := parseTestResponse(t, regenerated, rawURL)
+	if parsed.StatusCode != codes.OK || parsed.ReadBody() != resp.ReadBody() || parsed.Headers["Content-Type"] != "application/json" {
+		t.Errorf("regenerated message parsed to %+v", parsed)
+	}
+
+	var encoded struct {
+		RawResponse []byte `json:"rawResponse"`
+	}
+	data, _ := resp.ToJSON()
+	if err := json.Unmarshal(data, &encoded); err != nil || string(encoded.RawResponse) != regenerated {
+		t.Errorf("ToJSON() rawResponse = %q, %v", encoded.RawResponse, err)
+	}
+	if readable, _ := resp.ToReadableJSON(); !strings.Contains(string(readable), "HTTP/1.1 200 OK") {
+		t.Errorf("ToReadableJSON() = %s", readable)
+	}
+
+	compressed, _ := resp.Compress()
+	decompressed, err := response.NewResponseFromCompressed(compressed)
+	if err != nil || decompressed.ReadRawResponse() != regenerated {
+		t.Errorf("decompressed raw = %q, %v", decompressed.ReadRawResponse(), err)
+	}
+
+	// Responses without a raw message keep an empty one
+	plain, _ := response.NewResponse(rawURL, "example.com", codes.GET, codes.OK, nil, []byte("x"), 1, nil)
+	if plain.ReadRawResponse() != "" {
+		t.Errorf("ReadRawResponse() = %q, want empty", plain.ReadRawResponse())
+	}
+}
+
+func TestPackDropRawResponse(t *testing.T) {
+	pack := response.NewResponsePackFromConfig(response.ConfigResponsePack{DropRawResponse: true})
+	resp := parseTestResponse(t, rawFixedBody, rawURL)
+	if err := pack.AddResponse(resp); err != nil {
+		t.Fatalf("AddResponse() error = %v", err)
+	}
+	if resp.ReadRawResponse() != rawFixedBody || resp.RawResponse == nil {
+		t.Errorf("AddResponse() changed the caller's response")
+	}
+
+	stored, _ := pack.Latest(rawURL)
+	if stored.RawResponse != nil || !strings.HasPrefix(stored.ReadRawResponse(), "HTTP/1.1 200 OK") {
+		t.Errorf("stored raw %q, regenerated %q", stored.RawResponse, stored.ReadRawResponse())
+	}
+	if usage := pack.Memory(); usage.Logical != resp.Size()-int64(len(rawFixedBody)) {
+		t.Errorf("Memory() = %+v, want the raw message left out", usage)
+	}
+
+	data, err := pack.ToJSON()
+	if err != nil || !strings.Contains(string(data), `"rawResponse":"SFRUUC8xLjEgMjAwIE9L`) {
+		t.Errorf("pack ToJSON() = %s, %v", data, err)
+	}
+
+	if err := pack.ReplaceRound(rawURL, 1, parseTestResponse(t, rawFixedBody, rawURL)); err != nil {
+		t.Fatalf("ReplaceRound() error = %v", err)
+	}
+	if stored, _ := pack.Latest(rawURL); stored.RawResponse != nil {
+		t.Errorf("ReplaceRound() kept the raw message")
+	}
+}
+
+func TestErrorReportEntryJSON(t *testing.T) {
+	pack := response.NewResponsePackFromConfig(response.ConfigResponsePack{DropRawResponse: true})
+	resp := parseTestResponse(t, "HTTP/1.1 403 Forbidden\r\nContent-Type: application/problem+json\r\nContent-Length: 22\r\n\r\n{\"title\":\"No access\"}\n", rawURL)
+	_ = pack.AddResponse(resp)
+	report, err := pack.GetErrorReportEntries()
+	if err != nil {
+		t.Fatalf("GetErrorReportEntries() error = %v", err)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, want := range []string{`"url":"https://example.com/raw"`, `"statusCode":403`, `"problem":{`, `"title":"No access"`, `"rawResponse":"SFRUUC8xLjEgNDAz`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("report JSON = %s, want %s", data, want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package response_test
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func newSensitiveResponse(t *testing.T) *response.Response {
+	t.Helper()
+	body := `{"user":{"name":"ana","password":"hunter2"},"items":[{"token":"abc"},{"token":"def"}],"note":"mail ana@example.com"}`
+	resp := newTestResponse(t, "https://example.com/login", "example.com", codes.POST, codes.OK, map[string]string{
+		"Content-Type": "application/json",
+		"Set-Cookie":   "session=s3cr3t",
+	}, body)
+	resp.RawResponse = []byte("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nSet-Cookie: session=s3cr3t\r\nContent-Length: " +
+		strconv.Itoa(len(body)) + "\r\n\r\n" + body)
+	resp.Request = &response.RecordedRequest{
+		Method:  codes.POST,
+		Url:     "https://example.com/login",
+		Headers: map[string]string{"Authorization": "Bearer xyz"},
+		Body:    []byte(`{"password":"hunter2"}`),
+	}
+	return resp
+}
+
+func TestRedactionPolicyMask(t *testing.T) {
+	original := newSensitiveResponse(t)
+
+	policy := response.DefaultRedactionPolicy()
+	policy.Patterns = []*regexp.Regexp{regexp.MustCompile(`[a-z]+@example\.com`)}
+
+	redacted := policy.Redact(original)
+
+	if !strings.Contains(original.ReadBody(), "hunter2") {
+		t.Error("Redact() modified the original response")
+	}
+
+	checks := map[string]string{
+		"body":         redacted.ReadBody(),
+		"raw response": redacted.ReadRawResponse(),
+		"request body": string(redacted.Request.Body),
+	}
+	for name, text := range checks {
+		for _, secret := range []string{"hunter2", "s3cr3t", "ana@example.com", `"abc"`} {
+			if strings.Contains(text, secret) {
+				t.Errorf("%s still contains %q: %s", name, secret, text)
+			}
+		}
+	}
+
+	if redacted.Headers["Set-Cookie"] != response.DefaultRedactMask {
+		t.Errorf("Set-Cookie = %q, want mask", redacted.Headers["Set-Cookie"])
+	}
+	if redacted.Request.Headers["Authorization"] != response.DefaultRedactMask {
+		t.Errorf("Authorization = %q, want mask", redacted.Request.Headers["Authorization"])
+	}
+	if redacted.BodyLength != uint64(len(redacted.Body)) {
+		t.Errorf("BodyLength = %d, want %d", redacted.BodyLength, len(redacted.Body))
+	}
+
+	// The raw response must still parse with the new Content-Length
+	raw := redacted.RawResponse
+	parsed, err := response.ParseRawHTTPResponse(&raw, "https://example.com/login")
+	if err != nil {
+		t.Fatalf("ParseRawHTTPResponse() of redacted raw error = %v", err)
+	}
+	if parsed.ReadBody() != redacted.ReadBody() {
+		t.Errorf("raw body = %q, want %q", parsed.ReadBody(), redacted.ReadBody())
+	}
+}
+
+func TestRedactionPolicyHashAndDrop(t *testing.T) {
+	hash := response.NewRedactionPolicy(response.RedactHash)
+	hash.JSONPaths = []string{"$.user.password", "items.*.token"}
+
+	first := hash.Redact(newSensitiveResponse(t))
+	second := hash.Redact(newSensitiveResponse(t))
+	if first.ReadBody() != second.ReadBody() {
+		t.Error("hash strategy must be deterministic")
+	}
+	if !strings.Contains(first.ReadBody(), "sha256:") || strings.Contains(first.ReadBody(), "hunter2") {
+		t.Errorf("hashed body = %s", first.ReadBody())
+	}
+	if !strings.Contains(first.ReadBody(), `"name":"ana"`) {
+		t.Errorf("hash strategy redacted a field outside the paths: %s", first.ReadBody())
+	}
+
+	drop := response.NewRedactionPolicy(response.RedactDrop)
+	drop.Headers = []string{"set-cookie"}
+	drop.JSONKeys = []string{"password"}
+
+	dropped := drop.Redact(newSensitiveResponse(t))
+	if _, ok := dropped.Headers["Set-Cookie"]; ok {
+		t.Error("drop strategy kept Set-Cookie")
+	}
+	if strings.Contains(dropped.ReadBody(), "password") {
+		t.Errorf("drop strategy kept password field: %s", dropped.ReadBody())
+	}
+	if strings.Contains(dropped.ReadRawResponse(), "Set-Cookie") {
+		t.Errorf("drop strategy kept Set-Cookie in raw response: %s", dropped.ReadRawResponse())
+	}
+}
+
+func TestRedactionPolicyBodies(t *testing.T) {
+	policy := response.DefaultRedactionPolicy()
+	redact := func(body string) *response.Response {
+		raw := "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+		return policy.Redact(parseTestResponse(t, raw, "https://example.com/stream"))
+	}
+
+	// Every document of an NDJSON body is redacted
+	ndjson := redact("{\"token\":\"a\"}\n{\"id\":2}\n{\"token\":\"b\"}\n")
+	if want := "{\"token\":\"[REDACTED]\"}\n{\"id\":2}\n{\"token\":\"[REDACTED]\"}\n"; ndjson.ReadBody() != want {
+		t.Errorf("NDJSON body = %q, want %q", ndjson.ReadBody(), want)
+	}
+	if ndjson.Headers["Content-Length"] != strconv.Itoa(len(ndjson.Body)) || !strings.Contains(ndjson.ReadRawResponse(), "Content-Length: "+strconv.Itoa(len(ndjson.Body))) {
+		t.Errorf("Content-Length = %q for %d bytes, raw %q", ndjson.Headers["Content-Length"], len(ndjson.Body), ndjson.RawResponse)
+	}
+
+	// Bodies without a match keep their formatting, trailing data is kept
+	for _, body := range []string{"{\"html\": \"<b>&</b>\"}\n", "{\"token\":\"a\"} trailing"} {
+		if got := redact(body).ReadBody(); got != body {
+			t.Errorf("body %q redacted to %q", body, got)
+		}
+	}
+	if got := redact(`{"token":"a","html":"<b>"}`).ReadBody(); got != `{"html":"<b>","token":"[REDACTED]"}` {
+		t.Errorf("redacted body = %q, want HTML left unescaped", got)
+	}
+
+	// Dropped array items are removed
+	drop := response.NewRedactionPolicy(response.RedactDrop)
+	drop.JSONPaths = []string{"tokens.1"}
+	dropped := drop.Redact(redact(`{"tokens":["a","b","c"]}`))
+	if dropped.ReadBody() != `{"tokens":["a","c"]}` {
+		t.Errorf("dropped body = %q", dropped.ReadBody())
+	}
+}
+
+func TestPackRedactionOnAdd(t *testing.T) {
+	original := newSensitiveResponse(t)
+
+	pack := response.NewResponsePack()
+	pack.SetRedactionPolicy(response.DefaultRedactionPolicy())
+	_ = pack.AddResponse(original)
+
+	rounds, err := pack.GetResponse("https://example.com/login")
+	if err != nil {
+		t.Fatalf("GetResponse() error = %v", err)
+	}
+	if strings.Contains(rounds[0].ReadBody(), "hunter2") {
+		t.Errorf("ResponsePack stored unredacted body: %s", rounds[0].ReadBody())
+	}
+	if !strings.Contains(original.ReadBody(), "hunter2") {
+		t.Error("AddResponse() modified the caller's response")
+	}
+
+	data, err := pack.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Error("ToJSON() output contains a secret")
+	}
+
+	compressPack := response.NewCompressResponsePack()
+	compressPack.SetRedactionPolicy(response.DefaultRedactionPolicy())
+	_ = compressPack.AddResponse(original)
+
+	rounds, err = compressPack.GetResponse("https://example.com/login")
+	if err != nil {
+		t.Fatalf("GetResponse() error = %v", err)
+	}
+	if rounds[0].Headers["Set-Cookie"] != response.DefaultRedactMask {
+		t.Errorf("CompressResponsePack stored Set-Cookie = %q", rounds[0].Headers["Set-Cookie"])
+	}
+}
+
+func TestPackRedactionChunkedRaw(t *testing.T) {
+	raw := "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"a\r\n{\"token\":\"\r\nf\r\nsupersecret123\"\r\n1\r\n}\r\n0\r\n\r\n"
+	resp := parseTestResponse(t, raw, "https://example.com/chunked")
+	if resp.ReadBody() != `{"token":"supersecret123"}` {
+		t.Fatalf("parsed body = %q", resp.ReadBody())
+	}
+
+	pack := response.NewResponsePack()
+	pack.SetRedactionPolicy(response.DefaultRedactionPolicy())
+	_ = pack.AddResponse(resp)
+
+	stored, err := pack.Latest("https://example.com/chunked")
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	storedRaw := string(stored.RawBytes())
+	if strings.Contains(storedRaw, "supersecret123") || strings.Contains(storedRaw, "Transfer-Encoding") {
+		t.Errorf("RawBytes() = %q, want the secret and Transfer-Encoding removed", storedRaw)
+	}
+	if !strings.HasSuffix(storedRaw, "\r\n\r\n"+stored.ReadBody()) || !strings.Contains(storedRaw, "Content-Length: "+strconv.Itoa(len(stored.Body))) {
+		t.Errorf("RawBytes() = %q, want the redacted body %q with its Content-Length", storedRaw, stored.ReadBody())
+	}
+
+	data, err := pack.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	loaded, err := response.NewResponsePackFromJSON(data)
+	if err != nil {
+		t.Fatalf("NewResponsePackFromJSON() error = %v", err)
+	}
+	reloaded, _ := loaded.Latest("https://example.com/chunked")
+	if strings.Contains(string(data), "supersecret123") || reloaded == nil || strings.Contains(reloaded.ReadRawResponse(), "supersecret123") {
+		t.Error("ToJSON() output contains the secret")
+	}
+}
@@ -0,0 +1,258 @@
+package response_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func newReplayFixture(t *testing.T, url string, method codes.Method, bodies ...string) *response.Response {
+	t.Helper()
+	return newTestResponse(t, url, "example.com", method, codes.OK, map[string]string{"Content-Type": "text/plain"}, strings.Join(bodies, ""))
+}
+
+func readAll(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return string(data)
+}
+
+func TestReplayTransportReturnsRoundsInOrder(t *testing.T) {
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newReplayFixture(t, "https://example.com/items", codes.GET, "first"))
+	_ = pack.AddResponse(newReplayFixture(t, "https://example.com/items", codes.GET, "second"))
+
+	client := &http.Client{Transport: response.NewReplayTransport(pack, response.ReplayOnly)}
+
+	want := []string{"first", "second", "second"}
+	for i, expected := range want {
+		resp, err := client.Get("https://example.com/items")
+		if err != nil {
+			t.Fatalf("call %d: Get() error = %v", i, err)
+		}
+		if body := readAll(t, resp); body != expected {
+			t.Errorf("call %d: body = %q, want %q", i, body, expected)
+		}
+	}
+}
+
+func TestReplayTransportCompressPack(t *testing.T) {
+	pack := response.NewCompressResponsePack()
+	_ = pack.AddResponse(newReplayFixture(t, "https://example.com/items?a=1&b=2", codes.GET, "compressed"))
+
+	client := &http.Client{Transport: response.NewReplayTransport(pack, response.ReplayOnly)}
+
+	// Query order must not matter for the default matchers
+	resp, err := client.Get("https://example.com/items?b=2&a=1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if body := readAll(t, resp); body != "compressed" {
+		t.Errorf("body = %q, want %q", body, "compressed")
+	}
+}
+
+// countingPack counts the reads of rounds of a CompressResponsePack.
+type countingPack struct {
+	*response.CompressResponsePack
+	reads atomic.Int64
+}
+
+func (p *countingPack) GetResponse(key string) ([]*response.Response, error) {
+	p.reads.Add(1)
+	return p.CompressResponsePack.GetResponse(key)
+}
+
+func (p *countingPack) History(key string) ([]response.Round, error) {
+	p.reads.Add(1)
+	return p.CompressResponsePack.History(key)
+}
+
+func TestReplayTransportReadsCandidateKeysOnly(t *testing.T) {
+	pack := &countingPack{CompressResponsePack: response.NewCompressResponsePack()}
+	for i := 0; i < 20; i++ {
+		_ = pack.AddResponse(newReplayFixture(t, "https://example.com/items/"+strconv.Itoa(i), codes.GET, "item"))
+	}
+	transport := response.NewReplayTransport(pack, response.ReplayOnly)
+
+	if _, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "https://example.com/items/7", nil)); err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if reads := pack.reads.Load(); reads != 1 {
+		t.Errorf("lookup read %d keys, want 1", reads)
+	}
+
+	pack.reads.Store(0)
+	_, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "https://example.com/items/70", nil))
+	var mismatch *response.ReplayMismatchError
+	if !errors.As(err, &mismatch) || len(mismatch.Candidates) != 3 {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if reads := pack.reads.Load(); reads != 3 {
+		t.Errorf("mismatch read %d keys, want the 3 closest", reads)
+	}
+}
+
+func TestReplayTransportCursorKeepsPosition(t *testing.T) {
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newReplayFixture(t, "https://example.com/feed", codes.GET, "first"))
+	_ = pack.AddResponse(newReplayFixture(t, "https://example.com/feed", codes.GET, "second"))
+	client := &http.Client{Transport: response.NewReplayTransport(pack, response.ReplayOnly)}
+
+	get := func() string {
+		resp, err := client.Get("https://example.com/feed")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return readAll(t, resp)
+	}
+	if first, second := get(), get(); first != "first" || second != "second" {
+		t.Fatalf("bodies = %q, %q", first, second)
+	}
+
+	// A round recorded after the others is served next
+	_ = pack.AddResponse(newReplayFixture(t, "https://example.com/feed", codes.GET, "third"))
+	if body := get(); body != "third" {
+		t.Errorf("body = %q, want the new round", body)
+	}
+}
+
+func TestReplayTransportMismatch(t *testing.T) {
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newReplayFixture(t, "https://example.com/items", codes.GET, "x"))
+	_ = pack.AddResponse(newReplayFixture(t, "https://example.com/users", codes.POST, "y"))
+
+	transport := response.NewReplayTransport(pack, response.ReplayOnly)
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/item", nil)
+
+	_, err := transport.RoundTrip(req)
+	if err == nil {
+		t.Fatal("RoundTrip() for unknown URL should return error")
+	}
+
+	var mismatch *response.ReplayMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("RoundTrip() error type = %T, want *ReplayMismatchError", err)
+	}
+	if len(mismatch.Candidates) == 0 || mismatch.Candidates[0] != "GET https://example.com/items" {
+		t.Errorf("Candidates = %v, want GET https://example.com/items first", mismatch.Candidates)
+	}
+	if !strings.Contains(err.Error(), "closest candidates") {
+		t.Errorf("Error() = %q, want candidates listed", err.Error())
+	}
+}
+
+func TestReplayTransportRecordMissing(t *testing.T) {
+	var hits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("echo:" + string(body)))
+	}))
+	defer upstream.Close()
+
+	pack := response.NewResponsePack()
+	transport := response.NewReplayTransport(pack, response.RecordMissing, response.MatchQuery(), response.MatchBody())
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(upstream.URL+"/echo", "text/plain", strings.NewReader("ping"))
+		if err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+		if body := readAll(t, resp); body != "echo:ping" {
+			t.Errorf("body = %q, want %q", body, "echo:ping")
+		}
+	}
+
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("upstream hits = %d, want 1", hits)
+	}
+
+	rounds, err := pack.GetResponse(upstream.URL + "/echo")
+	if err != nil {
+		t.Fatalf("GetResponse() error = %v", err)
+	}
+	if rounds[0].Request == nil || string(rounds[0].Request.Body) != "ping" {
+		t.Errorf("recorded request = %+v, want body ping", rounds[0].Request)
+	}
+
+	// A different body is not matched and must be recorded as a new round
+	resp, err := client.Post(upstream.URL+"/echo", "text/plain", strings.NewReader("pong"))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	_ = readAll(t, resp)
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Errorf("upstream hits = %d, want 2", hits)
+	}
+}
+
+func TestReplayTransportRecordAlways(t *testing.T) {
+	var hits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		_, _ = w.Write([]byte("live"))
+	}))
+	defer upstream.Close()
+
+	pack := response.NewCompressResponsePack()
+	client := &http.Client{Transport: response.NewReplayTransport(pack, response.RecordAlways)}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(upstream.URL + "/live")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		_ = readAll(t, resp)
+	}
+
+	if atomic.LoadInt32(&hits) != 3 {
+		t.Errorf("upstream hits = %d, want 3", hits)
+	}
+	if pack.GetResponseCount() != 3 {
+		t.Errorf("GetResponseCount() = %d, want 3", pack.GetResponseCount())
+	}
+}
+
+func TestReplayTransportMatchHeaders(t *testing.T) {
+	pack := response.NewResponsePack()
+	recorded := newReplayFixture(t, "https://example.com/me", codes.GET, "alice")
+	recorded.Request = &response.RecordedRequest{
+		Method:  codes.GET,
+		Url:     "https://example.com/me",
+		Headers: map[string]string{"X-User": "alice"},
+	}
+	_ = pack.AddResponse(recorded)
+
+	transport := response.NewReplayTransport(pack, response.ReplayOnly, response.MatchHeaders("x-user"))
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/me", nil)
+	req.Header.Set("X-User", "bob")
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Error("RoundTrip() with different header should return error")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "https://example.com/me", nil)
+	req.Header.Set("X-User", "alice")
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if body := readAll(t, resp); body != "alice" {
+		t.Errorf("body = %q, want alice", body)
+	}
+}
@@ -363,6 +363,13 @@ func TestGetErrorReport(t *testing.T) {
 		t.Error("GetErrorReport() missing error for https://example.com/api3")
 	}
 
+	// The entries carry the same rounds
+	var rounds map[string]*response.Response = errorReport["https://example.com/api3"]
+	entries, err := pack.GetErrorReportEntries()
+	if err != nil || len(entries) != 1 || entries["https://example.com/api3"]["round_1"].Response != rounds["round_1"] {
+		t.Errorf("GetErrorReportEntries() = %v, %v", entries, err)
+	}
+
 	// Test empty pack
 	emptyPack := response.NewResponsePack()
 	_, err = emptyPack.GetErrorReport()
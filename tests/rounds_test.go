@@ -0,0 +1,185 @@
+package response_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+const roundsURL = "https://example.com/rounds"
+
+// newRoundResponse returns a response of roundsURL whose body is its round number.
+func newRoundResponse(t *testing.T, round int) *response.Response {
+	t.Helper()
+	return newTestResponse(t, roundsURL, "example.com", codes.GET, codes.OK, nil, fmt.Sprintf("%d", round))
+}
+
+// roundPack is the part of both packs these tests exercise.
+type roundPack interface {
+	response.Pack
+	GetRound(url string, n int) (*response.Response, error)
+	First(url string) (*response.Response, error)
+	Latest(url string) (*response.Response, error)
+	BatchGetResponse(urls []string) (map[string]map[string]*response.Response, []error)
+}
+
+func testRoundOrder(t *testing.T, pack roundPack) {
+	// More than nine rounds, "round_10" sorts before "round_2" as a string
+	for round := 1; round <= 12; round++ {
+		if err := pack.AddResponse(newRoundResponse(t, round)); err != nil {
+			t.Fatalf("AddResponse() error = %v", err)
+		}
+	}
+
+	history, err := pack.History(roundsURL)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(history) != 12 {
+		t.Fatalf("History() returned %d rounds, want 12", len(history))
+	}
+	for i, round := range history {
+		if round.Seq != i+1 || string(round.Response.Body) != fmt.Sprintf("%d", i+1) {
+			t.Errorf("History()[%d] = seq %d, body %q", i, round.Seq, round.Response.Body)
+		}
+	}
+
+	responses, err := pack.GetResponse(roundsURL)
+	if err != nil {
+		t.Fatalf("GetResponse() error = %v", err)
+	}
+	for i, resp := range responses {
+		if string(resp.Body) != fmt.Sprintf("%d", i+1) {
+			t.Errorf("GetResponse()[%d].Body = %q", i, resp.Body)
+		}
+	}
+
+	resp, err := pack.GetRound(roundsURL, 10)
+	if err != nil || string(resp.Body) != "10" {
+		t.Errorf("GetRound(10) = %v, %v", resp, err)
+	}
+	if _, err := pack.GetRound(roundsURL, 13); err == nil {
+		t.Errorf("GetRound(13) error = nil, want an error")
+	}
+	if _, err := pack.GetRound("https://example.com/missing", 1); err == nil {
+		t.Errorf("GetRound() of a missing URL error = nil, want an error")
+	}
+
+	first, err := pack.First(roundsURL)
+	if err != nil || string(first.Body) != "1" {
+		t.Errorf("First() = %v, %v", first, err)
+	}
+	latest, err := pack.Latest(roundsURL)
+	if err != nil || string(latest.Body) != "12" {
+		t.Errorf("Latest() = %v, %v", latest, err)
+	}
+	if _, err := pack.Latest("https://example.com/missing"); err == nil {
+		t.Errorf("Latest() of a missing URL error = nil, want an error")
+	}
+
+	batch, errs := pack.BatchGetResponse([]string{roundsURL})
+	if len(errs) != 0 {
+		t.Fatalf("BatchGetResponse() errors = %v", errs)
+	}
+	if len(batch[roundsURL]) != 12 {
+		t.Fatalf("BatchGetResponse() returned %d rounds, want 12", len(batch[roundsURL]))
+	}
+	for round := 1; round <= 12; round++ {
+		resp := batch[roundsURL][fmt.Sprintf("round_%d", round)]
+		if resp == nil || string(resp.Body) != fmt.Sprintf("%d", round) {
+			t.Errorf("BatchGetResponse()[round_%d] = %v", round, resp)
+		}
+	}
+}
+
+func TestResponsePackRoundOrder(t *testing.T) {
+	testRoundOrder(t, response.NewResponsePack())
+}
+
+func TestCompressResponsePackRoundOrder(t *testing.T) {
+	testRoundOrder(t, response.NewCompressResponsePack())
+}
+
+func TestResponsePackRoundsJSON(t *testing.T) {
+	pack := response.NewResponsePack()
+	for round := 1; round <= 3; round++ {
+		_ = pack.AddResponse(newRoundResponse(t, round))
+	}
+	data, err := pack.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+
+	loaded, err := response.NewResponsePackFromJSON(data)
+	if err != nil {
+		t.Fatalf("NewResponsePackFromJSON() error = %v", err)
+	}
+	if loaded.LastRound[roundsURL] != 3 {
+		t.Errorf("LastRound = %v, want 3", loaded.LastRound)
+	}
+	_ = loaded.AddResponse(newRoundResponse(t, 4))
+	latest, err := loaded.Latest(roundsURL)
+	if err != nil || string(latest.Body) != "4" {
+		t.Errorf("Latest() = %v, %v", latest, err)
+	}
+	if _, ok := loaded.Responses[roundsURL]["round_4"]; !ok {
+		t.Errorf("Responses keys = %v, want round_4", loaded.Responses[roundsURL])
+	}
+}
+
+func TestResponsePackRoundsLegacyJSON(t *testing.T) {
+	// Written before lastRound existed, rounds only known by their keys
+	data := []byte(`{"responses":{"https://example.com/rounds":{
+		"round_10":{"url":"https://example.com/rounds","statusCode":200,"body":"MTA="},
+		"round_2":{"url":"https://example.com/rounds","statusCode":200,"body":"Mg=="},
+		"round_1":{"url":"https://example.com/rounds","statusCode":200,"body":"MQ=="}}},
+		"total":3,"success":3}`)
+
+	pack, err := response.NewResponsePackFromJSON(data)
+	if err != nil {
+		t.Fatalf("NewResponsePackFromJSON() error = %v", err)
+	}
+	history, err := pack.History(roundsURL)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	var seqs []int
+	for _, round := range history {
+		seqs = append(seqs, round.Seq)
+	}
+	if fmt.Sprint(seqs) != "[1 2 10]" {
+		t.Errorf("History() seqs = %v, want [1 2 10]", seqs)
+	}
+
+	_ = pack.AddResponse(newRoundResponse(t, 11))
+	latest, err := pack.GetRound(roundsURL, 11)
+	if err != nil || string(latest.Body) != "11" {
+		t.Errorf("GetRound(11) = %v, %v", latest, err)
+	}
+}
+
+func TestCompressResponsePackRoundsNotReused(t *testing.T) {
+	pack := response.NewCompressResponsePack()
+	_ = pack.AddResponse(newRoundResponse(t, 1))
+	_ = pack.AddResponse(newRoundResponse(t, 2))
+	if err := pack.DeleteResponse(roundsURL); err != nil {
+		t.Fatalf("DeleteResponse() error = %v", err)
+	}
+	_ = pack.AddResponse(newRoundResponse(t, 3))
+
+	history, err := pack.History(roundsURL)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(history) != 1 || history[0].Seq != 3 {
+		t.Errorf("History() = %+v, want one round with seq 3", history)
+	}
+
+	pack.Clear()
+	_ = pack.AddResponse(newRoundResponse(t, 1))
+	if first, err := pack.GetRound(roundsURL, 1); err != nil || string(first.Body) != "1" {
+		t.Errorf("GetRound(1) after Clear() = %v, %v", first, err)
+	}
+}
@@ -0,0 +1,139 @@
+package response_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JuniorVieira99/jr_goresponse/response"
+	"github.com/JuniorVieira99/jr_httpcodes/codes"
+)
+
+func newStatsResponse(t *testing.T, url string, host string, method codes.Method, status codes.StatusCode, size int, latency time.Duration) *response.Response {
+	t.Helper()
+	resp := newTestResponse(t, url, host, method, status, nil, strings.Repeat("x", size))
+	if latency > 0 {
+		resp.Timing = &response.Timing{Start: time.Now(), Duration: latency}
+	}
+	return resp
+}
+
+func TestPackStats(t *testing.T) {
+	pack := response.NewResponsePack()
+	for i := 1; i <= 10; i++ {
+		status := codes.OK
+		if i > 8 {
+			status = codes.ServiceUnavailable
+		}
+		_ = pack.AddResponse(newStatsResponse(t, fmt.Sprintf("https://a.example.com/%d", i%3), "a.example.com", codes.GET, status, i*100, time.Duration(i)*time.Millisecond))
+	}
+	_ = pack.AddResponse(newStatsResponse(t, "https://b.example.com/x", "b.example.com", codes.POST, codes.Created, 0, 0))
+	_ = pack.AddResponse(newStatsResponse(t, "https://b.example.com/x", "b.example.com", codes.POST, codes.NotFound, 50, 0))
+
+	stats := pack.GetStats()
+
+	if stats.StatusClasses["2xx"] != 9 || stats.StatusClasses["4xx"] != 1 || stats.StatusClasses["5xx"] != 2 {
+		t.Errorf("StatusClasses = %v", stats.StatusClasses)
+	}
+	if stats.StatusCodes[200] != 8 || stats.StatusCodes[201] != 1 || stats.StatusCodes[503] != 2 {
+		t.Errorf("StatusCodes = %v", stats.StatusCodes)
+	}
+	if stats.Methods["GET"] != 10 || stats.Methods["POST"] != 2 {
+		t.Errorf("Methods = %v", stats.Methods)
+	}
+	if stats.Hosts["a.example.com"] != 10 || stats.Hosts["b.example.com"] != 2 {
+		t.Errorf("Hosts = %v", stats.Hosts)
+	}
+
+	size := stats.BodySize
+	if size.Count != 12 || size.Min != 0 || size.Max != 1000 || size.P50 != 400 || size.P90 != 900 || size.P99 != 1000 {
+		t.Errorf("BodySize = %+v", size)
+	}
+	if size.Mean != (5500+50)/12 {
+		t.Errorf("BodySize.Mean = %d", size.Mean)
+	}
+
+	latency := stats.Latency
+	if latency.Count != 10 || latency.Min != time.Millisecond || latency.Max != 10*time.Millisecond || latency.P50 != 5*time.Millisecond || latency.P95 != 10*time.Millisecond {
+		t.Errorf("Latency = %+v", latency)
+	}
+
+	// GetStats returns a copy
+	stats.Hosts["c.example.com"] = 1
+	if _, ok := pack.GetStats().Hosts["c.example.com"]; ok {
+		t.Errorf("GetStats() shares its maps with the pack")
+	}
+
+	text := pack.ToString()
+	for _, want := range []string{"StatusClasses:\n\t2xx: 9", "Methods:\n\tGET: 10\n\tPOST: 2", "BodySize: min 0, mean 462", "Latency: min 1ms"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("ToString() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestPackStatsJSON(t *testing.T) {
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newStatsResponse(t, "https://a.example.com/", "a.example.com", codes.GET, codes.OK, 10, 2*time.Millisecond))
+	_ = pack.AddResponse(newStatsResponse(t, "https://a.example.com/", "a.example.com", codes.GET, codes.NotFound, 30, 4*time.Millisecond))
+
+	data, err := pack.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var decoded struct {
+		Stats struct {
+			StatusCodes map[string]uint64 `json:"statusCodes"`
+			BodySize    struct {
+				Max int64 `json:"max"`
+			} `json:"bodySize"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded.Stats.StatusCodes["404"] != 1 || decoded.Stats.BodySize.Max != 30 {
+		t.Errorf("stats JSON = %s", data)
+	}
+
+	loaded, err := response.NewResponsePackFromJSON(data)
+	if err != nil {
+		t.Fatalf("NewResponsePackFromJSON() error = %v", err)
+	}
+	_ = loaded.AddResponse(newStatsResponse(t, "https://a.example.com/", "a.example.com", codes.GET, codes.OK, 20, 3*time.Millisecond))
+	stats := loaded.GetStats()
+	if stats.BodySize.Count != 3 || stats.BodySize.P50 != 20 || stats.Latency.Mean != 3*time.Millisecond {
+		t.Errorf("loaded stats = %+v", stats)
+	}
+}
+
+func TestPackStatsCalculate(t *testing.T) {
+	pack := response.NewResponsePack()
+	_ = pack.AddResponse(newStatsResponse(t, "https://a.example.com/", "a.example.com", codes.GET, codes.OK, 10, 0))
+	pack.Calculate()
+	if stats := pack.GetStats(); stats.StatusCodes[200] != 1 || stats.BodySize.Count != 1 || stats.Latency.Count != 0 {
+		t.Errorf("stats after Calculate() = %+v", stats)
+	}
+}
+
+func TestPackStatsAfterDelete(t *testing.T) {
+	pack := response.NewResponsePack()
+	for i := 1; i <= 4; i++ {
+		_ = pack.AddResponse(newStatsResponse(t, fmt.Sprintf("https://a.example.com/%d", i), "a.example.com", codes.GET, codes.OK, i*10, time.Duration(i)*time.Millisecond))
+	}
+	if size := pack.GetStats().BodySize; size.Max != 40 || size.P50 != 20 {
+		t.Errorf("BodySize = %+v", size)
+	}
+
+	_ = pack.DeleteResponse("https://a.example.com/4")
+	_ = pack.DeleteResponse("https://a.example.com/1")
+	if pack.Stats.BodySize.Count != 2 || pack.Stats.BodySize.Mean != 25 {
+		t.Errorf("Stats.BodySize = %+v, want Count and Mean current", pack.Stats.BodySize)
+	}
+	stats := pack.GetStats()
+	if stats.BodySize.Min != 20 || stats.BodySize.Max != 30 || stats.BodySize.P99 != 30 || stats.Latency.Min != 2*time.Millisecond || stats.Latency.Count != 2 {
+		t.Errorf("stats after DeleteResponse() = %+v", stats)
+	}
+}